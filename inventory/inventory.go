@@ -0,0 +1,210 @@
+/*
+Package inventory reads the inventory file which lists the hosts to apply the
+recipes.
+
+The inventory is written in YAML or JSON:
+
+	hosts:
+	  web1:
+	    address: deploy@192.0.2.10
+	    vars:
+	      server_name: www1.example.com
+	  web2:
+	    address: deploy@192.0.2.11:2222
+	  db1: {}
+	groups:
+	  web:
+	    hosts: [web1, web2]
+	    recipes: [web.json]
+	    vars:
+	      port: "8080"
+	  db:
+	    hosts: [db1]
+	    recipes: [db.json]
+
+The address is the SSH destination in the form of [user@]host[:port], and it
+is the name of the host if omitted. The recipes are relative to the directory
+of the inventory file. The variables of the host override the variables of its
+groups.
+*/
+package inventory
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Inventory represents the hosts and the groups of the hosts.
+type Inventory struct {
+	Hosts  map[string]*Host  `json:"hosts"  yaml:"hosts"`
+	Groups map[string]*Group `json:"groups" yaml:"groups"`
+
+	// Dir is the directory which the recipes are relative to.
+	Dir string `json:"-" yaml:"-"`
+}
+
+// Host represents the host in the inventory.
+type Host struct {
+	Address string            `json:"address" yaml:"address"`
+	Vars    map[string]string `json:"vars"    yaml:"vars"`
+}
+
+// Group represents the group of the hosts, and the recipes applied to them.
+type Group struct {
+	Hosts   []string          `json:"hosts"   yaml:"hosts"`
+	Recipes []string          `json:"recipes" yaml:"recipes"`
+	Vars    map[string]string `json:"vars"    yaml:"vars"`
+}
+
+// Target is the host selected from the inventory with the recipes and the
+// variables of the host.
+type Target struct {
+	Name    string
+	Address string
+	Recipes []string
+	Vars    map[string]string
+}
+
+// ReadFile reads the named inventory file. The file which has the ".json"
+// extension is parsed as JSON, otherwise as YAML.
+func ReadFile(name string) (*Inventory, error) {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	inv := &Inventory{}
+	if strings.HasSuffix(name, ".json") {
+		err = json.Unmarshal(data, inv)
+	} else {
+		err = yaml.Unmarshal(data, inv)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can not parse the inventory file: %s", err)
+	}
+	dir, err := filepath.Abs(filepath.Dir(name))
+	if err != nil {
+		return nil, err
+	}
+	inv.Dir = dir
+	if err := inv.validate(); err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+func (inv *Inventory) validate() error {
+	for name, g := range inv.Groups {
+		if _, ok := inv.Hosts[name]; ok {
+			return fmt.Errorf("the group %q has the same name as the host", name)
+		}
+		if g == nil {
+			continue
+		}
+		for _, h := range g.Hosts {
+			if _, ok := inv.Hosts[h]; !ok {
+				return fmt.Errorf("the group %q has the unknown host %q", name, h)
+			}
+		}
+	}
+	return nil
+}
+
+// Select returns the targets which are specified by the limit, in the order of
+// the host names. The limit is a comma separated list of the host names and
+// the group names. If the limit is empty, all of the hosts are selected.
+func (inv *Inventory) Select(limit string) ([]*Target, error) {
+	names := map[string]bool{}
+	if limit == "" {
+		for name := range inv.Hosts {
+			names[name] = true
+		}
+	}
+	for _, l := range strings.Split(limit, ",") {
+		l = strings.TrimSpace(l)
+		if l == "" {
+			continue
+		}
+		if _, ok := inv.Hosts[l]; ok {
+			names[l] = true
+			continue
+		}
+		g, ok := inv.Groups[l]
+		if !ok {
+			return nil, fmt.Errorf("unknown host or group: %q", l)
+		}
+		if g != nil {
+			for _, h := range g.Hosts {
+				names[h] = true
+			}
+		}
+	}
+
+	targets := []*Target{}
+	for name := range names {
+		targets = append(targets, inv.target(name))
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i].Name < targets[j].Name
+	})
+	return targets, nil
+}
+
+// target returns the target of the named host. The groups are merged in the
+// order of the group names.
+func (inv *Inventory) target(name string) *Target {
+	t := &Target{
+		Name:    name,
+		Address: name,
+		Vars:    map[string]string{},
+	}
+
+	groups := []string{}
+	for g := range inv.Groups {
+		groups = append(groups, g)
+	}
+	sort.Strings(groups)
+	seen := map[string]bool{}
+	for _, gn := range groups {
+		g := inv.Groups[gn]
+		if g == nil || !contains(g.Hosts, name) {
+			continue
+		}
+		for _, r := range g.Recipes {
+			if !filepath.IsAbs(r) {
+				r = filepath.Join(inv.Dir, r)
+			}
+			if !seen[r] {
+				seen[r] = true
+				t.Recipes = append(t.Recipes, r)
+			}
+		}
+		for k, v := range g.Vars {
+			t.Vars[k] = v
+		}
+	}
+
+	if h := inv.Hosts[name]; h != nil {
+		if h.Address != "" {
+			t.Address = h.Address
+		}
+		for k, v := range h.Vars {
+			t.Vars[k] = v
+		}
+	}
+	return t
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
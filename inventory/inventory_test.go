@@ -0,0 +1,107 @@
+package inventory_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/harukasan/orchestra-pit/inventory"
+)
+
+const input = `
+hosts:
+  web1:
+    address: deploy@192.0.2.10
+    vars:
+      server_name: www1.example.com
+  web2:
+    address: deploy@192.0.2.11:2222
+  db1: {}
+groups:
+  web:
+    hosts: [web1, web2]
+    recipes: [web.json]
+    vars:
+      port: 8080
+      server_name: www.example.com
+  db:
+    hosts: [db1]
+    recipes: [db.json]
+`
+
+func readInventory(t *testing.T, data string) *inventory.Inventory {
+	dir, err := ioutil.TempDir("", "inventory_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "inv.yaml")
+	if err := ioutil.WriteFile(name, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	inv, err := inventory.ReadFile(name)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	return inv
+}
+
+func TestSelect(t *testing.T) {
+	inv := readInventory(t, input)
+
+	targets, err := inv.Select("web")
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if len(targets) != 2 {
+		t.Fatalf("got %d targets, expected 2", len(targets))
+	}
+	web1 := targets[0]
+	expected := &inventory.Target{
+		Name:    "web1",
+		Address: "deploy@192.0.2.10",
+		Recipes: []string{filepath.Join(inv.Dir, "web.json")},
+		Vars: map[string]string{
+			"port":        "8080",
+			"server_name": "www1.example.com",
+		},
+	}
+	if !reflect.DeepEqual(web1, expected) {
+		t.Errorf("got %+v, expected %+v", web1, expected)
+	}
+
+	targets, err = inv.Select("db1,web2")
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if len(targets) != 2 || targets[0].Name != "db1" || targets[1].Name != "web2" {
+		t.Errorf("got unexpected targets: %+v", targets)
+	}
+	if targets[0].Address != "db1" {
+		t.Errorf("got address %q, expected the host name", targets[0].Address)
+	}
+
+	targets, err = inv.Select("")
+	if err != nil || len(targets) != 3 {
+		t.Errorf("got %d targets, %v, expected all of the hosts", len(targets), err)
+	}
+
+	if _, err := inv.Select("mail"); err == nil {
+		t.Errorf("got no error for the unknown group")
+	}
+}
+
+func TestUnknownHost(t *testing.T) {
+	dir, err := ioutil.TempDir("", "inventory_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "inv.json")
+	ioutil.WriteFile(name, []byte(`{"groups": {"web": {"hosts": ["web1"]}}}`), 0644)
+	if _, err := inventory.ReadFile(name); err == nil {
+		t.Errorf("got no error for the unknown host")
+	}
+}
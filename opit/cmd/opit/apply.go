@@ -3,22 +3,34 @@ package main
 import (
 	"flag"
 	"os"
+	"path/filepath"
+	"reflect"
 	"time"
 
+	"github.com/harukasan/orchestra-pit/opit/lock"
 	"github.com/harukasan/orchestra-pit/opit/logger"
 	"github.com/harukasan/orchestra-pit/recipe"
 	"github.com/harukasan/orchestra-pit/resource"
+	"github.com/harukasan/orchestra-pit/state"
 )
 
 type apply struct {
 	*logging
-	DryRun bool
+	DryRun    bool
+	Root      string
+	Host      string
+	Inventory string
+	Limit     string
+	Parallel  int
+	Vars      vars
+	Lock      string
+	Wait      time.Duration
 }
 
 func applyCommand() *apply {
 	return &apply{
-		&logging{},
-		false,
+		logging: &logging{},
+		Vars:    vars{},
 	}
 }
 
@@ -29,6 +41,15 @@ func (c *apply) description() string {
 func (c *apply) run(args []string) int {
 	f := c.flags(args)
 	c.initLogging()
+	if c.Inventory != "" {
+		return c.runInventory("apply", f.Arg(0))
+	}
+	if c.Host != "" {
+		return c.runRemote("apply", f.Arg(0))
+	}
+	c.initRoot()
+	l := c.acquireLock()
+	defer l.Release()
 	logger.Infof("Started at %s", time.Now().Format("2006-01-02T15:04:05-07:00"))
 
 	wd, err := os.Getwd()
@@ -36,33 +57,128 @@ func (c *apply) run(args []string) int {
 		logger.Fatal(err)
 	}
 	name := f.Arg(0)
-	rec, err := recipe.ReadRecipe(name, wd)
+	rec, err := recipe.ReadRecipe(name, wd, c.Vars)
 	if err != nil {
 		logger.Fatal(err)
 	}
 
 	exit := 0
-	for _, res := range rec.Resources {
-		logger.Debugf("------ testing %s", res)
-		if err := resource.Test(res); err != nil {
-			logger.Debugf("failed to test: %s", err)
-		} else {
+	n := &notifier{recipe: rec}
+	for _, group := range resource.Batches(rec.Resources) {
+		pending := []resource.Resource{}
+		for _, res := range group {
+			logger.Debugf("------ testing %s", res)
+			if err := resource.Test(res); err != nil {
+				logger.Debugf("failed to test: %s", err)
+				pending = append(pending, res)
+				continue
+			}
 			logger.Infof("[ OK ] %s", res)
-			continue
 		}
-		logger.Debugf("------ applying %s", res)
-		if err := resource.Apply(res); err != nil {
-			exit = 1
-			logger.Debugf("failed to apply: %s", err)
-			logger.Errorf("[FAIL] %s", res)
-			continue
+
+		if len(pending) > 1 {
+			logger.Debugf("------ applying %d resources at once", len(pending))
+			err := resource.ApplyBatch(pending)
+			if err == nil {
+				for _, res := range pending {
+					logger.Infof("[DONE] %s", res)
+					n.add(res)
+				}
+				continue
+			}
+			logger.Debugf("failed to apply at once, retry one by one: %s", err)
+		}
+
+		for _, res := range pending {
+			logger.Debugf("------ applying %s", res)
+			if err := resource.Apply(res); err != nil {
+				exit = 1
+				logger.Debugf("failed to apply: %s", err)
+				logger.Errorf("[FAIL] %s", res)
+				continue
+			}
+			logger.Infof("[DONE] %s", res)
+			n.add(res)
 		}
-		logger.Infof("[DONE] %s", res)
 	}
 
+	if !n.run() {
+		exit = 1
+	}
 	return exit
 }
 
+// notifier queues the deferred states and the notifications of the applied
+// resources. Each of them is applied once at the end of the run, in the order
+// of queued. The deferred states precede the notifications.
+type notifier struct {
+	recipe   *recipe.Recipe
+	deferred []state.State
+	queue    []resource.Notification
+}
+
+// add queues the deferred states and the notifications of the applied
+// resource.
+func (n *notifier) add(res resource.Resource) {
+	if d, ok := res.(resource.Deferrer); ok {
+		for _, s := range d.DeferredStates() {
+			if !n.deferredState(s) {
+				n.deferred = append(n.deferred, s)
+			}
+		}
+	}
+	for _, no := range n.recipe.Notify[res] {
+		if !n.queued(no) {
+			n.queue = append(n.queue, no)
+		}
+	}
+}
+
+func (n *notifier) deferredState(s state.State) bool {
+	for _, d := range n.deferred {
+		if reflect.DeepEqual(d, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *notifier) queued(no resource.Notification) bool {
+	for _, q := range n.queue {
+		if q == no {
+			return true
+		}
+	}
+	return false
+}
+
+// run applies the deferred states, and takes the actions of the queued
+// notifications. It returns false if any of them fails.
+func (n *notifier) run() bool {
+	ok := true
+	for _, s := range n.deferred {
+		logger.Debugf("------ applying deferred state: %s", s)
+		if err := s.Apply(); err != nil {
+			ok = false
+			logger.Debugf("failed to apply: %s", err)
+			logger.Errorf("[FAIL] %s", s)
+			continue
+		}
+		logger.Infof("[DONE] %s", s)
+	}
+	for _, no := range n.queue {
+		logger.Debugf("------ notifying %s", no)
+		if err := resource.ApplyAction(n.recipe.Lookup(no.Resource), no.Action); err != nil {
+			ok = false
+			logger.Debugf("failed to take the action: %s", err)
+			logger.Errorf("[FAIL] %s", no)
+			continue
+		}
+		logger.Infof("[NOTIFY] %s", no)
+	}
+	return ok
+}
+
 func (c *apply) flags(args []string) *flag.FlagSet {
 	usage := `
 Usage: opit apply [recipe]
@@ -77,8 +193,57 @@ in the current directory.
 	f := flag.NewFlagSet("apply", flag.ExitOnError)
 	f.Usage = getCommandUsage(usage, f.PrintDefaults)
 	f.BoolVar(&c.DryRun, "dry-run", false, "report the commands that will have executed")
+	f.StringVar(&c.Root, "root", "", "apply the recipe to the system under the directory instead of /")
+	f.StringVar(&c.Lock, "lock", "", "the path of the lock file to prevent running concurrently (default "+lock.DefaultPath+")")
+	f.DurationVar(&c.Wait, "wait", 0, "wait for the lock to be released up to the duration, e.g. 5m")
+	c.remoteFlags(f)
 	c.loggingFlags(f)
 	f.Parse(args)
 
 	return f
 }
+
+// acquireLock acquires the lock of the target system. If the lock file is not
+// specified, the default lock file of the host is used even if the root
+// directory is specified, because the directory of the lock file may not exist
+// under the root directory.
+func (c *apply) acquireLock() *lock.Lock {
+	path := c.Lock
+	if path == "" {
+		path = lock.DefaultPath
+	}
+	l, err := lock.Acquire(path, c.Wait)
+	if err != nil {
+		logger.Fatalf("can not acquire the lock: %s", err)
+	}
+	logger.Debugf("acquired the lock %s", l.Path)
+	return l
+}
+
+// initRoot sets the root directory of the target system.
+func (c *apply) initRoot() {
+	if c.Root == "" {
+		return
+	}
+	root, err := filepath.Abs(c.Root)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		logger.Fatalf("can not use the root directory: %s", err)
+	}
+	if !info.IsDir() {
+		logger.Fatalf("can not use the root directory: %s is not a directory", root)
+	}
+	state.Root = root
+	logger.Debugf("the root directory is %s", state.Root)
+}
+
+func (c *apply) remoteFlags(f *flag.FlagSet) {
+	f.StringVar(&c.Host, "host", "", "run on the remote host over SSH, in the form of [user@]host[:port]")
+	f.StringVar(&c.Inventory, "inventory", "", "run on the hosts listed in the inventory file")
+	f.StringVar(&c.Limit, "limit", "", "run only on the comma separated hosts or groups of the inventory")
+	f.IntVar(&c.Parallel, "parallel", 5, "the number of the hosts to run at once")
+	f.Var(c.Vars, "var", "set the recipe variable in the form of name=value, can be repeated")
+}
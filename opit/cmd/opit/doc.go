@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+
+	"github.com/harukasan/orchestra-pit/opit/logger"
+	"github.com/harukasan/orchestra-pit/resource"
+	"github.com/harukasan/orchestra-pit/resource/doc"
+)
+
+type docCommand struct {
+	Format string
+	Output string
+}
+
+func (c *docCommand) description() string {
+	return "print the reference of the resource types"
+}
+
+func (c *docCommand) run(args []string) int {
+	usage := `
+Usage: opit doc [options]
+
+Print the reference of the resource types in Markdown or the man page, which
+is generated from the resource types.
+`
+
+	f := flag.NewFlagSet("doc", flag.ExitOnError)
+	f.Usage = getCommandUsage(usage, f.PrintDefaults)
+	f.StringVar(&c.Format, "format", "markdown", "the format of the reference, markdown or man")
+	f.StringVar(&c.Output, "o", "", "write the reference to the file instead of stdout")
+	f.Parse(args)
+
+	var w io.Writer = os.Stdout
+	if c.Output != "" {
+		file, err := os.Create(c.Output)
+		if err != nil {
+			logger.Fatalf("can not create the file: %s", err)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	var err error
+	switch c.Format {
+	case "markdown", "md":
+		err = doc.Markdown(w, resource.Types())
+	case "man":
+		err = doc.Man(w, resource.Types())
+	default:
+		logger.Errorf("unknown format: %s", c.Format)
+		return 1
+	}
+	if err != nil {
+		logger.Error(err)
+		return 1
+	}
+	return 0
+}
@@ -18,9 +18,12 @@ type command interface {
 }
 
 var commands = map[string]command{
-	"apply":   applyCommand(),
-	"test":    testCommand(),
-	"version": &version{},
+	"apply":    applyCommand(),
+	"doc":      &docCommand{},
+	"schema":   &schemaCommand{},
+	"test":     testCommand(),
+	"validate": validateCommand(),
+	"version":  &version{},
 }
 
 func main() {
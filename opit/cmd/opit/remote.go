@@ -0,0 +1,232 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/harukasan/orchestra-pit/inventory"
+	"github.com/harukasan/orchestra-pit/opit/logger"
+	"github.com/harukasan/orchestra-pit/opit/remote"
+	"github.com/harukasan/orchestra-pit/recipe"
+)
+
+// runRemote runs the command on the remote host specified by the host option.
+// It uploads opit, the recipe and the files directory, and returns the exit
+// status of the remote command.
+func (c *apply) runRemote(command string, name string) int {
+	host, err := remote.ParseHost(c.Host)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		logger.Fatal(err)
+	}
+	name, err = recipe.FindFile(name, wd)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	exit, err := c.runOn(command, host, name, c.Vars, "")
+	if err != nil {
+		logger.Errorf("can not run opit on %s: %s", host, err)
+		return 1
+	}
+	return exit
+}
+
+// runOn runs the command with the recipe and the variables on the host. The
+// prefix is prepended to the logs of the host.
+func (c *apply) runOn(command string, host *remote.Host, name string, vars map[string]string, prefix string) (int, error) {
+	files, err := recipeFiles(name, vars)
+	if err != nil {
+		return 0, err
+	}
+
+	logger.Debugf("%s------ connecting to %s", prefix, host)
+	s, err := remote.Open(host)
+	if err != nil {
+		return 0, err
+	}
+	s.Prefix = prefix
+	defer func() {
+		if err := s.Close(); err != nil {
+			logger.Warningf("%scan not remove %s on %s: %s", prefix, s.Dir, host, err)
+		}
+	}()
+
+	logger.Debugf("%s------ uploading %s to %s:%s", prefix, filepath.Base(name), host, s.Dir)
+	if err := s.Upload(files); err != nil {
+		return 0, err
+	}
+
+	args := []string{command, "-q", "-log-json", "/dev/stdout"}
+	if c.Verbose {
+		args = append(args, "-v")
+	}
+	if c.DryRun {
+		args = append(args, "-dry-run")
+	}
+	if c.Root != "" {
+		args = append(args, "-root", c.Root)
+	}
+	if command == "apply" {
+		if c.Lock != "" {
+			args = append(args, "-lock", c.Lock)
+		}
+		if c.Wait != 0 {
+			args = append(args, "-wait", c.Wait.String())
+		}
+	}
+	names := []string{}
+	for k := range vars {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		args = append(args, "-var", k+"="+vars[k])
+	}
+	args = append(args, filepath.Base(name))
+
+	return s.Run(args...)
+}
+
+// recipeFiles returns the files to upload with the recipe; the recipe files,
+// the role directories and the files directories read from the recipe. They
+// must be in the directory of the recipe.
+func recipeFiles(name string, vars map[string]string) ([]remote.File, error) {
+	rec, err := recipe.ReadRecipe(name, "", vars)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Dir(name)
+	sources := rec.Sources
+
+	files := []remote.File{}
+	for _, s := range sources {
+		if inDirs(s, sources) {
+			continue
+		}
+		rel, err := filepath.Rel(dir, s)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+			return nil, fmt.Errorf("can not upload %s which is out of the directory of the recipe", s)
+		}
+		files = append(files, remote.File{Name: rel, Path: s})
+	}
+	return files, nil
+}
+
+// inDirs returns whether the path is under any of the directories.
+func inDirs(path string, dirs []string) bool {
+	for _, d := range dirs {
+		if strings.HasPrefix(path, d+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// result is the result of the command on the host of the inventory.
+type result struct {
+	exit int
+	err  error
+}
+
+// runInventory runs the command on the hosts of the inventory which are
+// selected by the limit option. The hosts are run at once up to the number of
+// the parallel option. It prints the summary of the hosts at the end.
+func (c *apply) runInventory(command string, name string) int {
+	inv, err := inventory.ReadFile(c.Inventory)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	targets, err := inv.Select(c.Limit)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if len(targets) == 0 {
+		logger.Warningf("no hosts are selected")
+		return 0
+	}
+	if c.Parallel < 1 {
+		logger.Fatalf("parameter \"parallel\" must be 1 or more")
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	results := make([]result, len(targets))
+	sem := make(chan struct{}, c.Parallel)
+	wg := sync.WaitGroup{}
+	for i, t := range targets {
+		wg.Add(1)
+		go func(i int, t *inventory.Target) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = c.runTarget(command, t, name, wd)
+		}(i, t)
+	}
+	wg.Wait()
+
+	exit := 0
+	logger.Infof("------ summary")
+	for i, t := range targets {
+		r := results[i]
+		switch {
+		case r.err != nil:
+			exit = 1
+			logger.Errorf("%-20s error: %s", t.Name, r.err)
+		case r.exit != 0:
+			exit = 1
+			logger.Errorf("%-20s failed (exit status %d)", t.Name, r.exit)
+		default:
+			logger.Infof("%-20s ok", t.Name)
+		}
+	}
+	return exit
+}
+
+// runTarget runs the command with the recipes of the target. If the target has
+// no recipes, the named recipe is used. The variables of the command line
+// override the variables of the inventory. It stops at the first recipe which
+// fails.
+func (c *apply) runTarget(command string, t *inventory.Target, name string, wd string) result {
+	host, err := remote.ParseHost(t.Address)
+	if err != nil {
+		return result{err: err}
+	}
+
+	recipes := t.Recipes
+	if len(recipes) == 0 {
+		name, err := recipe.FindFile(name, wd)
+		if err != nil {
+			return result{err: err}
+		}
+		recipes = []string{name}
+	}
+
+	vars := map[string]string{}
+	for k, v := range t.Vars {
+		vars[k] = v
+	}
+	for k, v := range c.Vars {
+		vars[k] = v
+	}
+
+	prefix := "[" + t.Name + "] "
+	for _, r := range recipes {
+		exit, err := c.runOn(command, host, r, vars, prefix)
+		if err != nil || exit != 0 {
+			return result{exit: exit, err: err}
+		}
+	}
+	return result{}
+}
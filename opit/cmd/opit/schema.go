@@ -0,0 +1,36 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+
+	"github.com/harukasan/orchestra-pit/opit/logger"
+	"github.com/harukasan/orchestra-pit/resource/schema"
+)
+
+type schemaCommand struct{}
+
+func (c *schemaCommand) description() string {
+	return "print JSON Schema of the recipe file"
+}
+
+func (c *schemaCommand) run(args []string) int {
+	usage := `
+Usage: opit schema
+
+Print JSON Schema of the recipe file, which is generated from the resource
+types. Editors can use the schema to complete and validate the recipe.
+`
+
+	f := flag.NewFlagSet("schema", flag.ExitOnError)
+	f.Usage = getCommandUsage(usage, f.PrintDefaults)
+	f.Parse(args)
+
+	b, err := json.MarshalIndent(schema.Recipe(), "", "  ")
+	if err != nil {
+		logger.Fatal(err)
+	}
+	fmt.Println(string(b))
+	return 0
+}
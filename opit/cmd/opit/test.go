@@ -29,6 +29,13 @@ func (c *test) description() string {
 func (c *test) run(args []string) int {
 	f := c.flags(args)
 	c.initLogging()
+	if c.Inventory != "" {
+		return c.runInventory("test", f.Arg(0))
+	}
+	if c.Host != "" {
+		return c.runRemote("test", f.Arg(0))
+	}
+	c.initRoot()
 	logger.Infof("Started at %s", time.Now().Format("2006-01-02T15:04:05-07:00"))
 
 	wd, err := os.Getwd()
@@ -36,7 +43,7 @@ func (c *test) run(args []string) int {
 		logger.Fatal(err)
 	}
 	name := f.Arg(0)
-	rec, err := recipe.ReadRecipe(name, wd)
+	rec, err := recipe.ReadRecipe(name, wd, c.Vars)
 	if err != nil {
 		logger.Fatal(err)
 	}
@@ -69,6 +76,8 @@ recipe.[ext] in current directory.
 	f := flag.NewFlagSet("apply", flag.ExitOnError)
 	f.Usage = getCommandUsage(usage, f.PrintDefaults)
 	f.BoolVar(&c.DryRun, "dry-run", false, "report the commands that will have executed")
+	f.StringVar(&c.Root, "root", "", "test the system under the directory instead of /")
+	c.remoteFlags(f)
 	c.loggingFlags(f)
 	f.Parse(args)
 
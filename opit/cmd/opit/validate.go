@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/harukasan/orchestra-pit/opit/logger"
+	"github.com/harukasan/orchestra-pit/recipe"
+)
+
+type validate struct {
+	*logging
+	Vars vars
+}
+
+func validateCommand() *validate {
+	return &validate{
+		logging: &logging{},
+		Vars:    vars{},
+	}
+}
+
+func (c *validate) description() string {
+	return "check the recipe file without touching the host"
+}
+
+func (c *validate) run(args []string) int {
+	f := c.flags(args)
+	c.initLogging()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		logger.Fatal(err)
+	}
+	name := f.Arg(0)
+	errs := recipe.Validate(name, wd, c.Vars)
+	for _, err := range errs {
+		logger.Errorf("%s", err)
+	}
+	if len(errs) > 0 {
+		logger.Errorf("found %d errors", len(errs))
+		return 1
+	}
+	logger.Infof("the recipe is valid")
+	return 0
+}
+
+func (c *validate) flags(args []string) *flag.FlagSet {
+	usage := `
+Usage: opit validate [recipe]
+
+Check the recipe file, its includes and roles without touching the host.
+It reports the syntax errors, the unknown attributes and the invalid attributes
+of the resources with their positions.
+
+The recipe must have the following extensions: yaml, yml, json, or rb.
+If the recipe file is not specified, try to find the recipe file that is named
+recipe.[ext] in current directory.
+`
+
+	f := flag.NewFlagSet("validate", flag.ExitOnError)
+	f.Usage = getCommandUsage(usage, f.PrintDefaults)
+	f.Var(c.Vars, "var", "set the recipe variable in the form of name=value, can be repeated")
+	c.loggingFlags(f)
+	f.Parse(args)
+
+	return f
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// vars is a flag.Value which collects the recipe variables given in the form
+// of name=value.
+type vars map[string]string
+
+func (v vars) String() string {
+	s := []string{}
+	for name, value := range v {
+		s = append(s, name+"="+value)
+	}
+	sort.Strings(s)
+	return strings.Join(s, ",")
+}
+
+func (v vars) Set(s string) error {
+	i := strings.IndexByte(s, '=')
+	if i <= 0 {
+		return fmt.Errorf("the variable must be in the form of name=value: %q", s)
+	}
+	v[s[:i]] = s[i+1:]
+	return nil
+}
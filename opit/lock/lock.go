@@ -0,0 +1,168 @@
+// +build linux darwin dragonfly freebsd openbsd netbsd
+
+/*
+Package lock implements the exclusive lock of the host to prevent running opit
+concurrently.
+
+The lock is the advisory lock (flock) of the lock file. The lock file has the
+information of the owner, so the other process can report who holds the lock.
+Because the kernel releases the lock when the owner process exits, the lock
+file of the dead process is not held; it is reported as a stale lock and taken
+over.
+*/
+package lock
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/harukasan/orchestra-pit/opit/logger"
+)
+
+// DefaultPath specifies the path of the lock file which is used by default.
+var DefaultPath = "/var/run/opit.lock"
+
+// PollInterval specifies the interval to try the lock while waiting.
+var PollInterval = 100 * time.Millisecond
+
+// Owner describes the process which holds the lock.
+type Owner struct {
+	PID     int       `json:"pid"`
+	User    string    `json:"user"`
+	Started time.Time `json:"started"`
+}
+
+func (o *Owner) String() string {
+	return fmt.Sprintf("pid %d (user %s) since %s", o.PID, o.User, o.Started.Format("2006-01-02T15:04:05-07:00"))
+}
+
+// HeldError reports that the lock is held by another process. Owner is nil if
+// the owner is unknown.
+type HeldError struct {
+	Path  string
+	Owner *Owner
+}
+
+func (e *HeldError) Error() string {
+	if e.Owner == nil {
+		return fmt.Sprintf("the lock %s is held by another process", e.Path)
+	}
+	return fmt.Sprintf("the lock %s is held by %s", e.Path, e.Owner)
+}
+
+// Lock represents the acquired lock.
+type Lock struct {
+	Path string
+	file *os.File
+}
+
+// Acquire acquires the lock of the lock file. If the lock is held by another
+// process, it waits for the lock to be released up to the duration of wait.
+// If the lock can not be acquired, it returns *HeldError.
+func Acquire(path string, wait time.Duration) (*Lock, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(wait)
+	for {
+		err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			break
+		}
+		if err != syscall.EWOULDBLOCK {
+			f.Close()
+			return nil, err
+		}
+		if !time.Now().Before(deadline) {
+			owner, _ := readOwner(f)
+			f.Close()
+			return nil, &HeldError{Path: path, Owner: owner}
+		}
+		logger.Debugf("waiting for the lock %s", path)
+		time.Sleep(PollInterval)
+	}
+
+	if owner, err := readOwner(f); err == nil && owner != nil {
+		logger.Warningf("take over the stale lock %s of %s", path, owner)
+	}
+	if err := writeOwner(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &Lock{Path: path, file: f}, nil
+}
+
+// Release clears the owner and releases the lock. The lock file is not
+// removed, because another process may be waiting for the lock of the file.
+func (l *Lock) Release() error {
+	if err := l.file.Truncate(0); err != nil {
+		l.file.Close()
+		return err
+	}
+	return l.file.Close()
+}
+
+// Holder returns the owner of the lock file. It returns nil if the lock is not
+// held.
+func Holder(path string) (*Owner, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_SH|syscall.LOCK_NB); err == nil {
+		return nil, nil
+	}
+	return readOwner(f)
+}
+
+// readOwner reads the owner from the lock file. It returns nil if the file
+// has no owner.
+func readOwner(f *os.File) (*Owner, error) {
+	if _, err := f.Seek(0, 0); err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadAll(f)
+	if err != nil || len(b) == 0 {
+		return nil, err
+	}
+	o := &Owner{}
+	if err := json.Unmarshal(b, o); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// writeOwner writes the current process as the owner to the lock file.
+func writeOwner(f *os.File) error {
+	o := &Owner{
+		PID:     os.Getpid(),
+		User:    strconv.Itoa(os.Getuid()),
+		Started: time.Now(),
+	}
+	if u, err := user.Current(); err == nil {
+		o.User = u.Username
+	}
+	b, err := json.Marshal(o)
+	if err != nil {
+		return err
+	}
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := f.WriteAt(append(b, '\n'), 0); err != nil {
+		return err
+	}
+	return f.Sync()
+}
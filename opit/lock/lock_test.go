@@ -0,0 +1,103 @@
+// +build linux darwin dragonfly freebsd openbsd netbsd
+
+package lock_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/harukasan/orchestra-pit/opit/lock"
+)
+
+// lockPath returns the path of the lock in a temporary directory, and the
+// function to remove the directory.
+func lockPath(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "lock_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path.Join(dir, "opit.lock"), func() { os.RemoveAll(dir) }
+}
+
+func TestAcquire(t *testing.T) {
+	p, cleanup := lockPath(t)
+	defer cleanup()
+
+	l, err := lock.Acquire(p, 0)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+
+	_, err = lock.Acquire(p, 0)
+	e, ok := err.(*lock.HeldError)
+	if !ok {
+		t.Fatalf("got %v, expected HeldError", err)
+	}
+	if e.Owner == nil || e.Owner.PID != os.Getpid() {
+		t.Errorf("got owner %v, expected pid %d", e.Owner, os.Getpid())
+	}
+	if o, err := lock.Holder(p); err != nil || o == nil || o.PID != os.Getpid() {
+		t.Errorf("got holder %v, %v", o, err)
+	}
+
+	if err := l.Release(); err != nil {
+		t.Fatalf("got error on release: %v", err)
+	}
+	if o, err := lock.Holder(p); err != nil || o != nil {
+		t.Errorf("got holder %v, %v after release", o, err)
+	}
+	l, err = lock.Acquire(p, 0)
+	if err != nil {
+		t.Fatalf("got error after release: %v", err)
+	}
+	l.Release()
+}
+
+func TestAcquireWait(t *testing.T) {
+	p, cleanup := lockPath(t)
+	defer cleanup()
+	interval := lock.PollInterval
+	defer func() { lock.PollInterval = interval }()
+	lock.PollInterval = 10 * time.Millisecond
+
+	l, err := lock.Acquire(p, 0)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+
+	start := time.Now()
+	if _, err := lock.Acquire(p, 50*time.Millisecond); err == nil {
+		t.Fatalf("got no error while the lock is held")
+	}
+	if d := time.Since(start); d < 50*time.Millisecond {
+		t.Errorf("gave up waiting after %s", d)
+	}
+
+	time.AfterFunc(30*time.Millisecond, func() { l.Release() })
+	l2, err := lock.Acquire(p, time.Second)
+	if err != nil {
+		t.Fatalf("got error while waiting: %v", err)
+	}
+	l2.Release()
+}
+
+func TestAcquireStale(t *testing.T) {
+	p, cleanup := lockPath(t)
+	defer cleanup()
+	stale := `{"pid": 99999999, "user": "nobody", "started": "2015-07-23T10:51:16+09:00"}`
+	if err := ioutil.WriteFile(p, []byte(stale), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := lock.Acquire(p, 0)
+	if err != nil {
+		t.Fatalf("got error on the stale lock: %v", err)
+	}
+	defer l.Release()
+	if o, err := lock.Holder(p); err != nil || o == nil || o.PID != os.Getpid() {
+		t.Errorf("got holder %v, %v, expected the current process", o, err)
+	}
+}
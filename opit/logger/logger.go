@@ -88,18 +88,22 @@ func (l *Logger) AddOutput(out Output) {
 func (l *Logger) RemoveOutput(out Output) {
 	for i, o := range l.outs {
 		if o == out {
-			if i == 0 {
-				l.outs = l.outs[1:]
-			} else if i < (len(l.outs) - 1) {
-				l.outs = append(l.outs[:i-1], l.outs[i+1:]...)
-			} else {
-				l.outs = l.outs[:i-1]
-			}
+			l.outs = append(l.outs[:i], l.outs[i+1:]...)
 			break
 		}
 	}
 }
 
+// WriteEntry writes the entry to the outputs as it is. It is used to relay the
+// entries which are logged by another process.
+func (l *Logger) WriteEntry(e *Entry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, out := range l.outs {
+		out.WriteEntry(e)
+	}
+}
+
 // Debug writes message at debug level. The arguments are handled in the manner
 // of fmt.Print.
 func (l *Logger) Debug(v ...interface{}) {
@@ -206,6 +210,11 @@ func RemoveOutput(out Output) {
 	std.RemoveOutput(out)
 }
 
+// WriteEntry writes the entry to the outputs of the standard logger as it is.
+func WriteEntry(e *Entry) {
+	std.WriteEntry(e)
+}
+
 // Debug writes message to standard logger at debug level. The arguments are
 // handled in the manner of fmt.Print.
 func Debug(v ...interface{}) {
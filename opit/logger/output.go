@@ -138,5 +138,5 @@ func formatJSON(e *Entry) []byte {
 	if err != nil {
 		panic(err)
 	}
-	return b
+	return append(b, '\n')
 }
@@ -0,0 +1,303 @@
+// Copyright 2015 MICHII Shunsuke. All rights reserved.
+
+/*
+Package remote runs opit on the remote host over SSH.
+
+A Session uploads the opit binary and the recipe to a temporary directory on
+the host, runs opit in the directory and relays its logs to the logger of the
+local process. The host must have the same OS and the architecture as the local
+host, because the running binary is uploaded as it is.
+
+	s, err := remote.Open(host)
+	if err != nil {
+		...
+	}
+	defer s.Close()
+	if err := s.Upload(files); err != nil {
+		...
+	}
+	code, err := s.Run("apply", "recipe.json")
+*/
+package remote
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/harukasan/orchestra-pit/opit/logger"
+	"github.com/harukasan/orchestra-pit/state/exec"
+)
+
+// SSHPath specifies the path of ssh command.
+var SSHPath = "/usr/bin/ssh"
+
+// Runner specifies the CommandRunner to run ssh command.
+var Runner = exec.DefaultRunner
+
+// BinaryName is the name of the uploaded opit binary in the session directory.
+const BinaryName = "opit"
+
+// Host represents the remote host in the form of [user@]name[:port].
+type Host struct {
+	User string
+	Name string
+	Port string
+}
+
+// ParseHost parses the string s as a Host.
+func ParseHost(s string) (*Host, error) {
+	h := &Host{}
+	if i := strings.LastIndexByte(s, '@'); i >= 0 {
+		h.User = s[:i]
+		s = s[i+1:]
+	}
+	if i := strings.LastIndexByte(s, ':'); i >= 0 && !strings.HasSuffix(s, "]") {
+		h.Port = s[i+1:]
+		s = s[:i]
+	}
+	h.Name = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	if h.Name == "" {
+		return nil, fmt.Errorf("invalid host: %q", s)
+	}
+	return h, nil
+}
+
+func (h *Host) String() string {
+	s := h.Name
+	if h.User != "" {
+		s = h.User + "@" + s
+	}
+	if h.Port != "" {
+		s = s + ":" + h.Port
+	}
+	return s
+}
+
+// sshArgs returns the arguments of ssh command to run the command line on the
+// host.
+func (h *Host) sshArgs(cmdline string) []string {
+	args := []string{"-o", "BatchMode=yes"}
+	if h.Port != "" {
+		args = append(args, "-p", h.Port)
+	}
+	if h.User != "" {
+		args = append(args, "-l", h.User)
+	}
+	return append(args, h.Name, "--", cmdline)
+}
+
+// File describes the local file or directory to upload. Name is the path
+// relative to the session directory, and Path is the path of the local file.
+// The directory is uploaded recursively.
+type File struct {
+	Name string
+	Path string
+}
+
+// Session represents the temporary directory on the host.
+//
+// Prefix is prepended to the messages of the relayed logs, to distinguish the
+// hosts on running several sessions at once.
+type Session struct {
+	Host   *Host
+	Dir    string
+	Prefix string
+}
+
+// Open checks whether the host can run the local opit binary, and creates the
+// temporary directory on the host.
+func Open(h *Host) (*Session, error) {
+	s := &Session{Host: h}
+	out, err := s.command("uname -sm", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPlatform(strings.TrimSpace(string(out))); err != nil {
+		return nil, err
+	}
+	out, err = s.command("mktemp -d /tmp/opit.XXXXXXXX", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	s.Dir = strings.TrimSpace(string(out))
+	if s.Dir == "" {
+		return nil, fmt.Errorf("can not create the temporary directory on %s", h)
+	}
+	return s, nil
+}
+
+var machines = map[string]string{
+	"x86_64":  "amd64",
+	"amd64":   "amd64",
+	"i386":    "386",
+	"i686":    "386",
+	"aarch64": "arm64",
+	"arm64":   "arm64",
+	"armv6l":  "arm",
+	"armv7l":  "arm",
+}
+
+// checkPlatform tests whether the output of "uname -sm" matches the platform
+// of the running binary.
+func checkPlatform(uname string) error {
+	f := strings.Fields(uname)
+	if len(f) != 2 {
+		return fmt.Errorf("unexpected output of uname: %q", uname)
+	}
+	goos, goarch := strings.ToLower(f[0]), machines[f[1]]
+	if goos != runtime.GOOS || goarch != runtime.GOARCH {
+		return fmt.Errorf("opit for %s/%s can not run on the host: %s", runtime.GOOS, runtime.GOARCH, uname)
+	}
+	return nil
+}
+
+// Upload uploads the running opit binary and the files to the session
+// directory.
+func (s *Session) Upload(files []File) error {
+	bin, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	files = append([]File{{Name: BinaryName, Path: bin}}, files...)
+
+	buf := &bytes.Buffer{}
+	w := tar.NewWriter(buf)
+	for _, f := range files {
+		if err := addFile(w, f.Name, f.Path); err != nil {
+			return err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	_, err = s.command("tar -x -f - -C "+exec.ShellEscape(s.Dir), buf, nil)
+	return err
+}
+
+// addFile writes the file or the directory to the tar archive.
+func addFile(w *tar.Writer, name string, path string) error {
+	return filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(path, p)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && !info.Mode().IsRegular() {
+			logger.Debugf("skip uploading the file: %s", p)
+			return nil
+		}
+		h, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		h.Name = filepath.ToSlash(filepath.Join(name, rel))
+		if err := w.WriteHeader(h); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(w, f)
+		return err
+	})
+}
+
+// Run runs the uploaded opit binary in the session directory with the
+// arguments, and relays the logs to the logger. It returns the exit status of
+// the remote opit.
+func (s *Session) Run(args ...string) (int, error) {
+	cmdline := "cd " + exec.ShellEscape(s.Dir) + " && ./" + BinaryName
+	for _, a := range args {
+		cmdline += " " + exec.ShellEscape(a)
+	}
+
+	w := &entryWriter{prefix: s.Prefix}
+	_, err := s.command(cmdline, nil, w)
+	w.Flush()
+	if e, ok := err.(*exec.ExitError); ok {
+		return e.ExitCode, nil
+	}
+	return 0, err
+}
+
+// Close removes the session directory from the host.
+func (s *Session) Close() error {
+	if s.Dir == "" {
+		return nil
+	}
+	_, err := s.command("rm -rf "+exec.ShellEscape(s.Dir), nil, nil)
+	return err
+}
+
+// command runs the command line on the host. If stdout is not nil, the output
+// is written to it.
+func (s *Session) command(cmdline string, stdin io.Reader, stdout io.Writer) ([]byte, error) {
+	c := exec.Command(SSHPath, s.Host.sshArgs(cmdline)...)
+	c.Stdin = stdin
+	c.Stdout = stdout
+	out, _, err := Runner.Run(c)
+	if stdout != nil && len(out) > 0 {
+		// some runners return the output instead of writing it.
+		stdout.Write(out)
+	}
+	if e, ok := err.(*exec.ExitError); ok && e.ExitCode == 255 {
+		return out, fmt.Errorf("can not connect to %s: %s", s.Host, strings.TrimSpace(string(e.Stderr)))
+	}
+	return out, err
+}
+
+// entryWriter decodes the lines of the JSON log and writes the entries to the
+// logger. The line which is not an entry is logged at info level.
+type entryWriter struct {
+	buf    bytes.Buffer
+	prefix string
+}
+
+func (w *entryWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	for {
+		i := bytes.IndexByte(w.buf.Bytes(), '\n')
+		if i < 0 {
+			break
+		}
+		w.writeLine(w.buf.Next(i + 1))
+	}
+	return len(p), nil
+}
+
+// Flush writes the incomplete line.
+func (w *entryWriter) Flush() {
+	if w.buf.Len() > 0 {
+		w.writeLine(w.buf.Bytes())
+		w.buf.Reset()
+	}
+}
+
+func (w *entryWriter) writeLine(line []byte) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return
+	}
+	e := &logger.Entry{}
+	if err := json.Unmarshal(line, e); err != nil || e.Time.IsZero() {
+		logger.Info(w.prefix + string(line))
+		return
+	}
+	e.Message = w.prefix + e.Message
+	logger.WriteEntry(e)
+}
@@ -0,0 +1,349 @@
+// Copyright 2015 MICHII Shunsuke. All rights reserved.
+
+package remote_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	osexec "os/exec"
+	"os/user"
+	"path"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/harukasan/orchestra-pit/opit/logger"
+	"github.com/harukasan/orchestra-pit/opit/remote"
+	"github.com/harukasan/orchestra-pit/state/exec/testutil"
+)
+
+func TestParseHost(t *testing.T) {
+	tests := []struct {
+		in   string
+		want remote.Host
+	}{
+		{"server", remote.Host{Name: "server"}},
+		{"user@server", remote.Host{User: "user", Name: "server"}},
+		{"user@server:2222", remote.Host{User: "user", Name: "server", Port: "2222"}},
+		{"[::1]", remote.Host{Name: "::1"}},
+		{"[::1]:22", remote.Host{Name: "::1", Port: "22"}},
+	}
+	for _, tt := range tests {
+		h, err := remote.ParseHost(tt.in)
+		if err != nil {
+			t.Errorf("%q: got error: %v", tt.in, err)
+			continue
+		}
+		if *h != tt.want {
+			t.Errorf("%q: got %+v, expected %+v", tt.in, *h, tt.want)
+		}
+	}
+
+	if _, err := remote.ParseHost("user@"); err == nil {
+		t.Errorf("got no error for the empty host name")
+	}
+}
+
+// uname returns the output of "uname -sm" on the local platform.
+func uname() string {
+	machine := map[string]string{
+		"amd64": "x86_64",
+		"386":   "i686",
+		"arm64": "aarch64",
+		"arm":   "armv7l",
+	}[runtime.GOARCH]
+	return runtime.GOOS + " " + machine + "\n"
+}
+
+func ssh(cmdline string) []string {
+	return []string{remote.SSHPath, "-o", "BatchMode=yes", "-p", "2222", "-l", "user", "server", "--", cmdline}
+}
+
+// entries records the logged entries.
+type entries []*logger.Entry
+
+func (o *entries) WriteEntry(e *logger.Entry) {
+	*o = append(*o, e)
+}
+
+func TestSession(t *testing.T) {
+	r := testutil.NewFakeRunner()
+	orig := remote.Runner
+	remote.Runner = r
+	defer func() { remote.Runner = orig }()
+
+	r.On(ssh("uname -sm")...).Return(uname(), "", 0)
+	r.On(ssh("mktemp -d /tmp/opit.XXXXXXXX")...).Return("/tmp/opit.abcd\n", "", 0)
+	r.On(ssh("tar -x -f - -C /tmp/opit.abcd")...).Return("", "", 0)
+	r.On(ssh("cd /tmp/opit.abcd && ./opit apply -q recipe.json")...).Return(
+		`{"Time":"2015-07-23T10:51:16+09:00","Level":5,"Message":"[DONE] sl"}`+"\n"+
+			`{"Time":"2015-07-23T10:51:17+09:00","Level":2,"Message":"[FAIL] vim"}`+"\n",
+		"", 1)
+	r.On(ssh("rm -rf /tmp/opit.abcd")...).Return("", "", 0)
+
+	h, _ := remote.ParseHost("user@server:2222")
+	s, err := remote.Open(h)
+	if err != nil {
+		t.Fatalf("got error on open: %v", err)
+	}
+	if s.Dir != "/tmp/opit.abcd" {
+		t.Errorf("got dir %q", s.Dir)
+	}
+
+	dir, err := ioutil.TempDir("", "remote_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(path.Join(dir, "recipe.json"), []byte("{}"), 0644)
+	os.Mkdir(path.Join(dir, "files"), 0755)
+	ioutil.WriteFile(path.Join(dir, "files", "motd"), []byte("hello"), 0644)
+
+	err = s.Upload([]remote.File{
+		{Name: "recipe.json", Path: path.Join(dir, "recipe.json")},
+		{Name: "files", Path: path.Join(dir, "files")},
+	})
+	if err != nil {
+		t.Fatalf("got error on upload: %v", err)
+	}
+	names := []string{}
+	tr := tar.NewReader(bytes.NewBufferString(r.Calls[2].Stdin))
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("got error on reading the uploaded archive: %v", err)
+		}
+		names = append(names, h.Name)
+	}
+	sort.Strings(names)
+	expected := []string{"files", "files/motd", "opit", "recipe.json"}
+	if strings.Join(names, " ") != strings.Join(expected, " ") {
+		t.Errorf("uploaded %v, expected %v", names, expected)
+	}
+
+	out := &entries{}
+	logger.AddOutput(out)
+	code, err := s.Run("apply", "-q", "recipe.json")
+	logger.RemoveOutput(out)
+	if err != nil {
+		t.Fatalf("got error on run: %v", err)
+	}
+	if code != 1 {
+		t.Errorf("got exit status %d, expected 1", code)
+	}
+	if len(*out) != 2 {
+		t.Fatalf("got %d entries, expected 2", len(*out))
+	}
+	if e := (*out)[1]; e.Level != logger.ErrorLevel || e.Message != "[FAIL] vim" {
+		t.Errorf("got entry %+v", e)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Errorf("got error on close: %v", err)
+	}
+}
+
+func TestOpenUnreachable(t *testing.T) {
+	r := testutil.NewFakeRunner()
+	orig := remote.Runner
+	remote.Runner = r
+	defer func() { remote.Runner = orig }()
+
+	r.On(ssh("uname -sm")...).Return("", "Connection refused", 255)
+
+	h, _ := remote.ParseHost("user@server:2222")
+	_, err := remote.Open(h)
+	if err == nil || !strings.Contains(err.Error(), "Connection refused") {
+		t.Errorf("got %v, expected a connection error", err)
+	}
+}
+
+// TestLocalSSH runs the test binary on the host specified by
+// OPIT_TEST_SSH_HOST, e.g., a local sshd.
+func TestLocalSSH(t *testing.T) {
+	host := os.Getenv("OPIT_TEST_SSH_HOST")
+	if host == "" {
+		t.Skip("OPIT_TEST_SSH_HOST is not set")
+	}
+	h, err := remote.ParseHost(host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := remote.Open(h)
+	if err != nil {
+		t.Fatalf("got error on open: %v", err)
+	}
+	defer s.Close()
+	if err := s.Upload(nil); err != nil {
+		t.Fatalf("got error on upload: %v", err)
+	}
+	code, err := s.Run("-test.run", "^$")
+	if err != nil || code != 0 {
+		t.Errorf("got exit status %d, %v", code, err)
+	}
+}
+
+// startSSHD starts sshd on a free port of the loopback with the keys generated
+// for the test, and returns the host to connect as the current user and the
+// function to stop it. ssh command is replaced by the script which uses the
+// generated key. The test is skipped if sshd, ssh or ssh-keygen is not found,
+// or sshd can not run on the host.
+func startSSHD(t *testing.T) (*remote.Host, func()) {
+	sshd, err := osexec.LookPath("sshd")
+	if err != nil {
+		if _, err := os.Stat("/usr/sbin/sshd"); err != nil {
+			t.Skip("sshd is not found")
+		}
+		sshd = "/usr/sbin/sshd"
+	}
+	ssh, err := osexec.LookPath("ssh")
+	if err != nil {
+		t.Skip("ssh is not found")
+	}
+	keygen, err := osexec.LookPath("ssh-keygen")
+	if err != nil {
+		t.Skip("ssh-keygen is not found")
+	}
+	u, err := user.Current()
+	if err != nil {
+		t.Skipf("can not get the current user: %v", err)
+	}
+
+	dir, err := ioutil.TempDir("", "remote_test_sshd_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"host_key", "id_key"} {
+		out, err := osexec.Command(keygen, "-q", "-t", "ed25519", "-N", "", "-f", path.Join(dir, key)).CombinedOutput()
+		if err != nil {
+			os.RemoveAll(dir)
+			t.Fatalf("ssh-keygen: %v: %s", err, out)
+		}
+	}
+	pub, err := ioutil.ReadFile(path.Join(dir, "id_key.pub"))
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	files := map[string]string{
+		"authorized_keys": string(pub),
+		"sshd_config": fmt.Sprintf("Port %d\n"+
+			"ListenAddress 127.0.0.1\n"+
+			"HostKey %s/host_key\n"+
+			"AuthorizedKeysFile %s/authorized_keys\n"+
+			"PidFile %s/sshd.pid\n"+
+			"PasswordAuthentication no\n"+
+			"PermitRootLogin yes\n"+
+			"StrictModes no\n"+
+			"UsePAM no\n", port, dir, dir, dir),
+		"ssh_config": fmt.Sprintf("IdentityFile %s/id_key\n"+
+			"IdentitiesOnly yes\n"+
+			"UserKnownHostsFile /dev/null\n"+
+			"StrictHostKeyChecking no\n"+
+			"LogLevel ERROR\n", dir),
+		"ssh": fmt.Sprintf("#!/bin/sh\nexec %s -F %s/ssh_config \"$@\"\n", ssh, dir),
+	}
+	for name, data := range files {
+		if err := ioutil.WriteFile(path.Join(dir, name), []byte(data), 0700); err != nil {
+			os.RemoveAll(dir)
+			t.Fatal(err)
+		}
+	}
+
+	stderr := &bytes.Buffer{}
+	c := osexec.Command(sshd, "-D", "-e", "-f", path.Join(dir, "sshd_config"))
+	c.Stderr = stderr
+	if err := c.Start(); err != nil {
+		os.RemoveAll(dir)
+		t.Skipf("can not start sshd: %v", err)
+	}
+	exited := make(chan struct{})
+	go func() {
+		c.Wait()
+		close(exited)
+	}()
+	orig := remote.SSHPath
+	remote.SSHPath = path.Join(dir, "ssh")
+	cleanup := func() {
+		remote.SSHPath = orig
+		c.Process.Kill()
+		<-exited
+		os.RemoveAll(dir)
+	}
+
+	addr := fmt.Sprintf("127.0.0.1:%d", port)
+	for i := 0; ; i++ {
+		select {
+		case <-exited:
+			cleanup()
+			t.Skipf("sshd can not run on the host: %s", stderr)
+		default:
+		}
+		if conn, err := net.Dial("tcp", addr); err == nil {
+			conn.Close()
+			break
+		}
+		if i == 50 {
+			cleanup()
+			t.Fatalf("sshd does not listen on %s: %s", addr, stderr)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return &remote.Host{User: u.Username, Name: "127.0.0.1", Port: fmt.Sprint(port)}, cleanup
+}
+
+// TestSSHD runs the session against sshd started for the test, and runs the
+// uploaded test binary on it.
+func TestSSHD(t *testing.T) {
+	h, cleanup := startSSHD(t)
+	defer cleanup()
+
+	s, err := remote.Open(h)
+	if err != nil {
+		t.Fatalf("got error on open: %v", err)
+	}
+
+	dir, err := ioutil.TempDir("", "remote_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(path.Join(dir, "recipe.json"), []byte("{}"), 0644)
+	if err := s.Upload([]remote.File{{Name: "recipe.json", Path: path.Join(dir, "recipe.json")}}); err != nil {
+		t.Fatalf("got error on upload: %v", err)
+	}
+	if _, err := os.Stat(path.Join(s.Dir, "recipe.json")); err != nil {
+		t.Errorf("the recipe is not uploaded: %v", err)
+	}
+
+	code, err := s.Run("-test.run", "^$")
+	if err != nil || code != 0 {
+		t.Errorf("got exit status %d, %v", code, err)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Errorf("got error on close: %v", err)
+	}
+	if _, err := os.Stat(s.Dir); !os.IsNotExist(err) {
+		t.Errorf("the session directory %s is not removed: %v", s.Dir, err)
+	}
+}
@@ -1,50 +1,387 @@
 package recipe
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"reflect"
+	"strings"
 
 	"github.com/harukasan/orchestra-pit/resource"
 )
 
-// ParseJSON parses the recipe file serialized by JSON.
-func ParseJSON(data []byte) (*Recipe, error) {
+// itemsKeys are the attributes which expand the resource for each item. The
+// item is referred as {{ .item }} in the attributes of the resource.
+var itemsKeys = []string{"with_items", "for_each"}
+
+// notifyKey is the attribute which has the notifications of the resource. The
+// notifications are an object or a list of the objects.
+const notifyKey = "notify"
+
+// ParseJSON parses the recipe file serialized by JSON. The references to the
+// variables in the strings of the recipe are replaced, see Render.
+//
+// The resource which has the with_items (or for_each) attribute is expanded
+// into the resources for each item. The items are a list, or a string which
+// refers the variable. The value of the variable is a JSON array or a comma
+// separated list.
+func ParseJSON(data []byte, vars map[string]string) (*Recipe, error) {
+	r, errs := parseJSON(data, vars, false)
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+	return r, nil
+}
+
+// parseJSON parses the recipe file serialized by JSON, and returns all of the
+// errors of the resources. The errors have the positions in the data. On the
+// strict mode, it rejects the unknown attributes and validates the resources.
+func parseJSON(data []byte, vars map[string]string, strict bool) (*Recipe, []error) {
 	// unmarshal only the root node of the JSON.
 	var root struct {
 		Config    map[string]string
+		Include   []string          `json:"include"`
+		Roles     []Role            `json:"roles"`
+		FilesDir  string            `json:"files_dir"`
+		RolesPath []string          `json:"roles_path"`
 		Resources []json.RawMessage `json:"resources"`
 	}
 	if err := json.Unmarshal(data, &root); err != nil {
-		return nil, err
+		e := &Error{Err: fmt.Errorf("can not parse the JSON file: %s", err)}
+		switch err := err.(type) {
+		case *json.SyntaxError:
+			e.Line, e.Pos = getCaretPos(data, int(err.Offset))
+		case *json.UnmarshalTypeError:
+			e.Line, e.Pos = getCaretPos(data, int(err.Offset))
+		}
+		return nil, []error{e}
 	}
 
+	errs := []error{}
+	d := templateData(vars)
 	recipe := &Recipe{
 		Config: root.Config,
+		Notify: map[resource.Resource][]resource.Notification{},
+	}
+	for k, v := range recipe.Config {
+		r, err := Render(v, d)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		recipe.Config[k] = r
+	}
+	for _, inc := range root.Include {
+		r, err := Render(inc, d)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		recipe.Include = append(recipe.Include, r)
 	}
-	for _, r := range root.Resources {
-		// unmarshal only the type attribute
-		var attr struct {
-			Type string `json:"type"`
+	if root.FilesDir != "" {
+		r, err := Render(root.FilesDir, d)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		recipe.FilesDir = r
+	}
+	for _, p := range root.RolesPath {
+		r, err := Render(p, d)
+		if err != nil {
+			errs = append(errs, err)
+			continue
 		}
-		if err := json.Unmarshal(r, &attr); err != nil {
+		recipe.RolesPath = append(recipe.RolesPath, r)
+	}
+	for _, role := range root.Roles {
+		for k, v := range role.Vars {
+			r, err := Render(v, d)
+			if err != nil {
+				errs = append(errs, err)
+			}
+			role.Vars[k] = r
+		}
+		recipe.Roles = append(recipe.Roles, role)
+	}
+
+	offsets := resourceOffsets(data)
+	for i, r := range root.Resources {
+		resources, err := parseResource(r, d, strict, recipe.Notify)
+		if err != nil {
+			e := &Error{Err: fmt.Errorf("resource #%d: %s", i+1, err)}
+			if i < len(offsets) {
+				e.Line, e.Pos = getCaretPos(data, offsets[i])
+			}
+			errs = append(errs, e)
+			continue
+		}
+		recipe.Resources = append(recipe.Resources, resources...)
+	}
+	return recipe, errs
+}
+
+// parseResource parses the resource, and expands it for each item. The
+// notifications of the resources are recorded in notify. On the strict mode,
+// the resources are validated.
+func parseResource(r json.RawMessage, d map[string]interface{}, strict bool, notify map[resource.Resource][]resource.Notification) ([]resource.Resource, error) {
+	var attrs map[string]interface{}
+	if err := json.Unmarshal(r, &attrs); err != nil {
+		return nil, err
+	}
+	resources, err := expandResource(attrs, d, strict, notify)
+	if err != nil {
+		return nil, err
+	}
+	if strict {
+		for _, res := range resources {
+			if err := resource.Validate(res); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return resources, nil
+}
+
+// resourceOffsets returns the offsets of the resources in the JSON data. It
+// returns nil if the data can not be parsed.
+func resourceOffsets(data []byte) []int {
+	d := json.NewDecoder(bytes.NewReader(data))
+	if t, err := d.Token(); err != nil || t != json.Delim('{') {
+		return nil
+	}
+	for d.More() {
+		key, err := d.Token()
+		if err != nil {
+			return nil
+		}
+		if k, ok := key.(string); !ok || !strings.EqualFold(k, "resources") {
+			var v json.RawMessage
+			if err := d.Decode(&v); err != nil {
+				return nil
+			}
+			continue
+		}
+		if t, err := d.Token(); err != nil || t != json.Delim('[') {
+			return nil
+		}
+		offsets := []int{}
+		for d.More() {
+			off := int(d.InputOffset())
+			for off < len(data) && strings.IndexByte(" \t\r\n,", data[off]) >= 0 {
+				off++
+			}
+			offsets = append(offsets, off)
+			var v json.RawMessage
+			if err := d.Decode(&v); err != nil {
+				return nil
+			}
+		}
+		return offsets
+	}
+	return nil
+}
+
+// expandResource renders the attributes of the resource, and expands it for
+// each item if it has the items.
+func expandResource(attrs map[string]interface{}, d map[string]interface{}, strict bool, notify map[resource.Resource][]resource.Notification) ([]resource.Resource, error) {
+	items, err := popItems(attrs, d)
+	if err != nil {
+		return nil, err
+	}
+	if items == nil {
+		res, n, err := renderResource(attrs, d, strict)
+		if err != nil {
 			return nil, err
 		}
+		if len(n) > 0 {
+			notify[res] = n
+		}
+		return []resource.Resource{res}, nil
+	}
 
-		res, err := unmarshalResource(r, attr.Type)
+	resources := []resource.Resource{}
+	for _, item := range items {
+		id := make(map[string]interface{}, len(d)+1)
+		for k, v := range d {
+			id[k] = v
+		}
+		id["item"] = item
+		res, n, err := renderResource(attrs, id, strict)
 		if err != nil {
 			return nil, err
 		}
-		recipe.Resources = append(recipe.Resources, res)
+		if len(n) > 0 {
+			notify[res] = n
+		}
+		resources = append(resources, res)
 	}
-	return recipe, nil
+	return resources, nil
 }
 
-func unmarshalResource(j json.RawMessage, t string) (resource.Resource, error) {
+// popItems removes the items attribute from the attributes, and returns the
+// items. It returns nil if the resource has no items.
+func popItems(attrs map[string]interface{}, d map[string]interface{}) ([]interface{}, error) {
+	key := ""
+	for _, k := range itemsKeys {
+		if _, ok := attrs[k]; !ok {
+			continue
+		}
+		if key != "" {
+			return nil, fmt.Errorf(`parameter "%s" and "%s" can not be used together`, key, k)
+		}
+		key = k
+	}
+	if key == "" {
+		return nil, nil
+	}
+	v := attrs[key]
+	delete(attrs, key)
+
+	switch v := v.(type) {
+	case []interface{}:
+		items, err := renderValue(v, d)
+		if err != nil {
+			return nil, err
+		}
+		return items.([]interface{}), nil
+	case string:
+		s, err := Render(v, d)
+		if err != nil {
+			return nil, err
+		}
+		if value, ok := d[s].(string); ok && s == v {
+			s = value
+		}
+		return parseItems(s)
+	}
+	return nil, fmt.Errorf(`parameter "%s" must be a list or a variable`, key)
+}
+
+// parseItems parses the string as a JSON array or a comma separated list.
+func parseItems(s string) ([]interface{}, error) {
+	s = strings.TrimSpace(s)
+	items := []interface{}{}
+	if strings.HasPrefix(s, "[") {
+		if err := json.Unmarshal([]byte(s), &items); err != nil {
+			return nil, fmt.Errorf("can not parse the items: %s", err)
+		}
+		return items, nil
+	}
+	for _, i := range strings.Split(s, ",") {
+		if i = strings.TrimSpace(i); i != "" {
+			items = append(items, i)
+		}
+	}
+	return items, nil
+}
+
+// renderResource renders the attributes, and unmarshals them into the
+// resource of the type attribute. It returns the notifications of the resource
+// with it. The templates of the resource are rendered with the same data. The
+// raw attributes are passed as they are, see rawAttributes. On the strict
+// mode, the unknown attributes are rejected.
+func renderResource(attrs map[string]interface{}, d map[string]interface{}, strict bool) (resource.Resource, []resource.Notification, error) {
+	t, _ := attrs["type"].(string)
+	raw := rawAttributes(t)
+	rendered := make(map[string]interface{}, len(attrs))
+	for k, e := range attrs {
+		if !raw[k] {
+			rendered[k] = e
+		}
+	}
+	v, err := renderValue(rendered, d)
+	if err != nil {
+		return nil, nil, err
+	}
+	m := v.(map[string]interface{})
+	for k := range raw {
+		if e, ok := attrs[k]; ok {
+			m[k] = e
+		}
+	}
+	delete(m, "type")
+	notify, err := popNotify(m)
+	if err != nil {
+		return nil, nil, err
+	}
+	j, err := json.Marshal(m)
+	if err != nil {
+		return nil, nil, err
+	}
+	res, err := unmarshalResource(j, t, strict)
+	if err != nil {
+		return nil, nil, err
+	}
+	if tr, ok := res.(resource.Templater); ok {
+		tr.SetTemplateFunc(func(text string) (string, error) {
+			return RenderTemplate(text, d)
+		})
+	}
+	return res, notify, nil
+}
+
+// rawAttributes returns the attributes of the resource type which are not
+// rendered with the variables. They are tagged with raw:"true", and the
+// resource renders them itself if it is requested.
+func rawAttributes(t string) map[string]bool {
+	raw := map[string]bool{}
+	res := resource.New(t)
+	if res == nil {
+		return raw
+	}
+	rt := reflect.TypeOf(res)
+	if rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	if rt.Kind() != reflect.Struct {
+		return raw
+	}
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		if f.Tag.Get("raw") == "true" {
+			raw[strings.Split(f.Tag.Get("json"), ",")[0]] = true
+		}
+	}
+	return raw
+}
+
+// popNotify removes the notify attribute from the attributes, and returns the
+// notifications. It returns nil if the resource has no notifications.
+func popNotify(attrs map[string]interface{}) ([]resource.Notification, error) {
+	v, ok := attrs[notifyKey]
+	if !ok {
+		return nil, nil
+	}
+	delete(attrs, notifyKey)
+	if _, ok := v.(map[string]interface{}); ok {
+		v = []interface{}{v}
+	}
+	j, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	notify := []resource.Notification{}
+	if err := json.Unmarshal(j, &notify); err != nil {
+		return nil, fmt.Errorf(`parameter "%s" must be an object or a list of the objects`, notifyKey)
+	}
+	for _, n := range notify {
+		if n.Resource == "" || n.Action == "" {
+			return nil, fmt.Errorf(`parameter "%s" requires "resource" and "action"`, notifyKey)
+		}
+	}
+	return notify, nil
+}
+
+func unmarshalResource(j json.RawMessage, t string, strict bool) (resource.Resource, error) {
 	res := resource.New(t)
 	if res == nil {
 		return nil, fmt.Errorf("unknwon resource type: %s", t)
 	}
-	if err := json.Unmarshal(j, res); err != nil {
+	d := json.NewDecoder(bytes.NewReader(j))
+	if strict {
+		d.DisallowUnknownFields()
+	}
+	if err := d.Decode(res); err != nil {
 		return nil, err
 	}
 	return res, nil
@@ -1,6 +1,13 @@
 package recipe
 
-import "testing"
+import (
+	"strings"
+	"testing"
+
+	"github.com/harukasan/orchestra-pit/resource/file"
+	"github.com/harukasan/orchestra-pit/resource/packagemanager"
+	filestate "github.com/harukasan/orchestra-pit/state/file"
+)
 
 var input = []byte(`{
   "resources": [
@@ -12,7 +19,7 @@ var input = []byte(`{
 }`)
 
 func TestParseJSON(t *testing.T) {
-	recipe, err := ParseJSON(input)
+	recipe, err := ParseJSON(input, nil)
 	if err != nil {
 		t.Fatalf("got error: %v", err)
 	}
@@ -20,3 +27,93 @@ func TestParseJSON(t *testing.T) {
 		t.Errorf("parsed 1 resource expected, but got %d", len(recipe.Resources))
 	}
 }
+
+func TestParseJSONWithItems(t *testing.T) {
+	input := []byte(`{
+  "resources": [
+    {
+      "type": "package",
+      "name": "{{ .item }}",
+      "with_items": ["vim", "git"]
+    },
+    {
+      "type": "file",
+      "path": "/etc/{{ .item.name }}",
+      "mode": "{{ .item.mode }}",
+      "for_each": [{"name": "a", "mode": "0644"}, {"name": "b", "mode": "0600"}]
+    },
+    {
+      "type": "package",
+      "name": "{{ .item }}",
+      "with_items": "tools"
+    }
+  ]
+}`)
+	recipe, err := ParseJSON(input, map[string]string{"tools": "curl, jq"})
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if len(recipe.Resources) != 6 {
+		t.Fatalf("parsed 6 resources expected, but got %d", len(recipe.Resources))
+	}
+	names := []string{}
+	for _, r := range recipe.Resources {
+		switch r := r.(type) {
+		case *packagemanager.Resource:
+			names = append(names, r.Name)
+		case *file.Resource:
+			names = append(names, r.Path+":"+r.Mode)
+		}
+	}
+	expected := "vim git /etc/a:0644 /etc/b:0600 curl jq"
+	if got := strings.Join(names, " "); got != expected {
+		t.Errorf("got %q, expected %q", got, expected)
+	}
+}
+
+func TestParseJSONContent(t *testing.T) {
+	input := []byte(`{
+  "resources": [
+    {
+      "type": "file",
+      "path": "/etc/{{ .site }}.tmpl",
+      "content": "{{ .Name }} {{ if .ok }}{{ end }}"
+    },
+    {
+      "type": "file",
+      "path": "/etc/{{ .site }}.conf",
+      "content": "server_name {{ .site }};",
+      "template": true
+    }
+  ]
+}`)
+	recipe, err := ParseJSON(input, map[string]string{"site": "www"})
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	expected := []string{"{{ .Name }} {{ if .ok }}{{ end }}\n", "server_name www;\n"}
+	for i, r := range recipe.Resources {
+		states, err := r.States()
+		if err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+		s, ok := states[0].(*filestate.Content)
+		if !ok {
+			t.Fatalf("state is not a Content state: %v", states[0])
+		}
+		if string(s.Content) != expected[i] {
+			t.Errorf("got content %q, expected %q", s.Content, expected[i])
+		}
+	}
+}
+
+func TestParseJSONWithItemsError(t *testing.T) {
+	input := []byte(`{
+  "resources": [
+    {"type": "package", "name": "{{ .item }}", "with_items": ["a"], "for_each": ["b"]}
+  ]
+}`)
+	if _, err := ParseJSON(input, nil); err == nil {
+		t.Errorf("got no error for both with_items and for_each")
+	}
+}
@@ -1,10 +1,8 @@
 package recipe
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"io/ioutil"
 	"os"
 	"path"
@@ -16,11 +14,60 @@ import (
 )
 
 // Recipe represents the recipe which desribes disired states of resources.
+//
+// A recipe can include other recipe files, and use roles. The includes are
+// the recipe files relative to the including recipe, and the file included by
+// several recipes is read once. A role is the directory named roles/<name>
+// next to the recipe, or in the directories of RolesPath, which has its own
+// recipe file and the files directory. The role's recipe is rendered with the
+// variables of the role, which override the variables of the recipe. The
+// resources of the includes and the roles precede the resources of the recipe.
+//
+// The files referred by the resources, such as the sources of the files, are
+// relative to the recipe which has the resources, and the default sources are
+// in the files directory. The files directory is FilesDir relative to the
+// recipe, or "files" next to the recipe. The includes share the files
+// directory of the including recipe unless they have FilesDir, and the roles
+// have their own files directories.
+//
+// A resource can notify the other resources in the recipe when it is applied,
+// by the notify attribute. The notified resource takes the action once at the
+// end of the run, see resource.Notifiable.
 type Recipe struct {
 	Config    map[string]string
+	Include   []string
+	Roles     []Role
 	Resources []resource.Resource
+
+	// FilesDir is the files directory of the recipe.
+	FilesDir string
+
+	// RolesPath lists the directories which the roles are searched in, after
+	// the roles directory next to the recipe. The included recipes and the
+	// roles search the roles in the same directories.
+	RolesPath []string
+
+	// Notify has the notifications of the resources which notify the other
+	// resources.
+	Notify map[resource.Resource][]resource.Notification
+
+	// Sources lists the recipe files, the role directories and the files
+	// directories read by ReadRecipe.
+	Sources []string
+}
+
+// Role represents the role used in the recipe.
+type Role struct {
+	Name string            `json:"name" doc:"the name of the role in the roles directory"`
+	Vars map[string]string `json:"vars" doc:"the variables of the role"`
 }
 
+// RolesDir is the name of the directory which has the roles.
+const RolesDir = "roles"
+
+// FilesDir is the name of the directory which has the files of the role.
+const FilesDir = "files"
+
 var fileNames = []string{
 	"recipe.json",
 	"recipe.rb",
@@ -29,78 +76,319 @@ var fileNames = []string{
 }
 
 // ReadRecipe reads the named recipe file. If the name is not specified, it
-// searchs the recipe file in specified directory.
+// searchs the recipe file in specified directory. The strings in the recipe
+// are rendered as templates with the variables, see Render. The includes and
+// the roles are read recursively, and including the recipe itself is an error.
 // If failed to read recipe, it returns an error.
-func ReadRecipe(name string, dir string) (*Recipe, error) {
-	var r io.Reader
-	var err error
-	if name != "" {
-		name, r, err = openFile(name)
-	} else {
-		name, r, err = findFile(dir)
-	}
+func ReadRecipe(name string, dir string, vars map[string]string) (*Recipe, error) {
+	name, err := FindFile(name, dir)
 	if err != nil {
 		return nil, err
 	}
+	rd := &reader{}
+	r := rd.readRecipe(name, vars, nil, scope{})
+	if len(rd.errs) > 0 {
+		return nil, rd.errs[0]
+	}
+	if errs := r.checkNotify(); len(errs) > 0 {
+		return nil, &Error{File: name, Err: errs[0]}
+	}
+	return r, nil
+}
+
+// Validate reads the named recipe file in the same manner as ReadRecipe, and
+// returns all of the errors found in the recipe, the includes and the roles.
+// In addition to the errors of ReadRecipe, it reports the unknown attributes
+// and the invalid attributes of the resources, see resource.Validate. The
+// errors in the recipe files are *Error.
+func Validate(name string, dir string, vars map[string]string) []error {
+	name, err := FindFile(name, dir)
+	if err != nil {
+		return []error{err}
+	}
+	rd := &reader{strict: true}
+	if r := rd.readRecipe(name, vars, nil, scope{}); r != nil {
+		for _, err := range r.checkNotify() {
+			rd.error(name, err)
+		}
+	}
+	return rd.errs
+}
+
+// Error represents the error at the position of the recipe file. Line and Pos
+// are 1-based, and they are zero if the position is unknown.
+type Error struct {
+	File string
+	Line int
+	Pos  int
+	Err  error
+}
+
+func (e *Error) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %s", e.File, e.Err)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Pos, e.Err)
+}
+
+// reader reads the recipe files, and collects the errors. On the strict mode,
+// it rejects the unknown attributes and validates the resources. The included
+// files are read once, even if several recipes include the same file.
+type reader struct {
+	strict   bool
+	errs     []error
+	included map[string]bool
+}
+
+// error records the error in the named file.
+func (rd *reader) error(name string, err error) {
+	if e, ok := err.(*Error); ok {
+		if e.File == "" {
+			e.File = name
+		}
+		rd.errs = append(rd.errs, e)
+		return
+	}
+	rd.errs = append(rd.errs, &Error{File: name, Err: err})
+}
+
+// scope is the directories which the recipe inherits from the including
+// recipe, or the role.
+type scope struct {
+	filesDir  string
+	rolesPath []string
+}
+
+// readRecipe reads the recipe file. The stack is the recipe files which are
+// including the file. If the file can not be read, it returns nil.
+func (rd *reader) readRecipe(name string, vars map[string]string, stack []string, sc scope) *Recipe {
+	for i, s := range stack {
+		if s == name {
+			cycle := append(stack[i:], name)
+			rd.error(stack[len(stack)-1], fmt.Errorf("include cycle: %s", strings.Join(cycle, " -> ")))
+			return nil
+		}
+	}
+	stack = append(stack, name)
 
 	logger.Debugf("------ reading recipe file: %s", name)
-	data, err := ioutil.ReadAll(r)
+	data, err := ioutil.ReadFile(name)
 	if err != nil {
-		logger.Fatalf("can not read the file: %s", err)
+		rd.error(name, fmt.Errorf("can not read the file: %s", err))
+		return nil
+	}
+	r, errs := parse(name, data, vars, rd.strict)
+	for _, err := range errs {
+		rd.error(name, err)
+	}
+	if r == nil {
+		return nil
 	}
 
-	switch {
-	case strings.HasSuffix(name, ".json"):
-		r, err := ParseJSON(data)
-		if err != nil {
-			if e, ok := err.(*json.SyntaxError); ok {
-				line, pos := getCaretPos(data, int(e.Offset))
-				return nil, fmt.Errorf("can not parse the JSON file: %s near line %d, pos %d", err, line, pos)
-			}
-			return nil, fmt.Errorf("can not parse the JSON file: %s", err)
+	dir := filepath.Dir(name)
+	filesDir := sc.filesDir
+	if r.FilesDir != "" {
+		filesDir = resolve(dir, r.FilesDir)
+	}
+	if filesDir == "" {
+		filesDir = filepath.Join(dir, FilesDir)
+	}
+	rolesPath := []string{}
+	for _, p := range r.RolesPath {
+		rolesPath = append(rolesPath, resolve(dir, p))
+	}
+	rolesPath = append(rolesPath, sc.rolesPath...)
+	for _, res := range r.Resources {
+		if f, ok := res.(resource.FileReferrer); ok {
+			f.SetRecipeDir(dir)
+			f.SetFilesDir(filesDir)
+		}
+	}
+
+	recipe := &Recipe{
+		Config:  map[string]string{},
+		Notify:  map[resource.Resource][]resource.Notification{},
+		Sources: []string{name},
+	}
+	if info, err := os.Stat(filesDir); err == nil && info.IsDir() {
+		recipe.Sources = append(recipe.Sources, filesDir)
+	}
+	for _, inc := range r.Include {
+		p := resolve(dir, inc)
+		if rd.included[p] {
+			logger.Debugf("skip the recipe file included already: %s", p)
+			continue
+		}
+		if rd.included == nil {
+			rd.included = map[string]bool{}
+		}
+		rd.included[p] = true
+		if sub := rd.readRecipe(p, vars, stack, scope{filesDir, rolesPath}); sub != nil {
+			recipe.merge(sub)
 		}
-		return r, nil
 	}
+	for _, role := range r.Roles {
+		if sub := rd.readRole(name, role, vars, stack, rolesPath); sub != nil {
+			recipe.merge(sub)
+		}
+	}
+	recipe.Include = r.Include
+	recipe.Roles = r.Roles
+	recipe.FilesDir = filesDir
+	recipe.RolesPath = rolesPath
+	recipe.merge(r)
+	return recipe
+}
 
-	return nil, fmt.Errorf("unsupported file format")
+// resolve returns the path relative to the directory.
+func resolve(dir string, p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(dir, p)
 }
 
-func openFile(name string) (path string, r io.Reader, err error) {
-	path, err = filepath.Abs(name)
+// readRole reads the recipe of the role used in the named recipe file. The
+// role is searched in the roles directory next to the recipe, and then in the
+// roles path.
+func (rd *reader) readRole(name string, role Role, vars map[string]string, stack []string, rolesPath []string) *Recipe {
+	if role.Name == "" {
+		rd.error(name, fmt.Errorf(`parameter "name" of the role is required`))
+		return nil
+	}
+	dirs := append([]string{filepath.Join(filepath.Dir(name), RolesDir)}, rolesPath...)
+	roleDir := ""
+	for _, d := range dirs {
+		if info, err := os.Stat(filepath.Join(d, role.Name)); err == nil && info.IsDir() {
+			roleDir = filepath.Join(d, role.Name)
+			break
+		}
+	}
+	if roleDir == "" {
+		rd.error(name, fmt.Errorf("can not find the role %q in %s", role.Name, strings.Join(dirs, ", ")))
+		return nil
+	}
+	file, err := FindFile("", roleDir)
 	if err != nil {
-		return path, nil, err
+		rd.error(name, fmt.Errorf("can not read the role %q: %s", role.Name, err))
+		return nil
 	}
-	r, err = os.Open(name)
-	return
+
+	roleVars := map[string]string{}
+	for k, v := range vars {
+		roleVars[k] = v
+	}
+	for k, v := range role.Vars {
+		roleVars[k] = v
+	}
+	r := rd.readRecipe(file, roleVars, stack, scope{filepath.Join(roleDir, FilesDir), rolesPath})
+	if r == nil {
+		return nil
+	}
+	r.Sources = append([]string{roleDir}, r.Sources...)
+	return r
+}
+
+// merge appends the resources, the notifications and the sources of the recipe
+// r. The config of r overrides the config.
+func (recipe *Recipe) merge(r *Recipe) {
+	for k, v := range r.Config {
+		recipe.Config[k] = v
+	}
+	recipe.Resources = append(recipe.Resources, r.Resources...)
+	for res, n := range r.Notify {
+		recipe.Notify[res] = n
+	}
+	for _, s := range r.Sources {
+		if !contains(recipe.Sources, s) {
+			recipe.Sources = append(recipe.Sources, s)
+		}
+	}
+}
+
+// Lookup returns the resource of the ID, see resource.ID. It returns nil if
+// the recipe has no such resource.
+func (recipe *Recipe) Lookup(id string) resource.Resource {
+	for _, res := range recipe.Resources {
+		if resource.ID(res) == id {
+			return res
+		}
+	}
+	return nil
+}
+
+// checkNotify checks that the notified resources are in the recipe, and they
+// can take the actions.
+func (recipe *Recipe) checkNotify() []error {
+	errs := []error{}
+	for _, res := range recipe.Resources {
+		for _, n := range recipe.Notify[res] {
+			target := recipe.Lookup(n.Resource)
+			if target == nil {
+				errs = append(errs, fmt.Errorf(`%s notifies the unknown resource "%s"`, resource.ID(res), n.Resource))
+				continue
+			}
+			t, ok := target.(resource.Notifiable)
+			if !ok {
+				errs = append(errs, fmt.Errorf("%s can not take actions", n.Resource))
+				continue
+			}
+			if _, err := t.ActionStates(n.Action); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %s", n.Resource, err))
+			}
+		}
+	}
+	return errs
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+// parse parses the recipe data in the format of the file extension.
+func parse(name string, data []byte, vars map[string]string, strict bool) (*Recipe, []error) {
+	switch {
+	case strings.HasSuffix(name, ".json"):
+		return parseJSON(data, vars, strict)
+	}
+
+	return nil, []error{fmt.Errorf("unsupported file format")}
 }
 
-func findFile(dir string) (name string, r io.Reader, err error) {
-	var file *os.File
-	for _, name = range fileNames {
-		file, err = os.Open(path.Join(dir, name))
+// FindFile returns the absolute path of the named recipe file. If the name is
+// not specified, it searches the recipe file in the specified directory.
+func FindFile(name string, dir string) (string, error) {
+	if name != "" {
+		return filepath.Abs(name)
+	}
+	for _, n := range fileNames {
+		p := path.Join(dir, n)
+		_, err := os.Stat(p)
 		if err == nil {
-			break
+			return filepath.Abs(p)
 		}
 		if !os.IsNotExist(err) {
-			return name, file, err
+			return "", err
 		}
 	}
-	if file == nil {
-		err = errors.New("the recipe file is not found")
-	}
-	return name, file, err
+	return "", errors.New("the recipe file is not found")
 }
 
 func getCaretPos(data []byte, off int) (line int, pos int) {
 	line = 1
-	pos = 0
+	pos = 1
 	for i, b := range data {
 		if i >= off {
 			break
 		}
 		if b == '\n' {
 			line++
-			pos = 0
+			pos = 1
 			continue
 		}
 		pos++
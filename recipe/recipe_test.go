@@ -0,0 +1,264 @@
+package recipe
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/harukasan/orchestra-pit/resource"
+	"github.com/harukasan/orchestra-pit/resource/file"
+)
+
+// writeFiles writes the files into a temporary directory, and returns the
+// directory. The caller removes the directory.
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "recipe_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestReadRecipeIncludesAndRoles(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"recipe.json": `{
+  "include": ["common/base.json"],
+  "roles": [{"name": "nginx", "vars": {"port": "8080"}}],
+  "resources": [{"type": "file", "path": "/etc/{{ .site }}"}]
+}`,
+		"common/base.json":        `{"resources": [{"type": "file", "path": "/etc/base"}]}`,
+		"roles/nginx/recipe.json": `{"resources": [{"type": "file", "path": "/etc/nginx/{{ .site }}-{{ .port }}.conf"}]}`,
+	})
+	defer os.RemoveAll(dir)
+
+	rec, err := ReadRecipe("", dir, map[string]string{"site": "www"})
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	paths := []string{}
+	for _, r := range rec.Resources {
+		paths = append(paths, r.(*file.Resource).Path)
+	}
+	expected := "/etc/base /etc/nginx/www-8080.conf /etc/www"
+	if got := strings.Join(paths, " "); got != expected {
+		t.Errorf("got resources %q, expected %q", got, expected)
+	}
+
+	role := rec.Resources[1].(*file.Resource)
+	if _, err := role.States(); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if src := filepath.Join(dir, "roles/nginx/files/etc/nginx/www-8080.conf"); role.Src != src {
+		t.Errorf("got src %q, expected %q", role.Src, src)
+	}
+
+	if len(rec.Sources) != 4 || rec.Sources[2] != filepath.Join(dir, "roles/nginx") {
+		t.Errorf("got unexpected sources: %v", rec.Sources)
+	}
+}
+
+func TestReadRecipeIncludeCycle(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.json": `{"include": ["b.json"]}`,
+		"b.json": `{"include": ["a.json"]}`,
+	})
+	defer os.RemoveAll(dir)
+
+	_, err := ReadRecipe(filepath.Join(dir, "a.json"), dir, nil)
+	if err == nil || !strings.Contains(err.Error(), "include cycle") {
+		t.Errorf("got %v, expected an include cycle error", err)
+	}
+}
+
+func TestReadRecipeDiamondInclude(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"recipe.json": `{"include": ["b.json", "c.json"]}`,
+		"b.json":      `{"include": ["d.json"], "resources": [{"type": "file", "path": "/etc/b"}]}`,
+		"c.json":      `{"include": ["d.json"], "resources": [{"type": "file", "path": "/etc/c"}]}`,
+		"d.json":      `{"resources": [{"type": "file", "path": "/etc/d"}]}`,
+	})
+	defer os.RemoveAll(dir)
+
+	rec, err := ReadRecipe("", dir, nil)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	paths := []string{}
+	for _, r := range rec.Resources {
+		paths = append(paths, r.(*file.Resource).Path)
+	}
+	expected := "/etc/d /etc/b /etc/c"
+	if got := strings.Join(paths, " "); got != expected {
+		t.Errorf("got resources %q, expected %q", got, expected)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"recipe.json": `{
+  "include": ["other.json"],
+  "resources": [
+    {"type": "package", "name": "sl", "stat": "installed"},
+    {"type": "file", "path": "/etc/motd"},
+    {"type": "file", "mode": "0644"}
+  ]
+}`,
+		"other.json": `{
+  "resources": [
+    {"type": "package", "name": "vim", "state": "instaled"}
+  ]
+}`,
+	})
+	defer os.RemoveAll(dir)
+
+	errs := Validate("", dir, nil)
+	if len(errs) != 3 {
+		t.Fatalf("got %d errors, expected 3: %v", len(errs), errs)
+	}
+	expected := []struct {
+		file string
+		line int
+		pos  int
+		msg  string
+	}{
+		{"recipe.json", 4, 5, `unknown field "stat"`},
+		{"recipe.json", 6, 5, `parameter "path" is required`},
+		{"other.json", 3, 5, `unknown state "instaled"`},
+	}
+	for i, e := range expected {
+		err, ok := errs[i].(*Error)
+		if !ok {
+			t.Errorf("got %v, expected *Error", errs[i])
+			continue
+		}
+		if filepath.Base(err.File) != e.file || err.Line != e.line || err.Pos != e.pos || !strings.Contains(err.Error(), e.msg) {
+			t.Errorf("got %q, expected %s:%d:%d: %s", err, e.file, e.line, e.pos, e.msg)
+		}
+	}
+
+	if _, err := ReadRecipe("", dir, nil); err != nil {
+		t.Errorf("ReadRecipe should ignore the unknown attributes, but got error: %v", err)
+	}
+}
+
+func TestTypeExamples(t *testing.T) {
+	for _, typ := range resource.Types() {
+		data := []byte(`{"resources": [` + typ.Example + `]}`)
+		if _, errs := parseJSON(data, nil, true); len(errs) > 0 {
+			t.Errorf("the example of %s is invalid: %v", typ.Name, errs)
+		}
+	}
+}
+
+func TestNotify(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"recipe.json": `{
+  "resources": [
+    {"type": "file", "path": "/etc/nginx/{{ .item }}", "with_items": ["a.conf", "b.conf"],
+     "notify": {"resource": "service:nginx", "action": "reload"}},
+    {"type": "service", "name": "nginx"}
+  ]
+}`,
+		"invalid.json": `{
+  "resources": [
+    {"type": "file", "path": "/etc/motd", "notify": [{"resource": "service:sshd", "action": "restart"}]},
+    {"type": "file", "path": "/etc/issue", "notify": [{"resource": "file:/etc/motd", "action": "restart"}]}
+  ]
+}`,
+	})
+	defer os.RemoveAll(dir)
+
+	rec, err := ReadRecipe("", dir, nil)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	for _, res := range rec.Resources[:2] {
+		n := rec.Notify[res]
+		if len(n) != 1 || n[0].Resource != "service:nginx" || n[0].Action != "reload" {
+			t.Errorf("got notifications %v", n)
+		}
+	}
+	if rec.Lookup("service:nginx") != rec.Resources[2] {
+		t.Errorf("can not look up the notified resource")
+	}
+
+	errs := Validate(filepath.Join(dir, "invalid.json"), dir, nil)
+	if len(errs) != 2 {
+		t.Fatalf("got %d errors, expected 2: %v", len(errs), errs)
+	}
+	if !strings.Contains(errs[0].Error(), `unknown resource "service:sshd"`) {
+		t.Errorf("got %q, expected the unknown resource", errs[0])
+	}
+	if !strings.Contains(errs[1].Error(), "file:/etc/motd can not take actions") {
+		t.Errorf("got %q, expected the resource which can not take actions", errs[1])
+	}
+}
+
+func TestReadRecipeRelativePaths(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"site/recipe.json": `{
+  "files_dir": "assets",
+  "roles_path": ["../shared"],
+  "include": ["common/base.json"],
+  "roles": [{"name": "web"}],
+  "resources": [
+    {"type": "file", "path": "/etc/motd"},
+    {"type": "file", "path": "/etc/issue", "src": "texts/issue"}
+  ]
+}`,
+		"site/assets/etc/motd":      "motd",
+		"site/common/base.json":     `{"resources": [{"type": "file", "path": "/etc/hosts"}]}`,
+		"shared/web/recipe.json":    `{"resources": [{"type": "file", "path": "/etc/nginx.conf"}]}`,
+		"shared/web/files/etc/a":    "",
+		"elsewhere/placeholder.txt": "",
+	})
+	defer os.RemoveAll(dir)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(filepath.Join(dir, "elsewhere")); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	rec, err := ReadRecipe("../site/recipe.json", "", nil)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	expected := []string{
+		filepath.Join(dir, "site/assets/etc/hosts"),
+		filepath.Join(dir, "shared/web/files/etc/nginx.conf"),
+		filepath.Join(dir, "site/assets/etc/motd"),
+		filepath.Join(dir, "site/texts/issue"),
+	}
+	for i, res := range rec.Resources {
+		f := res.(*file.Resource)
+		if _, err := f.States(); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+		if f.Src != expected[i] {
+			t.Errorf("got src %q, expected %q", f.Src, expected[i])
+		}
+	}
+	if !contains(rec.Sources, filepath.Join(dir, "site/assets")) || !contains(rec.Sources, filepath.Join(dir, "shared/web/files")) {
+		t.Errorf("the files directories are not in the sources: %v", rec.Sources)
+	}
+
+	missing := writeFiles(t, map[string]string{"recipe.json": `{"roles": [{"name": "db"}]}`})
+	defer os.RemoveAll(missing)
+	if _, err := ReadRecipe("", missing, nil); err == nil || !strings.Contains(err.Error(), `can not find the role "db"`) {
+		t.Errorf("got %v, expected the role not found", err)
+	}
+}
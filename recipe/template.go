@@ -0,0 +1,110 @@
+package recipe
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// variablePattern matches the reference to the variable such as {{ .name }}
+// or {{ .item.name }}.
+var variablePattern = regexp.MustCompile(`{{\s*\.([A-Za-z_][A-Za-z0-9_]*(?:\.[A-Za-z_][A-Za-z0-9_]*)*)\s*}}`)
+
+// Render replaces the references to the variables in the string s with the
+// values of the data. The variables are referred as {{ .name }} in the recipe,
+// and the fields of the object as {{ .item.name }}. Referring the undefined
+// variable is an error. The other braces are left as they are, so the values
+// can contain the templates of the other programs.
+func Render(s string, data map[string]interface{}) (string, error) {
+	if !strings.Contains(s, "{{") {
+		return s, nil
+	}
+	var err error
+	out := variablePattern.ReplaceAllStringFunc(s, func(ref string) string {
+		name := variablePattern.FindStringSubmatch(ref)[1]
+		v, ok := lookupVariable(data, name)
+		if !ok {
+			if err == nil {
+				err = fmt.Errorf("can not render %q: the variable %q is not defined", s, name)
+			}
+			return ref
+		}
+		return fmt.Sprint(v)
+	})
+	if err != nil {
+		return "", err
+	}
+	return out, nil
+}
+
+// lookupVariable returns the value of the variable. The name is separated by
+// the dots to refer the fields of the objects.
+func lookupVariable(data map[string]interface{}, name string) (interface{}, bool) {
+	var v interface{} = data
+	for _, key := range strings.Split(name, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if v, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// RenderTemplate renders the string s as a text/template with the data. It is
+// used for the templates which the resource opts in, such as the source file
+// of the file resource. Referring the undefined variable is an error.
+func RenderTemplate(s string, data map[string]interface{}) (string, error) {
+	t, err := template.New("recipe").Option("missingkey=error").Parse(s)
+	if err != nil {
+		return "", fmt.Errorf("can not parse the template: %s", err)
+	}
+	buf := &bytes.Buffer{}
+	if err := t.Execute(buf, data); err != nil {
+		return "", fmt.Errorf("can not render the template: %s", err)
+	}
+	return buf.String(), nil
+}
+
+// renderValue renders the strings in the value decoded from JSON recursively.
+// The keys of the objects are not rendered.
+func renderValue(v interface{}, data map[string]interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		return Render(v, data)
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, e := range v {
+			r, err := renderValue(e, data)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = r
+		}
+		return out, nil
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			r, err := renderValue(e, data)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = r
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+// templateData returns the data to render the templates with the variables.
+func templateData(vars map[string]string) map[string]interface{} {
+	data := make(map[string]interface{}, len(vars))
+	for k, v := range vars {
+		data[k] = v
+	}
+	return data
+}
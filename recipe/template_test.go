@@ -0,0 +1,66 @@
+package recipe
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	data := map[string]interface{}{"name": "web"}
+	out, err := Render("/etc/{{ .name }}.conf", data)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if out != "/etc/web.conf" {
+		t.Errorf("got %s, expected /etc/web.conf", out)
+	}
+
+	if _, err := Render("/etc/{{ .undefined }}.conf", data); err == nil {
+		t.Errorf("got no error for the undefined variable")
+	}
+
+	// only the references to the variables are rendered.
+	literal := `{{ if .ok }}{{ range $x := .list }}{{ $x }}{{ end }}{{ end }} {{ .name }}`
+	out, err = Render(literal, data)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if expected := `{{ if .ok }}{{ range $x := .list }}{{ $x }}{{ end }}{{ end }} web`; out != expected {
+		t.Errorf("got %s, expected %s", out, expected)
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	data := map[string]interface{}{"name": "web"}
+	out, err := RenderTemplate(`{{ if eq .name "web" }}server_name {{ .name }};{{ end }}`, data)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if out != "server_name web;" {
+		t.Errorf("got %s, expected server_name web;", out)
+	}
+	if _, err := RenderTemplate("{{ .undefined }}", data); err == nil {
+		t.Errorf("got no error for the undefined variable")
+	}
+}
+
+func TestRenderValue(t *testing.T) {
+	data := map[string]interface{}{
+		"item": map[string]interface{}{"name": "vim"},
+	}
+	v := map[string]interface{}{
+		"name":    "{{ .item.name }}",
+		"options": []interface{}{"--{{ .item.name }}", float64(1)},
+	}
+	out, err := renderValue(v, data)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	expected := map[string]interface{}{
+		"name":    "vim",
+		"options": []interface{}{"--vim", float64(1)},
+	}
+	if !reflect.DeepEqual(out, expected) {
+		t.Errorf("got %v, expected %v", out, expected)
+	}
+}
@@ -0,0 +1,58 @@
+/*
+Package aptpreference implements the applying state of the preferences of APT.
+*/
+package aptpreference
+
+import (
+	"fmt"
+
+	"github.com/harukasan/orchestra-pit/opit/logger"
+	"github.com/harukasan/orchestra-pit/state"
+	"github.com/harukasan/orchestra-pit/state/file"
+	"github.com/harukasan/orchestra-pit/state/packagemanager"
+)
+
+// Resource represents the attributes of apt_preference resource.
+type Resource struct {
+	Desc     string `json:"desc" yaml:"desc" doc:"the description of the resource"`
+	Name     string `json:"name" yaml:"name" doc:"the name of the preference file"`
+	Package  string `json:"package" yaml:"package" doc:"the packages which the preference applies to" default:"<name>"`
+	Pin      string `json:"pin" yaml:"pin" doc:"the pin of the preference, e.g. release a=stable"`
+	Priority int    `json:"priority" yaml:"priority" doc:"the pin priority"`
+	State    string `json:"state" yaml:"state" doc:"the state of the preference" enum:"present,absent" default:"present"`
+}
+
+func (r *Resource) States() ([]state.State, error) {
+	if r.State == "" {
+		r.State = "present"
+		logger.Debugf(`parameter "state" is not specified, assume as "%s"`, r.State)
+	}
+	if r.Name == "" {
+		return nil, fmt.Errorf(`parameter "name" is required`)
+	}
+
+	pref := &packagemanager.APTPreference{
+		Name:     r.Name,
+		Package:  r.Package,
+		Pin:      r.Pin,
+		Priority: r.Priority,
+	}
+
+	switch r.State {
+	case "present":
+		if pref.Package == "" {
+			pref.Package = r.Name
+			logger.Debugf(`parameter "package" is not specified, assume as "%s"`, pref.Package)
+		}
+		if r.Pin == "" {
+			return nil, fmt.Errorf(`parameter "pin" is required`)
+		}
+		if r.Priority == 0 {
+			return nil, fmt.Errorf(`parameter "priority" is required`)
+		}
+		return []state.State{pref}, nil
+	case "absent":
+		return []state.State{&file.Absence{Name: pref.Path()}}, nil
+	}
+	return nil, fmt.Errorf(`unknown state "%s"`, r.State)
+}
@@ -0,0 +1,96 @@
+/*
+Package aptrepository implements the applying state of the repositories of APT.
+*/
+package aptrepository
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/harukasan/orchestra-pit/opit/logger"
+	"github.com/harukasan/orchestra-pit/state"
+	"github.com/harukasan/orchestra-pit/state/file"
+	"github.com/harukasan/orchestra-pit/state/packagemanager"
+)
+
+// KeyringDir specifies the directory to put the keyring file when the keyring
+// path is not specified.
+var KeyringDir = "/usr/share/keyrings"
+
+// Resource represents the attributes of apt_repository resource.
+type Resource struct {
+	Desc       string   `json:"desc" yaml:"desc" doc:"the description of the resource"`
+	Name       string   `json:"name" yaml:"name" doc:"the name of the source list file"`
+	Type       string   `json:"archive_type" yaml:"archive_type" doc:"the archive type" enum:"deb,deb-src" default:"deb"`
+	URI        string   `json:"uri" yaml:"uri" doc:"the URI of the repository"`
+	Suite      string   `json:"suite" yaml:"suite" doc:"the suite or the codename of the distribution"`
+	Components []string `json:"components" yaml:"components" doc:"the components of the repository, e.g. main"`
+	Arch       []string `json:"arch" yaml:"arch" doc:"the architectures to download"`
+	SignedBy   string   `json:"signed_by" yaml:"signed_by" doc:"the path of the keyring to verify the repository" default:"/usr/share/keyrings/<name>.gpg if key is given"`
+	Key        string   `json:"key" yaml:"key" doc:"the keyring file to install to signed_by, relative to the recipe"`
+	State      string   `json:"state" yaml:"state" doc:"the state of the repository" enum:"present,absent" default:"present"`
+
+	recipeDir string
+}
+
+// SetRecipeDir sets the directory which the relative key is resolved against.
+func (r *Resource) SetRecipeDir(dir string) {
+	r.recipeDir = dir
+}
+
+// SetFilesDir does nothing, because the repository has no default key.
+func (r *Resource) SetFilesDir(dir string) {}
+
+func (r *Resource) States() ([]state.State, error) {
+	states := []state.State{}
+
+	if r.State == "" {
+		r.State = "present"
+		logger.Debugf(`parameter "state" is not specified, assume as "%s"`, r.State)
+	}
+	if r.Name == "" {
+		return nil, fmt.Errorf(`parameter "name" is required`)
+	}
+
+	repo := &packagemanager.APTRepository{
+		Name:       r.Name,
+		Type:       r.Type,
+		URI:        r.URI,
+		Suite:      r.Suite,
+		Components: r.Components,
+		Arch:       r.Arch,
+		SignedBy:   r.SignedBy,
+	}
+
+	switch r.State {
+	case "present":
+		if r.URI == "" {
+			return nil, fmt.Errorf(`parameter "uri" is required`)
+		}
+		if r.Suite == "" {
+			return nil, fmt.Errorf(`parameter "suite" is required`)
+		}
+		if r.Key != "" {
+			if !path.IsAbs(r.Key) && r.recipeDir != "" {
+				r.Key = path.Join(r.recipeDir, r.Key)
+			}
+			if repo.SignedBy == "" {
+				repo.SignedBy = path.Join(KeyringDir, r.Name+".gpg")
+				logger.Debugf(`parameter "signed_by" is not specified, assume as "%s"`, repo.SignedBy)
+			}
+			states = append(states, &file.Copy{
+				Name: repo.SignedBy,
+				Src:  r.Key,
+			})
+		}
+		states = append(states, repo)
+	case "absent":
+		states = append(states, &packagemanager.APTRepositoryAbsence{
+			Name: r.Name,
+		})
+	default:
+		return nil, fmt.Errorf(`unknown state "%s"`, r.State)
+	}
+
+	return states, nil
+}
@@ -0,0 +1,77 @@
+package aptrepository_test
+
+import (
+	"testing"
+
+	"github.com/harukasan/orchestra-pit/resource/aptrepository"
+	"github.com/harukasan/orchestra-pit/state/file"
+	"github.com/harukasan/orchestra-pit/state/packagemanager"
+)
+
+func TestStatesWithKey(t *testing.T) {
+	r := &aptrepository.Resource{
+		Name:       "docker",
+		URI:        "https://download.docker.com/linux/debian",
+		Suite:      "stretch",
+		Components: []string{"stable"},
+		Key:        "/tmp/docker.gpg",
+	}
+
+	states, err := r.States()
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if got := len(states); got != 2 {
+		t.Fatalf("got %d states, expected 2", got)
+	}
+
+	key, ok := states[0].(*file.Copy)
+	if !ok {
+		t.Fatalf("state is not a Copy state")
+	}
+	if key.Name != "/usr/share/keyrings/docker.gpg" {
+		t.Errorf("got Name %v, expected /usr/share/keyrings/docker.gpg", key.Name)
+	}
+	if key.Src != r.Key {
+		t.Errorf("got Src %v, expected %v", key.Src, r.Key)
+	}
+
+	repo, ok := states[1].(*packagemanager.APTRepository)
+	if !ok {
+		t.Fatalf("state is not an APTRepository state")
+	}
+	if repo.SignedBy != key.Name {
+		t.Errorf("got SignedBy %v, expected %v", repo.SignedBy, key.Name)
+	}
+}
+
+func TestStatesWithoutURI(t *testing.T) {
+	r := &aptrepository.Resource{
+		Name: "docker",
+	}
+	if _, err := r.States(); err == nil {
+		t.Errorf("got no error when the uri is not specified")
+	}
+}
+
+func TestStatesAbsent(t *testing.T) {
+	r := &aptrepository.Resource{
+		Name:  "docker",
+		State: "absent",
+	}
+
+	states, err := r.States()
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if got := len(states); got != 1 {
+		t.Fatalf("got %d states, expected just 1", got)
+	}
+	repo, ok := states[0].(*packagemanager.APTRepositoryAbsence)
+	if !ok {
+		t.Fatalf("state is not an APTRepositoryAbsence state")
+	}
+	if repo.Path() != "/etc/apt/sources.list.d/docker.list" {
+		t.Errorf("got Path %v, expected /etc/apt/sources.list.d/docker.list", repo.Path())
+	}
+}
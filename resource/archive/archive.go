@@ -0,0 +1,83 @@
+/*
+Package archive implements the archive resource which extracts the archive
+file into the directory.
+*/
+package archive
+
+import (
+	"fmt"
+
+	"github.com/harukasan/orchestra-pit/opit/logger"
+	"github.com/harukasan/orchestra-pit/state"
+	"github.com/harukasan/orchestra-pit/state/archive"
+	"github.com/harukasan/orchestra-pit/state/file"
+)
+
+// Resource represents the attributes of archive resource.
+type Resource struct {
+	Desc            string `json:"desc" yaml:"desc" doc:"the description of the resource"`
+	Src             string `json:"src" yaml:"src" doc:"the path of the archive file on the host, e.g. downloaded by remote_file"`
+	Dest            string `json:"dest" yaml:"dest" doc:"the directory to extract the archive into"`
+	Format          string `json:"format" yaml:"format" doc:"the format of the archive" enum:"tar,tar.gz,tar.xz,zip" default:"by the extension of src"`
+	StripComponents int    `json:"strip_components" yaml:"strip_components" doc:"the number of the leading path components to remove from the entries" default:"0"`
+	Checksum        string `json:"checksum" yaml:"checksum" doc:"the checksum of the archive to verify, e.g. sha256:<hex>"`
+	Owner           string `json:"owner" yaml:"owner" doc:"the name or the ID of the owner of the extracted files"`
+	Group           string `json:"group" yaml:"group" doc:"the name or the ID of the group of the extracted files"`
+	Mode            string `json:"mode" yaml:"mode" doc:"the mode in the manner of chmod applied to the modes in the archive, e.g. go-w"`
+}
+
+func (r *Resource) States() ([]state.State, error) {
+	if r.Src == "" {
+		return nil, fmt.Errorf(`parameter "src" is required`)
+	}
+	if r.Dest == "" {
+		return nil, fmt.Errorf(`parameter "dest" is required`)
+	}
+	if r.Format == "" {
+		r.Format = archive.FormatOf(r.Src)
+		if r.Format == "" {
+			return nil, fmt.Errorf(`parameter "format" is required for %s`, r.Src)
+		}
+		logger.Debugf(`parameter "format" is not specified, assume as "%s"`, r.Format)
+	}
+	if !contains(archive.Formats(), r.Format) {
+		return nil, fmt.Errorf(`unknown format "%s"`, r.Format)
+	}
+	return []state.State{&archive.Extracted{
+		Archive:         r.Src,
+		Format:          r.Format,
+		Dest:            r.Dest,
+		StripComponents: r.StripComponents,
+		Checksum:        r.Checksum,
+		Owner:           r.Owner,
+		Group:           r.Group,
+		Mode:            r.Mode,
+	}}, nil
+}
+
+// Validate checks the number of the components, the checksum and the mode.
+func (r *Resource) Validate() error {
+	if r.StripComponents < 0 {
+		return fmt.Errorf(`invalid strip_components %d`, r.StripComponents)
+	}
+	if r.Checksum != "" {
+		if _, err := file.ParseChecksum(r.Checksum); err != nil {
+			return err
+		}
+	}
+	if r.Mode != "" {
+		if _, err := file.ParseMode(r.Mode, 0); err != nil {
+			return fmt.Errorf(`invalid mode "%s": %s`, r.Mode, err)
+		}
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package archive_test
+
+import (
+	"testing"
+
+	"github.com/harukasan/orchestra-pit/resource/archive"
+	archivestate "github.com/harukasan/orchestra-pit/state/archive"
+)
+
+func TestStatesWithFormatOfSrc(t *testing.T) {
+	formats := map[string]string{
+		"/var/cache/go1.22.linux-amd64.tar.gz": "tar.gz",
+		"/var/cache/jdk-21.tgz":                "tar.gz",
+		"/var/cache/node-v20.tar.xz":           "tar.xz",
+		"/var/cache/tool.tar":                  "tar",
+		"/var/cache/tool.zip":                  "zip",
+	}
+	for src, format := range formats {
+		r := &archive.Resource{Src: src, Dest: "/usr/local/go", StripComponents: 1}
+
+		states, err := r.States()
+		if err != nil {
+			t.Fatalf("%s: got error: %v", src, err)
+		}
+		if got := len(states); got != 1 {
+			t.Fatalf("%s: got %d states, expected just 1", src, got)
+		}
+		s, ok := states[0].(*archivestate.Extracted)
+		if !ok {
+			t.Fatalf("%s: state is not an Extracted state", src)
+		}
+		if s.Format != format {
+			t.Errorf("%s: got Format %v, expected %v", src, s.Format, format)
+		}
+		if s.Archive != src || s.Dest != r.Dest || s.StripComponents != 1 {
+			t.Errorf("%s: got %+v", src, s)
+		}
+	}
+}
+
+func TestStatesWithFormat(t *testing.T) {
+	r := &archive.Resource{Src: "/var/cache/download", Format: "zip", Dest: "/opt/tool", Owner: "deploy", Mode: "go-w"}
+
+	states, err := r.States()
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	s, ok := states[0].(*archivestate.Extracted)
+	if !ok {
+		t.Fatalf("state is not an Extracted state")
+	}
+	if s.Format != "zip" {
+		t.Errorf("got Format %v, expected zip", s.Format)
+	}
+	if s.Owner != "deploy" || s.Mode != "go-w" {
+		t.Errorf("got Owner %v and Mode %v, expected deploy and go-w", s.Owner, s.Mode)
+	}
+}
+
+func TestStatesWithInvalidParameters(t *testing.T) {
+	invalid := []*archive.Resource{
+		{Dest: "/opt/a"},
+		{Src: "/tmp/a.zip"},
+		{Src: "/tmp/a.rar", Dest: "/opt/a"},
+		{Src: "/tmp/a", Format: "rar", Dest: "/opt/a"},
+	}
+	for _, r := range invalid {
+		if _, err := r.States(); err == nil {
+			t.Errorf("got no error for %+v", r)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	invalid := []*archive.Resource{
+		{Src: "/tmp/a.zip", Dest: "/opt/a", StripComponents: -1},
+		{Src: "/tmp/a.zip", Dest: "/opt/a", Checksum: "sha1:abc"},
+		{Src: "/tmp/a.zip", Dest: "/opt/a", Mode: "0999"},
+	}
+	for _, r := range invalid {
+		if err := r.Validate(); err == nil {
+			t.Errorf("got no error for %+v", r)
+		}
+	}
+}
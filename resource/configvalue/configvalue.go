@@ -0,0 +1,89 @@
+/*
+Package configvalue implements the config_value resource which manages the
+value of the key in the structured configuration file.
+*/
+package configvalue
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/harukasan/orchestra-pit/opit/logger"
+	"github.com/harukasan/orchestra-pit/state"
+	"github.com/harukasan/orchestra-pit/state/configfile"
+)
+
+// Resource represents the attributes of config_value resource.
+type Resource struct {
+	Desc    string      `json:"desc" yaml:"desc" doc:"the description of the resource"`
+	Path    string      `json:"path" yaml:"path" doc:"the path of the configuration file"`
+	Format  string      `json:"format" yaml:"format" doc:"the format of the file" enum:"ini,json,yaml" default:"by the extension of the path"`
+	Section string      `json:"section" yaml:"section" doc:"the section of the key in INI; the keys before the first section if empty"`
+	Key     string      `json:"key" yaml:"key" doc:"the key, which is the path of the keys separated by \".\" in JSON and YAML, e.g. log-opts.max-size"`
+	Value   interface{} `json:"value" yaml:"value" doc:"the value of the key"`
+	Backup  string      `json:"backup" yaml:"backup" doc:"the name or the path to back up the existing file before changing"`
+	State   string      `json:"state" yaml:"state" doc:"the state of the key" enum:"present,absent" default:"present"`
+}
+
+func (r *Resource) States() ([]state.State, error) {
+	if r.State == "" {
+		r.State = "present"
+		logger.Debugf(`parameter "state" is not specified, assume as "%s"`, r.State)
+	}
+	if r.Path == "" {
+		return nil, fmt.Errorf(`parameter "path" is required`)
+	}
+	if r.Key == "" {
+		return nil, fmt.Errorf(`parameter "key" is required`)
+	}
+	if r.Format == "" {
+		r.Format = configfile.FormatOf(r.Path)
+		if r.Format == "" {
+			return nil, fmt.Errorf(`parameter "format" is required for %s`, r.Path)
+		}
+		logger.Debugf(`parameter "format" is not specified, assume as "%s"`, r.Format)
+	}
+	if !contains(configfile.Formats(), r.Format) {
+		return nil, fmt.Errorf(`unknown format "%s"`, r.Format)
+	}
+	if r.Section != "" && r.Format != "ini" {
+		return nil, fmt.Errorf(`parameter "section" is only for ini format`)
+	}
+	if r.Backup != "" && !strings.ContainsRune(r.Backup, '/') {
+		r.Backup = path.Join(path.Dir(r.Path), r.Backup)
+	}
+
+	switch r.State {
+	case "present":
+		if r.Value == nil {
+			return nil, fmt.Errorf(`parameter "value" is required`)
+		}
+		return []state.State{&configfile.Value{
+			Name:    r.Path,
+			Format:  r.Format,
+			Section: r.Section,
+			Key:     r.Key,
+			Value:   r.Value,
+			Backup:  r.Backup,
+		}}, nil
+	case "absent":
+		return []state.State{&configfile.Absence{
+			Name:    r.Path,
+			Format:  r.Format,
+			Section: r.Section,
+			Key:     r.Key,
+			Backup:  r.Backup,
+		}}, nil
+	}
+	return nil, fmt.Errorf(`unknown state "%s"`, r.State)
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package configvalue_test
+
+import (
+	"testing"
+
+	"github.com/harukasan/orchestra-pit/resource/configvalue"
+	"github.com/harukasan/orchestra-pit/state/configfile"
+)
+
+func TestStatesWithFormatOfPath(t *testing.T) {
+	r := &configvalue.Resource{
+		Path:   "/etc/docker/daemon.json",
+		Key:    "log-opts.max-size",
+		Value:  "10m",
+		Backup: "daemon.json.orig",
+	}
+
+	states, err := r.States()
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if got := len(states); got != 1 {
+		t.Fatalf("got %d states, expected just 1", got)
+	}
+	s, ok := states[0].(*configfile.Value)
+	if !ok {
+		t.Fatalf("state is not a Value state")
+	}
+	if s.Format != "json" {
+		t.Errorf("got Format %v, expected json", s.Format)
+	}
+	if s.Key != r.Key || s.Value != "10m" {
+		t.Errorf("got Key %v and Value %v, expected %v and 10m", s.Key, s.Value, r.Key)
+	}
+	// the backup name is resolved in the directory of the file.
+	if s.Backup != "/etc/docker/daemon.json.orig" {
+		t.Errorf("got Backup %v, expected /etc/docker/daemon.json.orig", s.Backup)
+	}
+}
+
+func TestStatesAbsentInSection(t *testing.T) {
+	r := &configvalue.Resource{
+		Path:    "/etc/php/php.ini",
+		Section: "Date",
+		Key:     "date.timezone",
+		State:   "absent",
+	}
+
+	states, err := r.States()
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	s, ok := states[0].(*configfile.Absence)
+	if !ok {
+		t.Fatalf("state is not an Absence state")
+	}
+	if s.Format != "ini" || s.Section != "Date" {
+		t.Errorf("got Format %v and Section %v, expected ini and Date", s.Format, s.Section)
+	}
+}
+
+func TestStatesWithInvalidParameters(t *testing.T) {
+	invalid := []*configvalue.Resource{
+		{Path: "/etc/php.conf", Key: "a", Value: "b"},
+		{Path: "/etc/a.json", Key: "a"},
+		{Path: "/etc/a.json", Key: "a", Value: 1, Section: "s"},
+		{Path: "/etc/a.toml", Format: "toml", Key: "a", Value: 1},
+	}
+	for _, r := range invalid {
+		if _, err := r.States(); err == nil {
+			t.Errorf("got no error for %+v", r)
+		}
+	}
+}
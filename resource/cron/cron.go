@@ -0,0 +1,108 @@
+/*
+Package cron implements the cron resource which manages the cron jobs.
+*/
+package cron
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/harukasan/orchestra-pit/opit/logger"
+	"github.com/harukasan/orchestra-pit/state"
+	"github.com/harukasan/orchestra-pit/state/cron"
+)
+
+// namePattern is the names of the files which cron reads in /etc/cron.d.
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Resource represents the attributes of cron resource.
+type Resource struct {
+	Desc    string            `json:"desc" yaml:"desc" doc:"the description of the resource"`
+	Name    string            `json:"name" yaml:"name" doc:"the name of the job, which is the name of the file in /etc/cron.d"`
+	Minute  string            `json:"minute" yaml:"minute" doc:"the minute of the schedule" default:"*"`
+	Hour    string            `json:"hour" yaml:"hour" doc:"the hour of the schedule" default:"*"`
+	Day     string            `json:"day" yaml:"day" doc:"the day of the month of the schedule" default:"*"`
+	Month   string            `json:"month" yaml:"month" doc:"the month of the schedule" default:"*"`
+	Weekday string            `json:"weekday" yaml:"weekday" doc:"the day of the week of the schedule" default:"*"`
+	User    string            `json:"user" yaml:"user" doc:"the user to run the command" default:"root"`
+	Command string            `json:"command" yaml:"command" doc:"the command to run"`
+	Env     map[string]string `json:"env" yaml:"env" doc:"the environment variables of the job"`
+	Crontab bool              `json:"crontab" yaml:"crontab" doc:"manage the job in the crontab of the user instead of /etc/cron.d"`
+	State   string            `json:"state" yaml:"state" doc:"the state of the job" enum:"present,absent" default:"present"`
+}
+
+func (r *Resource) States() ([]state.State, error) {
+	if r.State == "" {
+		r.State = "present"
+		logger.Debugf(`parameter "state" is not specified, assume as "%s"`, r.State)
+	}
+	if r.Name == "" {
+		return nil, fmt.Errorf(`parameter "name" is required`)
+	}
+	if r.User == "" {
+		r.User = "root"
+		logger.Debugf(`parameter "user" is not specified, assume as "%s"`, r.User)
+	}
+	table := cron.Table{User: r.User}
+	if !r.Crontab {
+		table.File = path.Join(cron.CronDir, r.Name)
+	}
+
+	switch r.State {
+	case "present":
+		if r.Command == "" {
+			return nil, fmt.Errorf(`parameter "command" is required`)
+		}
+		entry := cron.Entry{
+			Minute:  schedule(r.Minute),
+			Hour:    schedule(r.Hour),
+			Day:     schedule(r.Day),
+			Month:   schedule(r.Month),
+			Weekday: schedule(r.Weekday),
+			User:    r.User,
+			Command: r.Command,
+			Env:     r.Env,
+		}
+		return []state.State{&cron.Job{Name: r.Name, Table: table, Entry: entry}}, nil
+	case "absent":
+		return []state.State{&cron.Absence{Name: r.Name, Table: table}}, nil
+	}
+	return nil, fmt.Errorf(`unknown state "%s"`, r.State)
+}
+
+// Validate checks the name of the job, the schedule and the environment
+// variables.
+func (r *Resource) Validate() error {
+	if !r.Crontab && !namePattern.MatchString(r.Name) {
+		return fmt.Errorf(`invalid name "%s": the file in /etc/cron.d must consist of letters, digits, "_" and "-"`, r.Name)
+	}
+	for _, f := range []string{r.Minute, r.Hour, r.Day, r.Month, r.Weekday} {
+		if strings.ContainsAny(f, " \t") {
+			return fmt.Errorf(`invalid schedule "%s": it must not contain spaces`, f)
+		}
+	}
+	if strings.Contains(r.Command, "\n") {
+		return fmt.Errorf(`parameter "command" must be a line`)
+	}
+	for k, v := range r.Env {
+		if !envNamePattern.MatchString(k) {
+			return fmt.Errorf(`invalid environment variable name "%s"`, k)
+		}
+		if strings.Contains(v, "\n") {
+			return fmt.Errorf(`the environment variable "%s" must be a line`, k)
+		}
+	}
+	return nil
+}
+
+// schedule returns the field of the schedule, which is "*" if not specified.
+func schedule(f string) string {
+	if f == "" {
+		return "*"
+	}
+	return f
+}
@@ -0,0 +1,87 @@
+package cron_test
+
+import (
+	"testing"
+
+	"github.com/harukasan/orchestra-pit/resource"
+	"github.com/harukasan/orchestra-pit/resource/cron"
+	cronstate "github.com/harukasan/orchestra-pit/state/cron"
+)
+
+func TestStatesInSystemTable(t *testing.T) {
+	r := &cron.Resource{Name: "backup", Hour: "3", Minute: "0", Command: "/usr/local/bin/backup"}
+
+	states, err := r.States()
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if got := len(states); got != 1 {
+		t.Fatalf("got %d states, expected just 1", got)
+	}
+	s, ok := states[0].(*cronstate.Job)
+	if !ok {
+		t.Fatalf("state is not a Job state")
+	}
+	if s.Table.File != "/etc/cron.d/backup" {
+		t.Errorf("got File %v, expected /etc/cron.d/backup", s.Table.File)
+	}
+	// the system table has the user column, which is root by default.
+	if s.Entry.User != "root" {
+		t.Errorf("got User %v, expected root", s.Entry.User)
+	}
+	if s.Entry.Minute != "0" || s.Entry.Hour != "3" {
+		t.Errorf("got Minute %v and Hour %v, expected 0 and 3", s.Entry.Minute, s.Entry.Hour)
+	}
+	if s.Entry.Day != "*" || s.Entry.Month != "*" || s.Entry.Weekday != "*" {
+		t.Errorf("got Day %v, Month %v and Weekday %v, expected *", s.Entry.Day, s.Entry.Month, s.Entry.Weekday)
+	}
+}
+
+func TestStatesInCrontab(t *testing.T) {
+	r := &cron.Resource{Name: "sync", User: "alice", Crontab: true, Command: "/usr/bin/sync"}
+
+	states, err := r.States()
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	s, ok := states[0].(*cronstate.Job)
+	if !ok {
+		t.Fatalf("state is not a Job state")
+	}
+	// the crontab of the user has no file under /etc/cron.d.
+	if s.Table.File != "" {
+		t.Errorf("got File %v, expected the crontab", s.Table.File)
+	}
+	if s.Table.User != "alice" {
+		t.Errorf("got the crontab of %v, expected alice", s.Table.User)
+	}
+
+	r.State = "absent"
+	states, err = r.States()
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	a, ok := states[0].(*cronstate.Absence)
+	if !ok {
+		t.Fatalf("state is not an Absence state")
+	}
+	if a.Name != "sync" || a.Table.File != "" || a.Table.User != "alice" {
+		t.Errorf("got %+v, expected the job in the crontab of alice", a)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cases := []*cron.Resource{
+		{Name: "back.up", Command: "true"},
+		{Name: "backup", Minute: "0 3", Command: "true"},
+		{Name: "backup", Command: "true", Env: map[string]string{"MAIL TO": "root"}},
+	}
+	for _, r := range cases {
+		if err := resource.Validate(r); err == nil {
+			t.Errorf("got no error for %+v", r)
+		}
+	}
+	if err := resource.Validate(&cron.Resource{Name: "back.up", Crontab: true, Command: "true"}); err != nil {
+		t.Errorf("got error for the job in the crontab: %v", err)
+	}
+}
@@ -0,0 +1,36 @@
+/*
+Package debconf implements the applying state of the answers of debconf.
+*/
+package debconf
+
+import (
+	"fmt"
+
+	"github.com/harukasan/orchestra-pit/resource/packagemanager"
+	"github.com/harukasan/orchestra-pit/state"
+)
+
+// Resource represents the attributes of debconf resource.
+//
+// The debconf resource preseeds the answers of the package which is installed
+// as a dependency of the other package. To preseed the answers of the package
+// declared in the recipe, use "debconf" attribute of the package resource.
+type Resource struct {
+	Desc       string                     `json:"desc" yaml:"desc" doc:"the description of the resource"`
+	Package    string                     `json:"package" yaml:"package" doc:"the name of the package which owns the questions"`
+	Selections []packagemanager.Selection `json:"selections" yaml:"selections" doc:"the answers of debconf"`
+}
+
+func (r *Resource) States() ([]state.State, error) {
+	if r.Package == "" {
+		return nil, fmt.Errorf(`parameter "package" is required`)
+	}
+	if len(r.Selections) == 0 {
+		return nil, fmt.Errorf(`parameter "selections" is required`)
+	}
+	s, err := packagemanager.DebconfState(r.Package, r.Selections)
+	if err != nil {
+		return nil, err
+	}
+	return []state.State{s}, nil
+}
@@ -0,0 +1,175 @@
+/*
+Package doc generates the reference documents of the resource types from the
+registered resources and the tags of their attributes, see the schema package.
+*/
+package doc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/harukasan/orchestra-pit/resource"
+	"github.com/harukasan/orchestra-pit/resource/schema"
+)
+
+// commonAttributes are the attributes which every resource has.
+var commonAttributes = [][2]string{
+	{"type", "the type of the resource"},
+	{"with_items", "the items to expand the resource for each, referred as {{ .item }}"},
+	{"for_each", "the alias of with_items"},
+	{"notify", "the actions to take on the other resources when the resource is applied, e.g. {\"resource\": \"service:nginx\", \"action\": \"restart\"}"},
+}
+
+// Row describes the attribute in the reference. The attributes of the nested
+// objects are named as parent[].child or parent.child.
+type Row struct {
+	Name    string
+	Type    string
+	Default string
+	Doc     string
+	Enum    []string
+}
+
+// Rows returns the attributes of the resource type.
+func Rows(t *resource.Type) []Row {
+	return rows("", reflect.TypeOf(t.New()).Elem())
+}
+
+func rows(prefix string, t reflect.Type) []Row {
+	rs := []Row{}
+	for _, a := range schema.Attributes(t) {
+		ft := a.Field.Type
+		rs = append(rs, Row{
+			Name:    prefix + a.Name,
+			Type:    TypeName(ft),
+			Default: a.Default(),
+			Doc:     a.Doc(),
+			Enum:    a.Enum(),
+		})
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		switch {
+		case ft.Kind() == reflect.Struct:
+			rs = append(rs, rows(prefix+a.Name+".", ft)...)
+		case ft.Kind() == reflect.Slice && ft.Elem().Kind() == reflect.Struct:
+			rs = append(rs, rows(prefix+a.Name+"[].", ft.Elem())...)
+		}
+	}
+	return rs
+}
+
+// TypeName returns the name of the type of the attribute in the documents.
+func TypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return TypeName(t.Elem())
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "list of " + TypeName(t.Elem())
+	case reflect.Map:
+		return "map of " + TypeName(t.Elem())
+	case reflect.Struct:
+		return "object"
+	case reflect.Interface:
+		return "any"
+	}
+	return t.Kind().String()
+}
+
+// description returns the description of the attribute with its values.
+func (r Row) description() string {
+	d := r.Doc
+	if len(r.Enum) > 0 {
+		d += "; one of " + strings.Join(r.Enum, ", ")
+	}
+	return d
+}
+
+// Markdown writes the reference of the resource types in Markdown.
+func Markdown(w io.Writer, types []*resource.Type) error {
+	buf := &bytes.Buffer{}
+	buf.WriteString("# Resource reference\n\n")
+	buf.WriteString("The resources of the recipe have the following attributes in common.\n\n")
+	buf.WriteString("| Attribute | Description |\n|---|---|\n")
+	for _, a := range commonAttributes {
+		fmt.Fprintf(buf, "| `%s` | %s |\n", a[0], escapeMarkdown(a[1]))
+	}
+
+	for _, t := range types {
+		fmt.Fprintf(buf, "\n## %s\n\n", t.Name)
+		fmt.Fprintf(buf, "The %s resource %s.\n\n", t.Name, t.Description)
+		buf.WriteString("| Attribute | Type | Default | Description |\n|---|---|---|---|\n")
+		for _, r := range Rows(t) {
+			def := ""
+			if r.Default != "" {
+				def = "`" + r.Default + "`"
+			}
+			fmt.Fprintf(buf, "| `%s` | %s | %s | %s |\n", r.Name, r.Type, def, escapeMarkdown(r.description()))
+		}
+		if t.Example != "" {
+			fmt.Fprintf(buf, "\n### Example\n\n```json\n%s\n```\n", t.Example)
+		}
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func escapeMarkdown(s string) string {
+	return strings.NewReplacer("|", "\\|", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// Man writes the reference of the resource types in the format of the man
+// page, in the section 5.
+func Man(w io.Writer, types []*resource.Type) error {
+	buf := &bytes.Buffer{}
+	buf.WriteString(".TH OPIT-RESOURCES 5\n")
+	buf.WriteString(".SH NAME\nopit-resources \\- the resource types of the opit recipe\n")
+	buf.WriteString(".SH DESCRIPTION\nThe resources of the recipe have the following attributes in common.\n")
+	for _, a := range commonAttributes {
+		fmt.Fprintf(buf, ".TP\n.B %s\n%s\n", escapeRoff(a[0]), escapeRoff(a[1]))
+	}
+	buf.WriteString(".SH RESOURCES\n")
+	for _, t := range types {
+		fmt.Fprintf(buf, ".SS %s\n", escapeRoff(t.Name))
+		fmt.Fprintf(buf, "The %s resource %s.\n", escapeRoff(t.Name), escapeRoff(t.Description))
+		for _, r := range Rows(t) {
+			fmt.Fprintf(buf, ".TP\n.B %s\n(%s", escapeRoff(r.Name), escapeRoff(r.Type))
+			if r.Default != "" {
+				fmt.Fprintf(buf, ", default: %s", escapeRoff(r.Default))
+			}
+			fmt.Fprintf(buf, ") %s\n", escapeRoff(r.description()))
+		}
+		if t.Example != "" {
+			buf.WriteString(".PP\nExample:\n.PP\n.nf\n.RS\n")
+			buf.WriteString(escapeRoff(t.Example))
+			buf.WriteString("\n.RE\n.fi\n")
+		}
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// escapeRoff escapes the backslashes, the hyphens and the control characters
+// at the beginning of the lines.
+func escapeRoff(s string) string {
+	s = strings.NewReplacer("\\", "\\e", "-", "\\-").Replace(s)
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		if strings.HasPrefix(l, ".") || strings.HasPrefix(l, "'") {
+			lines[i] = "\\&" + l
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,53 @@
+package doc_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/harukasan/orchestra-pit/resource"
+	"github.com/harukasan/orchestra-pit/resource/doc"
+)
+
+func TestMarkdown(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := doc.Markdown(buf, resource.Types()); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	out := buf.String()
+	for _, s := range []string{
+		"## package\n",
+		"| `state` | string | `installed` | the state of the package; one of installed, removed |",
+		"| `debconf[].question` | string |  | the name of the question |",
+		"```json\n{\n  \"type\": \"file\",",
+	} {
+		if !strings.Contains(out, s) {
+			t.Errorf("the document does not contain %q", s)
+		}
+	}
+}
+
+func TestMan(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := doc.Man(buf, resource.Types()); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if !strings.Contains(buf.String(), ".SS apt_repository\n") {
+		t.Errorf("the man page does not have the section of apt_repository")
+	}
+}
+
+// TestDocumented tests whether every attribute of the resources has the
+// description, so that the documents do not drift from the code.
+func TestDocumented(t *testing.T) {
+	for _, typ := range resource.Types() {
+		if typ.Description == "" {
+			t.Errorf("%s has no description", typ.Name)
+		}
+		for _, r := range doc.Rows(typ) {
+			if r.Doc == "" {
+				t.Errorf("%s.%s has no description", typ.Name, r.Name)
+			}
+		}
+	}
+}
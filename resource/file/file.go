@@ -5,8 +5,10 @@ package file
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path"
+	"regexp"
 	"strings"
 
 	"github.com/harukasan/orchestra-pit/opit/logger"
@@ -15,12 +17,44 @@ import (
 )
 
 type Resource struct {
-	Desc   string `json:"desc" yaml:"desc"`
-	Path   string `json:"path"  yaml:"path"`
-	State  string `json:"state" yaml:"state"`
-	Src    string `json:"src"   yaml:"src"`
-	Backup string `json:"backup" yaml:"backup"`
-	Mode   string `json:"mode"  yaml:"mode"`
+	Desc   string `json:"desc" yaml:"desc" doc:"the description of the resource"`
+	Path   string `json:"path"  yaml:"path" doc:"the path of the file"`
+	State  string `json:"state" yaml:"state" doc:"the state of the file" enum:"file,directory,symlink,hardlink,absence,line,line_absence,block,block_absence" default:"file"`
+	Src    string `json:"src"   yaml:"src" doc:"the source of the file relative to the recipe, or the target of the link" default:"files/<path>"`
+	Backup string `json:"backup" yaml:"backup" doc:"the name or the path to back up the existing file before overwriting"`
+	Mode   string `json:"mode"  yaml:"mode" doc:"the file mode in the manner of chmod, e.g. 0644 or u+rw"`
+	Line   string `json:"line"  yaml:"line" doc:"the line to put into the file, or to remove from the file"`
+	Match  string `json:"match" yaml:"match" doc:"the regular expression of the lines to replace with the line, or to remove"`
+	Block  string `json:"block" yaml:"block" doc:"the content of the block to put into the file"`
+	Marker string `json:"marker" yaml:"marker" doc:"the name of the block in the marker comments" default:"opit managed block"`
+
+	Content  *string `json:"content" yaml:"content" doc:"the content of the file instead of src, which is written as it is unless template is true" raw:"true"`
+	Template bool    `json:"template" yaml:"template" doc:"render src or content as a template with the variables of the recipe"`
+	Newline  string  `json:"newline" yaml:"newline" doc:"the trailing newline of content and the template; ensure adds it if missing" enum:"ensure,keep,strip" default:"ensure"`
+
+	recipeDir string
+	filesDir  string
+	render    func(string) (string, error)
+}
+
+// SetRecipeDir sets the directory which the relative source of the file is
+// resolved against. If it is not set, the source is relative to the working
+// directory.
+func (r *Resource) SetRecipeDir(dir string) {
+	r.recipeDir = dir
+}
+
+// SetFilesDir sets the directory which the default source of the file is
+// searched in. If it is not set, the files directory in the working directory
+// is used.
+func (r *Resource) SetFilesDir(dir string) {
+	r.filesDir = dir
+}
+
+// SetTemplateFunc sets the function which renders src or content as a
+// template.
+func (r *Resource) SetTemplateFunc(render func(text string) (string, error)) {
+	r.render = render
 }
 
 func (r *Resource) States() ([]state.State, error) {
@@ -30,13 +64,14 @@ func (r *Resource) States() ([]state.State, error) {
 		r.State = "file"
 		logger.Debugf(`parameter "state" is not specified, assume as "%s"`, r.State)
 	}
-	if stateFuncMap[r.State] != nil {
-		s, err := stateFuncMap[r.State](r)
-		if err != nil {
-			return nil, err
-		}
-		states = append(states, s)
+	if stateFuncMap[r.State] == nil {
+		return nil, fmt.Errorf(`unknown state "%s"`, r.State)
 	}
+	s, err := stateFuncMap[r.State](r)
+	if err != nil {
+		return nil, err
+	}
+	states = append(states, s)
 
 	if r.Mode != "" {
 		s := &file.Mode{
@@ -49,14 +84,33 @@ func (r *Resource) States() ([]state.State, error) {
 	return states, nil
 }
 
+// Validate checks the format of the mode and the regular expression.
+func (r *Resource) Validate() error {
+	if r.Mode != "" {
+		if _, err := file.ParseMode(r.Mode, 0); err != nil {
+			return fmt.Errorf(`invalid mode "%s": %s`, r.Mode, err)
+		}
+	}
+	if r.Match != "" {
+		if _, err := regexp.Compile(r.Match); err != nil {
+			return fmt.Errorf(`invalid match "%s": %s`, r.Match, err)
+		}
+	}
+	return nil
+}
+
 type stateFunc func(r *Resource) (state.State, error)
 
 var stateFuncMap = map[string]stateFunc{
-	"absence":   absenceState,
-	"directory": directoryState,
-	"file":      fileState,
-	"hardlink":  hardlinkState,
-	"symlink":   symlinkState,
+	"absence":       absenceState,
+	"block":         blockState,
+	"block_absence": blockAbsenceState,
+	"directory":     directoryState,
+	"file":          fileState,
+	"hardlink":      hardlinkState,
+	"line":          lineState,
+	"line_absence":  lineAbsenceState,
+	"symlink":       symlinkState,
 }
 
 func absenceState(r *Resource) (state.State, error) {
@@ -81,21 +135,51 @@ func fileState(r *Resource) (state.State, error) {
 	if r.Path == "" {
 		return nil, fmt.Errorf(`parameter "path" is required`)
 	}
+	if r.Content != nil {
+		if r.Src != "" {
+			return nil, fmt.Errorf(`parameter "src" and "content" can not be used together`)
+		}
+		content := *r.Content
+		if r.Template && r.render != nil {
+			var err error
+			content, err = r.render(content)
+			if err != nil {
+				return nil, fmt.Errorf("can not render the content: %s", err)
+			}
+		}
+		return contentState(r, content)
+	}
 	if r.Src == "" {
 		if strings.HasPrefix(r.Path, "/") {
-			wd, err := os.Getwd()
-			if err != nil {
-				return nil, err
+			dir := r.filesDir
+			if dir == "" {
+				wd, err := os.Getwd()
+				if err != nil {
+					return nil, err
+				}
+				dir = path.Join(wd, "files")
 			}
-			r.Src = path.Join(wd, "files", r.Path[1:])
+			r.Src = path.Join(dir, r.Path[1:])
 		}
 		logger.Debugf(`parameter "src" is not specified, assume as "%s"`, r.Src)
+	} else if !path.IsAbs(r.Src) && r.recipeDir != "" {
+		r.Src = path.Join(r.recipeDir, r.Src)
 	}
-	if r.Backup != "" {
-		if !strings.ContainsRune(r.Backup, '/') {
-			r.Backup = path.Join(path.Dir(r.Path), r.Backup)
+	if r.Template {
+		data, err := ioutil.ReadFile(r.Src)
+		if err != nil {
+			return nil, err
 		}
+		content := string(data)
+		if r.render != nil {
+			content, err = r.render(content)
+			if err != nil {
+				return nil, fmt.Errorf("can not render the template %s: %s", r.Src, err)
+			}
+		}
+		return contentState(r, content)
 	}
+	r.resolveBackup()
 	return &file.Copy{
 		Name:   r.Path,
 		Src:    r.Src,
@@ -128,3 +212,94 @@ func symlinkState(r *Resource) (state.State, error) {
 		Src:  r.Src,
 	}, nil
 }
+
+// contentState returns the state of the file which has the content. The
+// trailing newline of the content follows the newline attribute.
+func contentState(r *Resource, content string) (state.State, error) {
+	if r.Newline == "" {
+		r.Newline = "ensure"
+		logger.Debugf(`parameter "newline" is not specified, assume as "%s"`, r.Newline)
+	}
+	switch r.Newline {
+	case "ensure":
+		if content != "" && !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+	case "strip":
+		content = strings.TrimRight(content, "\n")
+	case "keep":
+	default:
+		return nil, fmt.Errorf(`unknown newline "%s"`, r.Newline)
+	}
+	r.resolveBackup()
+	return &file.Content{
+		Name:    r.Path,
+		Content: []byte(content),
+		Backup:  r.Backup,
+	}, nil
+}
+
+// resolveBackup resolves the backup name relative to the directory of the
+// file.
+func (r *Resource) resolveBackup() {
+	if r.Backup != "" && !strings.ContainsRune(r.Backup, '/') {
+		r.Backup = path.Join(path.Dir(r.Path), r.Backup)
+	}
+}
+
+func lineState(r *Resource) (state.State, error) {
+	if r.Path == "" {
+		return nil, fmt.Errorf(`parameter "path" is required`)
+	}
+	if r.Line == "" {
+		return nil, fmt.Errorf(`parameter "line" is required`)
+	}
+	r.resolveBackup()
+	return &file.Line{
+		Name:   r.Path,
+		Line:   r.Line,
+		Match:  r.Match,
+		Backup: r.Backup,
+	}, nil
+}
+
+func lineAbsenceState(r *Resource) (state.State, error) {
+	if r.Path == "" {
+		return nil, fmt.Errorf(`parameter "path" is required`)
+	}
+	if r.Line == "" && r.Match == "" {
+		return nil, fmt.Errorf(`parameter "line" or "match" is required`)
+	}
+	r.resolveBackup()
+	return &file.LineAbsence{
+		Name:   r.Path,
+		Line:   r.Line,
+		Match:  r.Match,
+		Backup: r.Backup,
+	}, nil
+}
+
+func blockState(r *Resource) (state.State, error) {
+	if r.Path == "" {
+		return nil, fmt.Errorf(`parameter "path" is required`)
+	}
+	r.resolveBackup()
+	return &file.Block{
+		Name:    r.Path,
+		Content: r.Block,
+		Marker:  r.Marker,
+		Backup:  r.Backup,
+	}, nil
+}
+
+func blockAbsenceState(r *Resource) (state.State, error) {
+	if r.Path == "" {
+		return nil, fmt.Errorf(`parameter "path" is required`)
+	}
+	r.resolveBackup()
+	return &file.BlockAbsence{
+		Name:   r.Path,
+		Marker: r.Marker,
+		Backup: r.Backup,
+	}, nil
+}
@@ -1,9 +1,12 @@
 package file_test
 
 import (
+	"io/ioutil"
+	"os"
 	"strings"
 	"testing"
 
+	"github.com/harukasan/orchestra-pit/resource"
 	"github.com/harukasan/orchestra-pit/resource/file"
 	filestate "github.com/harukasan/orchestra-pit/state/file"
 )
@@ -63,3 +66,139 @@ func TestCopyState(t *testing.T) {
 		t.Errorf("state is not a Copy state")
 	}
 }
+
+func TestValidate(t *testing.T) {
+	r := &file.Resource{
+		Path:  "/tmp/test",
+		State: "directory",
+		Mode:  "u+rwx,go=rx",
+	}
+	if err := resource.Validate(r); err != nil {
+		t.Errorf("got error: %v", err)
+	}
+
+	r = &file.Resource{
+		Path:  "/tmp/test",
+		State: "directory",
+		Mode:  "u~x",
+	}
+	if err := resource.Validate(r); err == nil {
+		t.Errorf("got no error for the invalid mode")
+	}
+
+	r = &file.Resource{
+		Path:  "/tmp/test",
+		State: "dir",
+	}
+	if err := resource.Validate(r); err == nil {
+		t.Errorf("got no error for the unknown state")
+	}
+}
+
+func TestLineState(t *testing.T) {
+	r := &file.Resource{
+		Path:   "/etc/ssh/sshd_config",
+		State:  "line",
+		Line:   "PermitRootLogin no",
+		Match:  "^#?PermitRootLogin",
+		Backup: "sshd_config.orig",
+	}
+	states, err := r.States()
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	s, ok := states[0].(*filestate.Line)
+	if !ok {
+		t.Fatalf("state is not a Line state: %v", states[0])
+	}
+	if s.Line != r.Line || s.Match != r.Match || s.Backup != "/etc/ssh/sshd_config.orig" {
+		t.Errorf("got unexpected state: %+v", s)
+	}
+
+	if _, err := (&file.Resource{Path: "/etc/hosts", State: "line"}).States(); err == nil {
+		t.Errorf("got no error without the line")
+	}
+	if err := resource.Validate(&file.Resource{Path: "/etc/hosts", State: "line_absence", Match: "("}); err == nil {
+		t.Errorf("got no error for the invalid match")
+	}
+}
+
+func TestContentState(t *testing.T) {
+	content := "hello"
+	r := &file.Resource{Path: "/etc/motd", Content: &content, Backup: "motd.orig"}
+	states, err := r.States()
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	s, ok := states[0].(*filestate.Content)
+	if !ok {
+		t.Fatalf("state is not a Content state: %v", states[0])
+	}
+	if string(s.Content) != "hello\n" || s.Backup != "/etc/motd.orig" {
+		t.Errorf("got unexpected state: %+v", s)
+	}
+
+	content = "hello\n\n"
+	r = &file.Resource{Path: "/etc/motd", Content: &content, Newline: "strip"}
+	states, err = r.States()
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if got := string(states[0].(*filestate.Content).Content); got != "hello" {
+		t.Errorf("got content %q, expected the newlines to be stripped", got)
+	}
+
+	if _, err := (&file.Resource{Path: "/etc/motd", Content: &content, Src: "/tmp/motd"}).States(); err == nil {
+		t.Errorf("got no error with both of src and content")
+	}
+}
+
+func TestTemplateState(t *testing.T) {
+	f, err := ioutil.TempFile("", "file_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("server_name {{ .name }};")
+	f.Close()
+
+	r := &file.Resource{Path: "/etc/nginx/site.conf", Src: f.Name(), Template: true}
+	r.SetTemplateFunc(func(text string) (string, error) {
+		return strings.Replace(text, "{{ .name }}", "www.example.com", -1), nil
+	})
+	states, err := r.States()
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if got := string(states[0].(*filestate.Content).Content); got != "server_name www.example.com;\n" {
+		t.Errorf("got content %q", got)
+	}
+}
+
+func TestTemplateContent(t *testing.T) {
+	content := "{{ .name }} {{ if .ok }}{{ end }}"
+	render := func(text string) (string, error) {
+		return strings.Replace(text, "{{ .name }}", "www", -1), nil
+	}
+
+	// the content is written as it is unless template is true.
+	r := &file.Resource{Path: "/etc/site.tmpl", Content: &content}
+	r.SetTemplateFunc(render)
+	states, err := r.States()
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if got := string(states[0].(*filestate.Content).Content); got != content+"\n" {
+		t.Errorf("got content %q, expected %q", got, content+"\n")
+	}
+
+	r = &file.Resource{Path: "/etc/site.conf", Content: &content, Template: true}
+	r.SetTemplateFunc(render)
+	states, err = r.States()
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if got := string(states[0].(*filestate.Content).Content); got != "www {{ if .ok }}{{ end }}\n" {
+		t.Errorf("got content %q", got)
+	}
+}
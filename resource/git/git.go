@@ -0,0 +1,51 @@
+/*
+Package git implements the git resource which checks out the revision of the
+git repository.
+*/
+package git
+
+import (
+	"fmt"
+
+	"github.com/harukasan/orchestra-pit/opit/logger"
+	"github.com/harukasan/orchestra-pit/state"
+	"github.com/harukasan/orchestra-pit/state/git"
+)
+
+// Resource represents the attributes of git resource.
+type Resource struct {
+	Desc        string `json:"desc" yaml:"desc" doc:"the description of the resource"`
+	Repository  string `json:"repository" yaml:"repository" doc:"the URL or the path of the repository"`
+	Revision    string `json:"revision" yaml:"revision" doc:"the branch, the tag or the commit ID to check out" default:"HEAD"`
+	Destination string `json:"destination" yaml:"destination" doc:"the directory of the working tree"`
+	Depth       int    `json:"depth" yaml:"depth" doc:"the number of the commits to fetch; the whole history if 0" default:"0"`
+	User        string `json:"user" yaml:"user" doc:"the name or the ID of the user to run git"`
+}
+
+func (r *Resource) States() ([]state.State, error) {
+	if r.Repository == "" {
+		return nil, fmt.Errorf(`parameter "repository" is required`)
+	}
+	if r.Destination == "" {
+		return nil, fmt.Errorf(`parameter "destination" is required`)
+	}
+	if r.Revision == "" {
+		r.Revision = "HEAD"
+		logger.Debugf(`parameter "revision" is not specified, assume as "%s"`, r.Revision)
+	}
+	return []state.State{&git.Checkout{
+		Repository: r.Repository,
+		Revision:   r.Revision,
+		Dest:       r.Destination,
+		Depth:      r.Depth,
+		User:       r.User,
+	}}, nil
+}
+
+// Validate checks the depth.
+func (r *Resource) Validate() error {
+	if r.Depth < 0 {
+		return fmt.Errorf(`invalid depth %d`, r.Depth)
+	}
+	return nil
+}
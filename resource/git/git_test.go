@@ -0,0 +1,71 @@
+package git_test
+
+import (
+	"testing"
+
+	"github.com/harukasan/orchestra-pit/resource/git"
+	gitstate "github.com/harukasan/orchestra-pit/state/git"
+)
+
+func TestStatesWithMinimumArguments(t *testing.T) {
+	r := &git.Resource{Repository: "https://example.com/tool.git", Destination: "/srv/tool"}
+
+	states, err := r.States()
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if got := len(states); got != 1 {
+		t.Fatalf("got %d states, expected just 1", got)
+	}
+	s, ok := states[0].(*gitstate.Checkout)
+	if !ok {
+		t.Fatalf("state is not a Checkout state")
+	}
+	if s.Repository != r.Repository || s.Dest != r.Destination {
+		t.Errorf("got %+v, expected the checkout of %v into %v", s, r.Repository, r.Destination)
+	}
+	// HEAD of the repository is checked out by default.
+	if s.Revision != "HEAD" {
+		t.Errorf("got Revision %v, expected HEAD", s.Revision)
+	}
+	if s.Depth != 0 || s.User != "" {
+		t.Errorf("got Depth %v and User %v, expected the whole history as the current user", s.Depth, s.User)
+	}
+}
+
+func TestStatesWithRevision(t *testing.T) {
+	r := &git.Resource{Repository: "https://example.com/tool.git", Revision: "v1.2.0", Destination: "/srv/tool", Depth: 1, User: "deploy"}
+
+	states, err := r.States()
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	s, ok := states[0].(*gitstate.Checkout)
+	if !ok {
+		t.Fatalf("state is not a Checkout state")
+	}
+	if s.Revision != "v1.2.0" {
+		t.Errorf("got Revision %v, expected v1.2.0", s.Revision)
+	}
+	if s.Depth != 1 || s.User != "deploy" {
+		t.Errorf("got Depth %v and User %v, expected 1 and deploy", s.Depth, s.User)
+	}
+}
+
+func TestStatesWithoutRequiredParameters(t *testing.T) {
+	invalid := []*git.Resource{
+		{Destination: "/srv/tool"},
+		{Repository: "https://example.com/tool.git"},
+	}
+	for _, r := range invalid {
+		if _, err := r.States(); err == nil {
+			t.Errorf("got no error for %+v", r)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := (&git.Resource{Depth: -1}).Validate(); err == nil {
+		t.Errorf("got no error for the negative depth")
+	}
+}
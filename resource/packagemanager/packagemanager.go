@@ -13,12 +13,43 @@ import (
 
 // Resource represents the attributes of package resource.
 type Resource struct {
-	Desc    string   `json:"desc" yaml:"desc"`
-	Name    string   `json:"name" yaml:"name"`
-	Version string   `json:"version" yaml:"version"`
-	Options []string `json:"options" yaml:"options"`
-	Update  bool     `json:"update" yaml:"update"`
-	State   string   `json:"state" yaml:"state"`
+	Desc    string      `json:"desc" yaml:"desc" doc:"the description of the resource"`
+	Name    string      `json:"name" yaml:"name" doc:"the name of the package"`
+	Version string      `json:"version" yaml:"version" doc:"the version of the package, or the constraint such as \">= 1.2, < 2\""`
+	Options []string    `json:"options" yaml:"options" doc:"the additional options of the package manager"`
+	Update  bool        `json:"update" yaml:"update" doc:"update the package index before installing"`
+	Hold    *bool       `json:"hold" yaml:"hold" doc:"hold the package not to be upgraded or removed"`
+	Debconf []Selection `json:"debconf" yaml:"debconf" doc:"the answers of debconf to preseed before installing"`
+	State   string      `json:"state" yaml:"state" doc:"the state of the package" enum:"installed,removed" default:"installed"`
+}
+
+// Selection represents the answer to the question of debconf.
+type Selection struct {
+	Question string `json:"question" yaml:"question" doc:"the name of the question"`
+	Type     string `json:"type" yaml:"type" doc:"the type of the question, e.g. string, boolean, select or password"`
+	Value    string `json:"value" yaml:"value" doc:"the answer to the question"`
+}
+
+// DebconfState returns the state to preseed the answers of debconf for the
+// named package.
+func DebconfState(name string, sels []Selection) (state.State, error) {
+	s := &packagemanager.Debconf{
+		Package: name,
+	}
+	for _, sel := range sels {
+		if sel.Question == "" {
+			return nil, fmt.Errorf(`parameter "question" is required for debconf`)
+		}
+		if sel.Type == "" {
+			return nil, fmt.Errorf(`parameter "type" is required for debconf`)
+		}
+		s.Selections = append(s.Selections, packagemanager.DebconfSelection{
+			Question: sel.Question,
+			Type:     sel.Type,
+			Value:    sel.Value,
+		})
+	}
+	return s, nil
 }
 
 func (r *Resource) States() ([]state.State, error) {
@@ -29,6 +60,15 @@ func (r *Resource) States() ([]state.State, error) {
 		logger.Debugf(`parameter "state" is not specified, assume as "%s"`, r.State)
 	}
 
+	// the answers of debconf should be set before installing the package.
+	if len(r.Debconf) > 0 && r.State == "installed" {
+		s, err := DebconfState(r.Name, r.Debconf)
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, s)
+	}
+
 	var s state.State
 	var err error
 	switch r.State {
@@ -36,15 +76,55 @@ func (r *Resource) States() ([]state.State, error) {
 		s, err = r.installedState()
 	case "removed":
 		s, err = r.removedState()
+	default:
+		err = fmt.Errorf(`unknown state "%s"`, r.State)
 	}
 	if err != nil {
 		return nil, err
 	}
 	states = append(states, s)
 
+	if r.Hold != nil {
+		states = append(states, &packagemanager.Held{
+			Name: r.Name,
+			Hold: *r.Hold,
+		})
+	}
+
 	return states, nil
 }
 
+// BatchKey returns the key to batch the package resources. Only the resources
+// whose state is "installed" can be installed at once.
+func (r *Resource) BatchKey() string {
+	if r.State == "" || r.State == "installed" {
+		return "package:installed"
+	}
+	return ""
+}
+
+// BatchStates merges the installed states of the packages into the state to
+// install all of them at once. The answers of debconf are set before the
+// installation, and the other states such as holds are applied after the
+// installation.
+func (r *Resource) BatchStates(states []state.State) ([]state.State, error) {
+	batch := &packagemanager.InstalledBatch{}
+	pre := []state.State{}
+	post := []state.State{}
+	for _, s := range states {
+		switch s := s.(type) {
+		case *packagemanager.Installed:
+			batch.Packages = append(batch.Packages, s)
+		case *packagemanager.Debconf:
+			pre = append(pre, s)
+		default:
+			post = append(post, s)
+		}
+	}
+	merged := append(pre, batch)
+	return append(merged, post...), nil
+}
+
 func (r *Resource) installedState() (state.State, error) {
 	if r.Name == "" {
 		return nil, fmt.Errorf(`parameter "name" is required`)
@@ -61,7 +141,7 @@ func (r *Resource) removedState() (state.State, error) {
 	if r.Name == "" {
 		return nil, fmt.Errorf(`parameter "name" is required`)
 	}
-	return &packagemanager.Installed{
+	return &packagemanager.Removed{
 		Name: r.Name,
 	}, nil
 }
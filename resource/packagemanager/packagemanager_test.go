@@ -0,0 +1,113 @@
+package packagemanager_test
+
+import (
+	"testing"
+
+	"github.com/harukasan/orchestra-pit/resource/packagemanager"
+	"github.com/harukasan/orchestra-pit/state"
+	pmstate "github.com/harukasan/orchestra-pit/state/packagemanager"
+)
+
+func TestRemovedState(t *testing.T) {
+	hold := true
+	r := &packagemanager.Resource{
+		Name:  "sl",
+		State: "removed",
+		Hold:  &hold,
+	}
+
+	states, err := r.States()
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if got := len(states); got != 2 {
+		t.Fatalf("got %d states, expected 2", got)
+	}
+	if _, ok := states[0].(*pmstate.Removed); !ok {
+		t.Errorf("the first state is not a Removed state")
+	}
+	if _, ok := states[1].(*pmstate.Held); !ok {
+		t.Errorf("the second state is not a Held state")
+	}
+}
+
+func TestHeldState(t *testing.T) {
+	hold := true
+	r := &packagemanager.Resource{
+		Name: "postgresql",
+		Hold: &hold,
+	}
+
+	states, err := r.States()
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if got := len(states); got != 2 {
+		t.Fatalf("got %d states, expected 2", got)
+	}
+	s, ok := states[1].(*pmstate.Held)
+	if !ok {
+		t.Fatalf("state is not a Held state")
+	}
+	if s.Name != r.Name || !s.Hold {
+		t.Errorf("got %+v, expected to hold %s", s, r.Name)
+	}
+}
+
+func TestBatchStatesWithHold(t *testing.T) {
+	hold := true
+	rs := []*packagemanager.Resource{
+		{Name: "a"},
+		{Name: "b", Version: "1.0", Hold: &hold},
+	}
+
+	all := []state.State{}
+	for _, r := range rs {
+		states, err := r.States()
+		if err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+		all = append(all, states...)
+	}
+
+	states, err := rs[0].BatchStates(all)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if got := len(states); got != 2 {
+		t.Fatalf("got %d states, expected 2", got)
+	}
+	if s, ok := states[0].(*pmstate.InstalledBatch); !ok || len(s.Packages) != 2 {
+		t.Errorf("got %+v, expected the batch of 2 packages", states[0])
+	}
+	if _, ok := states[1].(*pmstate.Held); !ok {
+		t.Errorf("the state following the batch is not a Held state")
+	}
+}
+
+func TestDebconfState(t *testing.T) {
+	r := &packagemanager.Resource{
+		Name: "tzdata",
+		Debconf: []packagemanager.Selection{
+			{Question: "tzdata/Areas", Type: "select", Value: "Asia"},
+		},
+	}
+
+	states, err := r.States()
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if got := len(states); got != 2 {
+		t.Fatalf("got %d states, expected 2", got)
+	}
+	s, ok := states[0].(*pmstate.Debconf)
+	if !ok {
+		t.Fatalf("the first state is not a Debconf state")
+	}
+	if s.Package != r.Name {
+		t.Errorf("got Package %v, expected %v", s.Package, r.Name)
+	}
+	if s.Selections[0].Value != "Asia" {
+		t.Errorf("got Value %v, expected Asia", s.Selections[0].Value)
+	}
+}
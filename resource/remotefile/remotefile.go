@@ -0,0 +1,87 @@
+/*
+Package remotefile implements the remote_file resource which manages the file
+downloaded over HTTP or HTTPS.
+*/
+package remotefile
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/harukasan/orchestra-pit/state"
+	"github.com/harukasan/orchestra-pit/state/file"
+)
+
+// Resource represents the attributes of remote_file resource.
+type Resource struct {
+	Desc     string `json:"desc" yaml:"desc" doc:"the description of the resource"`
+	Path     string `json:"path" yaml:"path" doc:"the path of the file"`
+	URL      string `json:"url" yaml:"url" doc:"the HTTP or HTTPS URL to download the file from"`
+	Checksum string `json:"checksum" yaml:"checksum" doc:"the checksum of the file, e.g. sha256:<hex>"`
+	Backup   string `json:"backup" yaml:"backup" doc:"the name or the path to back up the existing file before overwriting"`
+	Mode     string `json:"mode" yaml:"mode" doc:"the file mode in the manner of chmod, e.g. 0644 or u+rw"`
+	Owner    string `json:"owner" yaml:"owner" doc:"the name or the ID of the owner"`
+	Group    string `json:"group" yaml:"group" doc:"the name or the ID of the group"`
+}
+
+func (r *Resource) States() ([]state.State, error) {
+	if r.Path == "" {
+		return nil, fmt.Errorf(`parameter "path" is required`)
+	}
+	if r.URL == "" {
+		return nil, fmt.Errorf(`parameter "url" is required`)
+	}
+	if r.Checksum == "" {
+		return nil, fmt.Errorf(`parameter "checksum" is required`)
+	}
+	if r.Backup != "" && !strings.ContainsRune(r.Backup, '/') {
+		r.Backup = path.Join(path.Dir(r.Path), r.Backup)
+	}
+
+	states := []state.State{&file.Download{
+		Name:     r.Path,
+		URL:      r.URL,
+		Checksum: r.Checksum,
+		Backup:   r.Backup,
+	}}
+	if r.Mode != "" {
+		states = append(states, &file.Mode{
+			Name: r.Path,
+			Mode: r.Mode,
+		})
+	}
+	if r.Owner != "" || r.Group != "" {
+		states = append(states, &file.NamedOwner{
+			Name:  r.Path,
+			Owner: r.Owner,
+			Group: r.Group,
+		})
+	}
+	return states, nil
+}
+
+// Validate checks the URL, the checksum and the mode.
+func (r *Resource) Validate() error {
+	if r.URL != "" {
+		u, err := url.Parse(r.URL)
+		if err != nil {
+			return fmt.Errorf(`invalid url "%s": %s`, r.URL, err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf(`invalid url "%s": the scheme must be http or https`, r.URL)
+		}
+	}
+	if r.Checksum != "" {
+		if _, err := file.ParseChecksum(r.Checksum); err != nil {
+			return err
+		}
+	}
+	if r.Mode != "" {
+		if _, err := file.ParseMode(r.Mode, 0); err != nil {
+			return fmt.Errorf(`invalid mode "%s": %s`, r.Mode, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package remotefile_test
+
+import (
+	"testing"
+
+	"github.com/harukasan/orchestra-pit/resource/remotefile"
+	"github.com/harukasan/orchestra-pit/state/file"
+)
+
+const checksum = "sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+func TestStates(t *testing.T) {
+	r := &remotefile.Resource{
+		Path:     "/usr/local/bin/tool",
+		URL:      "https://example.com/tool",
+		Checksum: checksum,
+		Backup:   "tool.orig",
+		Mode:     "0755",
+		Owner:    "root",
+	}
+
+	states, err := r.States()
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if got := len(states); got != 3 {
+		t.Fatalf("got %d states, expected 3", got)
+	}
+
+	d, ok := states[0].(*file.Download)
+	if !ok {
+		t.Fatalf("state is not a Download state")
+	}
+	if d.Name != r.Path || d.URL != r.URL || d.Checksum != checksum {
+		t.Errorf("got %+v, expected the download of %v", d, r.URL)
+	}
+	// the backup name is resolved in the directory of the file.
+	if d.Backup != "/usr/local/bin/tool.orig" {
+		t.Errorf("got Backup %v, expected /usr/local/bin/tool.orig", d.Backup)
+	}
+
+	m, ok := states[1].(*file.Mode)
+	if !ok {
+		t.Fatalf("state is not a Mode state")
+	}
+	if m.Mode != "0755" {
+		t.Errorf("got Mode %v, expected 0755", m.Mode)
+	}
+
+	o, ok := states[2].(*file.NamedOwner)
+	if !ok {
+		t.Fatalf("state is not a NamedOwner state")
+	}
+	if o.Owner != "root" || o.Group != "" {
+		t.Errorf("got Owner %v and Group %v, expected root and no group", o.Owner, o.Group)
+	}
+}
+
+func TestStatesWithMinimumArguments(t *testing.T) {
+	r := &remotefile.Resource{Path: "/tool", URL: "https://example.com/tool", Checksum: checksum}
+
+	states, err := r.States()
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if got := len(states); got != 1 {
+		t.Fatalf("got %d states, expected just 1", got)
+	}
+	if d, ok := states[0].(*file.Download); !ok || d.Backup != "" {
+		t.Errorf("got %+v, expected the download without the backup", states[0])
+	}
+}
+
+func TestStatesWithoutRequiredParameters(t *testing.T) {
+	invalid := []*remotefile.Resource{
+		{URL: "https://example.com/tool", Checksum: checksum},
+		{Path: "/tool", Checksum: checksum},
+		{Path: "/tool", URL: "https://example.com/tool"},
+	}
+	for _, r := range invalid {
+		if _, err := r.States(); err == nil {
+			t.Errorf("got no error for %+v", r)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	invalid := []*remotefile.Resource{
+		{Path: "/tool", URL: "ftp://example.com/tool", Checksum: checksum},
+		{Path: "/tool", URL: "https://example.com/tool", Checksum: "md5:abc"},
+		{Path: "/tool", URL: "https://example.com/tool", Checksum: checksum, Mode: "0999"},
+	}
+	for _, r := range invalid {
+		if err := r.Validate(); err == nil {
+			t.Errorf("got no error for %+v", r)
+		}
+	}
+}
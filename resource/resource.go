@@ -1,9 +1,23 @@
 package resource
 
 import (
+	"fmt"
+	"reflect"
+	"strings"
+
 	"github.com/harukasan/orchestra-pit/opit/logger"
+	"github.com/harukasan/orchestra-pit/resource/aptpreference"
+	"github.com/harukasan/orchestra-pit/resource/aptrepository"
+	"github.com/harukasan/orchestra-pit/resource/archive"
+	"github.com/harukasan/orchestra-pit/resource/configvalue"
+	"github.com/harukasan/orchestra-pit/resource/cron"
+	"github.com/harukasan/orchestra-pit/resource/debconf"
 	"github.com/harukasan/orchestra-pit/resource/file"
+	"github.com/harukasan/orchestra-pit/resource/git"
 	"github.com/harukasan/orchestra-pit/resource/packagemanager"
+	"github.com/harukasan/orchestra-pit/resource/remotefile"
+	"github.com/harukasan/orchestra-pit/resource/service"
+	"github.com/harukasan/orchestra-pit/resource/systemdunit"
 	"github.com/harukasan/orchestra-pit/state"
 )
 
@@ -11,12 +25,297 @@ type Resource interface {
 	States() ([]state.State, error)
 }
 
+// Batcher is interface of the resource whose states can be applied together
+// with the adjacent resources of the same kind.
+//
+// BatchKey returns the key which identifies the kind of the batch. Resources
+// which return the same non-empty key can be applied at once. If the resource
+// can not be batched, BatchKey returns an empty string.
+//
+// BatchStates merges the states of the resources which have the same batch key
+// into the states to apply all of them at once.
+type Batcher interface {
+	Resource
+	BatchKey() string
+	BatchStates(states []state.State) ([]state.State, error)
+}
+
+// FileReferrer is interface of the resource which refers the files next to the
+// recipe, such as the source of the file. SetRecipeDir sets the directory of
+// the recipe which the relative paths are resolved against. SetFilesDir sets
+// the directory which the default files are searched in.
+type FileReferrer interface {
+	Resource
+	SetRecipeDir(dir string)
+	SetFilesDir(dir string)
+}
+
+// Validator is interface of the resource which checks its attributes further
+// than building its states, such as the formats of the attributes. Validate
+// must not touch the host.
+type Validator interface {
+	Resource
+	Validate() error
+}
+
+// Notifiable is interface of the resource which takes the actions on the
+// notifications from the other resources, such as restarting the service when
+// its configuration file is changed. ActionStates returns the states which
+// take the named action.
+type Notifiable interface {
+	Resource
+	ActionStates(action string) ([]state.State, error)
+}
+
+// Deferrer is interface of the resource which has the states to apply at the
+// end of the run when the resource is applied, such as reloading the unit files
+// of systemd after writing the unit. The same states deferred by several
+// resources are applied once, before the notifications.
+type Deferrer interface {
+	Resource
+	DeferredStates() []state.State
+}
+
+// Templater is interface of the resource which renders the template files with
+// the variables of the recipe. SetTemplateFunc sets the function which renders
+// the text of the template.
+type Templater interface {
+	Resource
+	SetTemplateFunc(render func(text string) (string, error))
+}
+
+// Notification represents the action which is taken on the resource when the
+// notifying resource is applied. The resource is referred by its ID, see ID.
+type Notification struct {
+	Resource string `json:"resource" doc:"the resource to notify in the form of type:name, e.g. service:nginx"`
+	Action   string `json:"action" doc:"the action to take on the resource, e.g. restart"`
+}
+
+func (n Notification) String() string {
+	return n.Action + " " + n.Resource
+}
+
+// Type describes the type of the resource. New returns the new resource of the
+// type, whose exported fields are the attributes of the resource. Example is
+// the example of the resource in JSON, used in the documents.
+type Type struct {
+	Name        string
+	Description string
+	New         func() Resource
+	Example     string
+}
+
+var types = []*Type{
+	{
+		Name:        "file",
+		Description: "manages the file, the directory or the link",
+		New:         func() Resource { return &file.Resource{} },
+		Example: `{
+  "type": "file",
+  "path": "/etc/motd",
+  "src": "files/motd",
+  "mode": "0644"
+}`,
+	},
+	{
+		Name:        "package",
+		Description: "installs or removes the package by the package manager of the platform",
+		New:         func() Resource { return &packagemanager.Resource{} },
+		Example: `{
+  "type": "package",
+  "name": "nginx",
+  "version": ">= 1.18",
+  "state": "installed"
+}`,
+	},
+	{
+		Name:        "apt_repository",
+		Description: "manages the source list of APT",
+		New:         func() Resource { return &aptrepository.Resource{} },
+		Example: `{
+  "type": "apt_repository",
+  "name": "nginx",
+  "uri": "http://nginx.org/packages/debian",
+  "suite": "bookworm",
+  "components": ["nginx"],
+  "key": "files/nginx.gpg"
+}`,
+	},
+	{
+		Name:        "apt_preference",
+		Description: "manages the pin preference of APT",
+		New:         func() Resource { return &aptpreference.Resource{} },
+		Example: `{
+  "type": "apt_preference",
+  "name": "nginx",
+  "pin": "origin nginx.org",
+  "priority": 900
+}`,
+	},
+	{
+		Name:        "debconf",
+		Description: "preseeds the answers of debconf",
+		New:         func() Resource { return &debconf.Resource{} },
+		Example: `{
+  "type": "debconf",
+  "package": "tzdata",
+  "selections": [
+    {"question": "tzdata/Areas", "type": "select", "value": "Asia"}
+  ]
+}`,
+	},
+	{
+		Name:        "service",
+		Description: "manages the service by systemd",
+		New:         func() Resource { return &service.Resource{} },
+		Example: `{
+  "type": "service",
+  "name": "nginx",
+  "enabled": true,
+  "state": "running"
+}`,
+	},
+	{
+		Name:        "systemd_unit",
+		Description: "manages the unit file or the drop-in of systemd, and reloads the units when it is changed",
+		New:         func() Resource { return &systemdunit.Resource{} },
+		Example: `{
+  "type": "systemd_unit",
+  "name": "app.service",
+  "sections": {
+    "Unit": {"Description": "the application"},
+    "Service": {"ExecStart": "/usr/local/bin/app", "Restart": "always"},
+    "Install": {"WantedBy": "multi-user.target"}
+  }
+}`,
+	},
+	{
+		Name:        "cron",
+		Description: "manages the cron job in /etc/cron.d or the crontab of the user",
+		New:         func() Resource { return &cron.Resource{} },
+		Example: `{
+  "type": "cron",
+  "name": "backup",
+  "minute": "0",
+  "hour": "3",
+  "command": "/usr/local/bin/backup",
+  "env": {"MAILTO": "admin@example.com"}
+}`,
+	},
+	{
+		Name:        "config_value",
+		Description: "manages the value of the key in the INI, JSON or YAML file, keeping the rest of the file",
+		New:         func() Resource { return &configvalue.Resource{} },
+		Example: `{
+  "type": "config_value",
+  "path": "/etc/php/8.2/fpm/php.ini",
+  "format": "ini",
+  "section": "Date",
+  "key": "date.timezone",
+  "value": "Asia/Tokyo"
+}`,
+	},
+	{
+		Name:        "remote_file",
+		Description: "manages the file downloaded over HTTP or HTTPS, verified by its checksum",
+		New:         func() Resource { return &remotefile.Resource{} },
+		Example: `{
+  "type": "remote_file",
+  "path": "/usr/local/bin/tool",
+  "url": "https://example.com/releases/tool-1.0.0",
+  "checksum": "sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+  "mode": "0755"
+}`,
+	},
+	{
+		Name:        "archive",
+		Description: "extracts the tar, tar.gz, tar.xz or zip archive into the directory",
+		New:         func() Resource { return &archive.Resource{} },
+		Example: `{
+  "type": "archive",
+  "src": "/var/cache/go1.22.0.linux-amd64.tar.gz",
+  "dest": "/usr/local/go",
+  "strip_components": 1,
+  "owner": "root"
+}`,
+	},
+	{
+		Name:        "git",
+		Description: "checks out the branch, the tag or the commit of the git repository",
+		New:         func() Resource { return &git.Resource{} },
+		Example: `{
+  "type": "git",
+  "repository": "https://example.com/tools/deploy.git",
+  "revision": "v1.2.0",
+  "destination": "/srv/deploy",
+  "depth": 1,
+  "user": "deploy"
+}`,
+	},
+}
+
+// Types returns the registered types of the resources.
+func Types() []*Type {
+	return types
+}
+
+// New returns the new resource of the named type. It returns nil if the type
+// is not registered.
 func New(t string) Resource {
-	switch t {
-	case "file":
-		return &file.Resource{}
-	case "package":
-		return &packagemanager.Resource{}
+	for _, typ := range types {
+		if typ.Name == t {
+			return typ.New()
+		}
+	}
+	return nil
+}
+
+// TypeOf returns the type of the resource. It returns nil if the type is not
+// registered.
+func TypeOf(r Resource) *Type {
+	for _, t := range types {
+		if reflect.TypeOf(t.New()) == reflect.TypeOf(r) {
+			return t
+		}
+	}
+	return nil
+}
+
+// idAttributes are the attributes which identify the resource in its type.
+var idAttributes = []string{"name", "path", "package", "destination", "dest"}
+
+// ID returns the identifier of the resource in the form of type:name, where the
+// name is the first non-empty attribute of name, path, package, destination
+// and dest.
+func ID(r Resource) string {
+	t := TypeOf(r)
+	if t == nil {
+		return ""
+	}
+	v := reflect.Indirect(reflect.ValueOf(r))
+	for _, a := range idAttributes {
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if strings.Split(f.Tag.Get("json"), ",")[0] != a || f.Type.Kind() != reflect.String {
+				continue
+			}
+			if s := v.Field(i).String(); s != "" {
+				return t.Name + ":" + s
+			}
+		}
+	}
+	return t.Name
+}
+
+// Validate checks the attributes of the resource without touching the host. It
+// builds the states of the resource to check the required attributes, and calls
+// Validate if the resource implements Validator.
+func Validate(r Resource) error {
+	if _, err := r.States(); err != nil {
+		return err
+	}
+	if v, ok := r.(Validator); ok {
+		return v.Validate()
 	}
 	return nil
 }
@@ -35,6 +334,87 @@ func Apply(r Resource) error {
 	return nil
 }
 
+// ApplyAction takes the action of the notification on the resource.
+func ApplyAction(r Resource, action string) error {
+	n, ok := r.(Notifiable)
+	if !ok {
+		return fmt.Errorf("%s can not take actions", ID(r))
+	}
+	states, err := n.ActionStates(action)
+	if err != nil {
+		return err
+	}
+	for _, state := range states {
+		logger.Debugf("applying state: %s", state)
+		if err := state.Apply(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Batches splits the resources into the groups of the adjacent resources which
+// have the same batch key. The resource which can not be batched forms a group
+// by itself. The order of the resources is preserved.
+func Batches(rs []Resource) [][]Resource {
+	groups := [][]Resource{}
+	key := ""
+	for _, r := range rs {
+		k := batchKey(r)
+		if k != "" && k == key {
+			groups[len(groups)-1] = append(groups[len(groups)-1], r)
+			continue
+		}
+		groups = append(groups, []Resource{r})
+		key = k
+	}
+	return groups
+}
+
+func batchKey(r Resource) string {
+	if b, ok := r.(Batcher); ok {
+		return b.BatchKey()
+	}
+	return ""
+}
+
+// ApplyBatch applies all of the given resources at once. The resources should
+// be a group returned by Batches. If the group has just one resource, it is
+// applied in the same manner as Apply.
+func ApplyBatch(rs []Resource) error {
+	if len(rs) == 0 {
+		return nil
+	}
+	b, ok := rs[0].(Batcher)
+	if len(rs) == 1 || !ok {
+		for _, r := range rs {
+			if err := Apply(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	all := []state.State{}
+	for _, r := range rs {
+		states, err := r.States()
+		if err != nil {
+			return err
+		}
+		all = append(all, states...)
+	}
+	states, err := b.BatchStates(all)
+	if err != nil {
+		return err
+	}
+	for _, state := range states {
+		logger.Debugf("applying state: %s", state)
+		if err := state.Apply(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func Test(r Resource) error {
 	states, err := r.States()
 	if err != nil {
@@ -0,0 +1,86 @@
+package resource_test
+
+import (
+	"testing"
+
+	"github.com/harukasan/orchestra-pit/resource"
+	"github.com/harukasan/orchestra-pit/resource/file"
+	"github.com/harukasan/orchestra-pit/resource/git"
+	"github.com/harukasan/orchestra-pit/resource/packagemanager"
+	"github.com/harukasan/orchestra-pit/resource/service"
+	"github.com/harukasan/orchestra-pit/state"
+	pmstate "github.com/harukasan/orchestra-pit/state/packagemanager"
+)
+
+func TestBatches(t *testing.T) {
+	rs := []resource.Resource{
+		&packagemanager.Resource{Name: "a"},
+		&packagemanager.Resource{Name: "b", State: "installed"},
+		&file.Resource{Path: "/tmp/test"},
+		&packagemanager.Resource{Name: "c"},
+		&packagemanager.Resource{Name: "d", State: "removed"},
+		&packagemanager.Resource{Name: "e"},
+	}
+
+	groups := resource.Batches(rs)
+	expected := []int{2, 1, 1, 1, 1}
+	if len(groups) != len(expected) {
+		t.Fatalf("got %d groups, expected %d", len(groups), len(expected))
+	}
+	for i, n := range expected {
+		if got := len(groups[i]); got != n {
+			t.Errorf("group %d: got %d resources, expected %d", i, got, n)
+		}
+	}
+}
+
+func TestBatchStates(t *testing.T) {
+	rs := []resource.Resource{
+		&packagemanager.Resource{Name: "a"},
+		&packagemanager.Resource{Name: "b", Version: "1.0"},
+	}
+
+	all := []state.State{}
+	for _, r := range rs {
+		states, err := r.States()
+		if err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+		all = append(all, states...)
+	}
+
+	states, err := rs[0].(resource.Batcher).BatchStates(all)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if got := len(states); got != 1 {
+		t.Fatalf("got %d states, expected just 1", got)
+	}
+	s, ok := states[0].(*pmstate.InstalledBatch)
+	if !ok {
+		t.Fatalf("state is not an InstalledBatch state")
+	}
+	if got := len(s.Packages); got != 2 {
+		t.Errorf("got %d packages, expected 2", got)
+	}
+	if s.Packages[1].Version != "1.0" {
+		t.Errorf("got Version %v, expected 1.0", s.Packages[1].Version)
+	}
+}
+
+func TestID(t *testing.T) {
+	cases := []struct {
+		r  resource.Resource
+		id string
+	}{
+		{&file.Resource{Path: "/etc/motd"}, "file:/etc/motd"},
+		{&packagemanager.Resource{Name: "nginx"}, "package:nginx"},
+		{&service.Resource{Name: "nginx"}, "service:nginx"},
+		{&git.Resource{Repository: "https://example.com/tool.git", Destination: "/srv/tool"}, "git:/srv/tool"},
+	}
+	for _, c := range cases {
+		if id := resource.ID(c.r); id != c.id {
+			t.Errorf("got %q, expected %q", id, c.id)
+		}
+	}
+}
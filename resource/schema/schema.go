@@ -0,0 +1,221 @@
+/*
+Package schema generates the JSON Schema of the recipe file from the registered
+resource types.
+
+The attributes of the resources are derived from the exported fields of the
+resources and their tags:
+
+	json     ... the name of the attribute
+	doc      ... the description of the attribute
+	enum     ... the comma separated values which the attribute can take
+	default  ... the value which is assumed if the attribute is not specified
+*/
+package schema
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/harukasan/orchestra-pit/recipe"
+	"github.com/harukasan/orchestra-pit/resource"
+)
+
+// Draft is the version of JSON Schema.
+const Draft = "http://json-schema.org/draft-07/schema#"
+
+// Schema represents the JSON Schema.
+type Schema map[string]interface{}
+
+// Recipe returns the schema of the recipe file. The schemas of the resource
+// types are in the definitions, and each resource is checked by the schema of
+// its type.
+func Recipe() Schema {
+	defs := Schema{}
+	names := []interface{}{}
+	conds := []interface{}{}
+	for _, t := range resource.Types() {
+		defs[t.Name] = Resource(t)
+		names = append(names, t.Name)
+		conds = append(conds, Schema{
+			"if": Schema{
+				"properties": Schema{"type": Schema{"const": t.Name}},
+				"required":   []string{"type"},
+			},
+			"then": Schema{"$ref": "#/definitions/" + t.Name},
+		})
+	}
+
+	return Schema{
+		"$schema": Draft,
+		"title":   "opit recipe",
+		"type":    "object",
+		"properties": Schema{
+			"config": Schema{
+				"type":                 "object",
+				"additionalProperties": Schema{"type": "string"},
+			},
+			"include": Schema{
+				"description": "the recipe files to include, relative to the recipe",
+				"type":        "array",
+				"items":       Schema{"type": "string"},
+			},
+			"files_dir": Schema{
+				"description": "the directory of the default sources of the files, relative to the recipe",
+				"type":        "string",
+			},
+			"roles_path": Schema{
+				"description": "the directories to search the roles in, relative to the recipe",
+				"type":        "array",
+				"items":       Schema{"type": "string"},
+			},
+			"roles": Schema{
+				"description": "the roles to use",
+				"type":        "array",
+				"items":       structSchema(reflect.TypeOf(recipe.Role{})),
+			},
+			"resources": Schema{
+				"description": "the resources which describe the desired states",
+				"type":        "array",
+				"items": Schema{
+					"type":     "object",
+					"required": []string{"type"},
+					"properties": Schema{
+						"type": Schema{
+							"description": "the type of the resource",
+							"enum":        names,
+						},
+					},
+					"allOf": conds,
+				},
+			},
+		},
+		"definitions": defs,
+	}
+}
+
+// Resource returns the schema of the attributes of the resource type.
+func Resource(t *resource.Type) Schema {
+	s := structSchema(reflect.TypeOf(t.New()).Elem())
+	s["description"] = t.Description
+	props := s["properties"].(Schema)
+	props["type"] = Schema{"const": t.Name}
+	for _, k := range []string{"with_items", "for_each"} {
+		props[k] = Schema{
+			"description": "the items to expand the resource for each, or the variable which has the items",
+			"type":        []string{"array", "string"},
+		}
+	}
+	n := structSchema(reflect.TypeOf(resource.Notification{}))
+	n["required"] = []string{"resource", "action"}
+	props["notify"] = Schema{
+		"description": "the resources to notify when the resource is applied",
+		"oneOf":       []interface{}{n, Schema{"type": "array", "items": n}},
+	}
+	return s
+}
+
+// Attribute describes the attribute of the resource.
+type Attribute struct {
+	Name  string
+	Field reflect.StructField
+}
+
+// Attributes returns the attributes of the struct type in the order of the
+// fields.
+func Attributes(t reflect.Type) []Attribute {
+	attrs := []Attribute{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		attrs = append(attrs, Attribute{Name: name, Field: f})
+	}
+	return attrs
+}
+
+// Enum returns the values which the attribute can take. It returns nil if the
+// attribute has no enum tag.
+func (a Attribute) Enum() []string {
+	e := a.Field.Tag.Get("enum")
+	if e == "" {
+		return nil
+	}
+	return strings.Split(e, ",")
+}
+
+// Default returns the description of the default value of the attribute.
+func (a Attribute) Default() string {
+	return a.Field.Tag.Get("default")
+}
+
+// Doc returns the description of the attribute.
+func (a Attribute) Doc() string {
+	return a.Field.Tag.Get("doc")
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+func structSchema(t reflect.Type) Schema {
+	props := Schema{}
+	for _, a := range Attributes(t) {
+		props[a.Name] = attributeSchema(a)
+	}
+	return Schema{
+		"type":                 "object",
+		"properties":           props,
+		"additionalProperties": false,
+	}
+}
+
+func attributeSchema(a Attribute) Schema {
+	s := typeSchema(a.Field.Type)
+	if doc := a.Doc(); doc != "" {
+		s["description"] = doc
+	}
+	if enum := a.Enum(); enum != nil {
+		s["enum"] = enum
+		// the default is a literal only if it is one of the enum.
+		if d := a.Default(); d != "" && contains(enum, d) {
+			s["default"] = d
+		}
+	}
+	return s
+}
+
+func typeSchema(t reflect.Type) Schema {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return typeSchema(t.Elem())
+	case reflect.String:
+		return Schema{"type": "string"}
+	case reflect.Bool:
+		return Schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Schema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return Schema{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return Schema{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return Schema{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Struct:
+		return structSchema(t)
+	}
+	return Schema{}
+}
@@ -0,0 +1,55 @@
+package schema_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/harukasan/orchestra-pit/resource"
+	"github.com/harukasan/orchestra-pit/resource/schema"
+)
+
+func TestRecipe(t *testing.T) {
+	s := schema.Recipe()
+	if _, err := json.Marshal(s); err != nil {
+		t.Fatalf("can not marshal the schema: %v", err)
+	}
+
+	defs := s["definitions"].(schema.Schema)
+	for _, typ := range resource.Types() {
+		if defs[typ.Name] == nil {
+			t.Errorf("the schema of %q is not defined", typ.Name)
+		}
+	}
+}
+
+func TestResource(t *testing.T) {
+	var typ *resource.Type
+	for _, t := range resource.Types() {
+		if t.Name == "package" {
+			typ = t
+		}
+	}
+	s := schema.Resource(typ)
+	if s["additionalProperties"] != false {
+		t.Errorf("the unknown attributes should be rejected")
+	}
+	props := s["properties"].(schema.Schema)
+
+	state := props["state"].(schema.Schema)
+	if !reflect.DeepEqual(state["enum"], []string{"installed", "removed"}) {
+		t.Errorf("got enum %v of state", state["enum"])
+	}
+	if state["description"] == "" {
+		t.Errorf("state has no description")
+	}
+
+	debconf := props["debconf"].(schema.Schema)
+	item := debconf["items"].(schema.Schema)
+	if item["type"] != "object" || item["properties"].(schema.Schema)["question"] == nil {
+		t.Errorf("got unexpected schema of debconf: %v", debconf)
+	}
+	if hold := props["hold"].(schema.Schema); hold["type"] != "boolean" {
+		t.Errorf("got type %v of hold", hold["type"])
+	}
+}
@@ -0,0 +1,58 @@
+/*
+Package service implements the service resource which manages the services
+by systemd.
+*/
+package service
+
+import (
+	"fmt"
+
+	"github.com/harukasan/orchestra-pit/opit/logger"
+	"github.com/harukasan/orchestra-pit/state"
+	"github.com/harukasan/orchestra-pit/state/service"
+)
+
+// Resource represents the attributes of service resource.
+type Resource struct {
+	Desc    string `json:"desc" yaml:"desc" doc:"the description of the resource"`
+	Name    string `json:"name" yaml:"name" doc:"the name of the service, e.g. nginx or nginx.service"`
+	Enabled *bool  `json:"enabled" yaml:"enabled" doc:"whether the service starts on boot; it is left as is if not specified"`
+	State   string `json:"state" yaml:"state" doc:"the state of the service" enum:"running,stopped" default:"running"`
+}
+
+func (r *Resource) States() ([]state.State, error) {
+	if r.State == "" {
+		r.State = "running"
+		logger.Debugf(`parameter "state" is not specified, assume as "%s"`, r.State)
+	}
+	if r.Name == "" {
+		return nil, fmt.Errorf(`parameter "name" is required`)
+	}
+
+	states := []state.State{}
+	if r.Enabled != nil {
+		states = append(states, &service.Enabled{Name: r.Name, Enabled: *r.Enabled})
+	}
+	switch r.State {
+	case "running":
+		return append(states, &service.Running{Name: r.Name}), nil
+	case "stopped":
+		return append(states, &service.Stopped{Name: r.Name}), nil
+	}
+	return nil, fmt.Errorf(`unknown state "%s"`, r.State)
+}
+
+// ActionStates returns the states to take the action on the notification. The
+// action is either "restart" or "reload".
+func (r *Resource) ActionStates(action string) ([]state.State, error) {
+	if r.Name == "" {
+		return nil, fmt.Errorf(`parameter "name" is required`)
+	}
+	switch action {
+	case "restart":
+		return []state.State{&service.Restarted{Name: r.Name}}, nil
+	case "reload":
+		return []state.State{&service.Reloaded{Name: r.Name}}, nil
+	}
+	return nil, fmt.Errorf(`unknown action "%s"`, action)
+}
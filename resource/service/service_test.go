@@ -0,0 +1,55 @@
+package service_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/harukasan/orchestra-pit/resource/service"
+	"github.com/harukasan/orchestra-pit/state"
+	svcstate "github.com/harukasan/orchestra-pit/state/service"
+)
+
+func TestStates(t *testing.T) {
+	enabled := true
+	r := &service.Resource{Name: "nginx", Enabled: &enabled}
+	states, err := r.States()
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	expected := []state.State{
+		&svcstate.Enabled{Name: "nginx", Enabled: true},
+		&svcstate.Running{Name: "nginx"},
+	}
+	if !reflect.DeepEqual(states, expected) {
+		t.Errorf("got states %v, expected %v", states, expected)
+	}
+
+	states, err = (&service.Resource{Name: "nginx", State: "stopped"}).States()
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if !reflect.DeepEqual(states, []state.State{&svcstate.Stopped{Name: "nginx"}}) {
+		t.Errorf("got states %v, expected stopped", states)
+	}
+
+	if _, err := (&service.Resource{Name: "nginx", State: "started"}).States(); err == nil {
+		t.Errorf("got no error for the unknown state")
+	}
+	if _, err := (&service.Resource{}).States(); err == nil {
+		t.Errorf("got no error without the name")
+	}
+}
+
+func TestActionStates(t *testing.T) {
+	r := &service.Resource{Name: "nginx"}
+	states, err := r.ActionStates("reload")
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if !reflect.DeepEqual(states, []state.State{&svcstate.Reloaded{Name: "nginx"}}) {
+		t.Errorf("got states %v, expected reloaded", states)
+	}
+	if _, err := r.ActionStates("kill"); err == nil {
+		t.Errorf("got no error for the unknown action")
+	}
+}
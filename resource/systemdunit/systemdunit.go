@@ -0,0 +1,182 @@
+/*
+Package systemdunit implements the systemd_unit resource which manages the unit
+files and the drop-ins of systemd.
+*/
+package systemdunit
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/harukasan/orchestra-pit/opit/logger"
+	"github.com/harukasan/orchestra-pit/state"
+	"github.com/harukasan/orchestra-pit/state/file"
+	"github.com/harukasan/orchestra-pit/state/service"
+)
+
+// Resource represents the attributes of systemd_unit resource.
+type Resource struct {
+	Desc     string                            `json:"desc" yaml:"desc" doc:"the description of the resource"`
+	Name     string                            `json:"name" yaml:"name" doc:"the name of the unit, e.g. app.service"`
+	DropIn   string                            `json:"dropin" yaml:"dropin" doc:"the name of the drop-in, which is written into <name>.d/<dropin>.conf instead of the unit file"`
+	Sections map[string]map[string]interface{} `json:"sections" yaml:"sections" doc:"the keys of the sections of the unit; the key which has a list of the values is repeated"`
+	Template string                            `json:"template" yaml:"template" doc:"the template file of the unit relative to the recipe, rendered with the variables of the recipe"`
+	State    string                            `json:"state" yaml:"state" doc:"the state of the unit file" enum:"present,absent" default:"present"`
+
+	recipeDir string
+	render    func(string) (string, error)
+}
+
+// SetRecipeDir sets the directory which the relative template is resolved
+// against.
+func (r *Resource) SetRecipeDir(dir string) {
+	r.recipeDir = dir
+}
+
+// SetFilesDir does nothing, because the unit has no default template.
+func (r *Resource) SetFilesDir(dir string) {}
+
+// SetTemplateFunc sets the function which renders the template of the unit.
+func (r *Resource) SetTemplateFunc(render func(text string) (string, error)) {
+	r.render = render
+}
+
+func (r *Resource) States() ([]state.State, error) {
+	if r.State == "" {
+		r.State = "present"
+		logger.Debugf(`parameter "state" is not specified, assume as "%s"`, r.State)
+	}
+	if r.Name == "" {
+		return nil, fmt.Errorf(`parameter "name" is required`)
+	}
+
+	switch r.State {
+	case "present":
+		content, err := r.content()
+		if err != nil {
+			return nil, err
+		}
+		states := []state.State{}
+		if r.DropIn == "" {
+			states = append(states, &service.VerifiedUnit{Name: r.Name, Content: content})
+		} else {
+			states = append(states, &file.Directory{Name: path.Dir(r.Path())})
+		}
+		return append(states, &file.Content{Name: r.Path(), Content: content}), nil
+	case "absent":
+		return []state.State{&file.Absence{Name: r.Path()}}, nil
+	}
+	return nil, fmt.Errorf(`unknown state "%s"`, r.State)
+}
+
+// DeferredStates returns the state to reload the unit files once after the
+// units are changed.
+func (r *Resource) DeferredStates() []state.State {
+	return []state.State{&service.DaemonReloaded{}}
+}
+
+// Path returns the path of the unit file, or the drop-in.
+func (r *Resource) Path() string {
+	if r.DropIn == "" {
+		return path.Join(service.UnitDir, r.Name)
+	}
+	name := r.DropIn
+	if !strings.HasSuffix(name, ".conf") {
+		name += ".conf"
+	}
+	return path.Join(service.UnitDir, r.Name+".d", name)
+}
+
+// content returns the content of the unit, which is rendered from the
+// sections or the template.
+func (r *Resource) content() ([]byte, error) {
+	switch {
+	case r.Sections != nil && r.Template != "":
+		return nil, fmt.Errorf(`parameter "sections" and "template" can not be used together`)
+	case r.Template != "":
+		if !path.IsAbs(r.Template) && r.recipeDir != "" {
+			r.Template = path.Join(r.recipeDir, r.Template)
+		}
+		data, err := ioutil.ReadFile(r.Template)
+		if err != nil {
+			return nil, err
+		}
+		if r.render == nil {
+			return data, nil
+		}
+		s, err := r.render(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("can not render the template %s: %s", r.Template, err)
+		}
+		return []byte(s), nil
+	case r.Sections != nil:
+		return formatSections(r.Sections), nil
+	}
+	return nil, fmt.Errorf(`parameter "sections" or "template" is required`)
+}
+
+// sectionOrder returns the order of the section. The Unit section comes first,
+// and the Install section comes last.
+func sectionOrder(name string) int {
+	switch name {
+	case "Unit":
+		return 0
+	case "Install":
+		return 2
+	}
+	return 1
+}
+
+// formatSections formats the sections in the unit file format. The keys are
+// sorted in each section.
+func formatSections(sections map[string]map[string]interface{}) []byte {
+	names := []string{}
+	for name := range sections {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		oi, oj := sectionOrder(names[i]), sectionOrder(names[j])
+		if oi != oj {
+			return oi < oj
+		}
+		return names[i] < names[j]
+	})
+
+	buf := &bytes.Buffer{}
+	for i, name := range names {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		fmt.Fprintf(buf, "[%s]\n", name)
+		keys := []string{}
+		for k := range sections[name] {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			v := sections[name][k]
+			if list, ok := v.([]interface{}); ok {
+				for _, l := range list {
+					fmt.Fprintf(buf, "%s=%s\n", k, formatValue(l))
+				}
+				continue
+			}
+			fmt.Fprintf(buf, "%s=%s\n", k, formatValue(v))
+		}
+	}
+	return buf.Bytes()
+}
+
+// formatValue formats the value of the key. The numbers decoded from the
+// recipe are float64, so they are formatted without the exponent.
+func formatValue(v interface{}) string {
+	if f, ok := v.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,145 @@
+package systemdunit_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/harukasan/orchestra-pit/resource"
+	"github.com/harukasan/orchestra-pit/resource/systemdunit"
+	"github.com/harukasan/orchestra-pit/state"
+	"github.com/harukasan/orchestra-pit/state/file"
+)
+
+func content(t *testing.T, r *systemdunit.Resource) string {
+	states, err := r.States()
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	for _, s := range states {
+		if c, ok := s.(*file.Content); ok {
+			if c.Name != r.Path() {
+				t.Errorf("got path %q, expected %q", c.Name, r.Path())
+			}
+			return string(c.Content)
+		}
+	}
+	t.Fatalf("got no content in %v", states)
+	return ""
+}
+
+func TestSections(t *testing.T) {
+	r := &systemdunit.Resource{
+		Name: "app.service",
+		Sections: map[string]map[string]interface{}{
+			"Install": {"WantedBy": "multi-user.target"},
+			"Service": {"ExecStart": "/usr/local/bin/app", "Environment": []interface{}{"A=1", "B=2"}, "RestartSec": 5.0, "TimeoutSec": 3600000.0},
+			"Unit":    {"Description": "app"},
+		},
+	}
+	expected := `[Unit]
+Description=app
+
+[Service]
+Environment=A=1
+Environment=B=2
+ExecStart=/usr/local/bin/app
+RestartSec=5
+TimeoutSec=3600000
+
+[Install]
+WantedBy=multi-user.target
+`
+	if got := content(t, r); got != expected {
+		t.Errorf("got %q, expected %q", got, expected)
+	}
+	if r.Path() != "/etc/systemd/system/app.service" {
+		t.Errorf("got path %q", r.Path())
+	}
+}
+
+func TestSectionsFromJSON(t *testing.T) {
+	r := &systemdunit.Resource{}
+	data := `{"name": "app.service", "sections": {"Service": {"LimitNOFILE": 1048576, "Nice": -5}}}`
+	if err := json.Unmarshal([]byte(data), r); err != nil {
+		t.Fatal(err)
+	}
+	if got := content(t, r); got != "[Service]\nLimitNOFILE=1048576\nNice=-5\n" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestDropIn(t *testing.T) {
+	r := &systemdunit.Resource{
+		Name:     "nginx.service",
+		DropIn:   "override",
+		Sections: map[string]map[string]interface{}{"Service": {"ExecStart": []interface{}{"", "/usr/sbin/nginx -g 'daemon off;'"}}},
+	}
+	if got := content(t, r); got != "[Service]\nExecStart=\nExecStart=/usr/sbin/nginx -g 'daemon off;'\n" {
+		t.Errorf("got %q", got)
+	}
+	if r.Path() != "/etc/systemd/system/nginx.service.d/override.conf" {
+		t.Errorf("got path %q", r.Path())
+	}
+}
+
+func TestApplyDropIn(t *testing.T) {
+	root, err := ioutil.TempDir("", "systemdunit_test_root_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	orig := state.Root
+	state.Root = root
+	defer func() { state.Root = orig }()
+	if err := os.MkdirAll(filepath.Join(root, "etc/systemd/system"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// the second apply rewrites the drop-in in the existing directory.
+	for _, limit := range []string{"1024", "4096"} {
+		r := &systemdunit.Resource{
+			Name:     "nginx.service",
+			DropIn:   "limits",
+			Sections: map[string]map[string]interface{}{"Service": {"LimitNOFILE": limit}},
+		}
+		if err := resource.Apply(r); err != nil {
+			t.Fatalf("got error on apply: %v", err)
+		}
+		data, err := ioutil.ReadFile(filepath.Join(root, r.Path()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := "[Service]\nLimitNOFILE=" + limit + "\n"; string(data) != expected {
+			t.Errorf("got %q, expected %q", data, expected)
+		}
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "systemdunit_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tmpl := filepath.Join(dir, "app.service")
+	if err := ioutil.WriteFile(tmpl, []byte("[Service]\nExecStart={{ .bin }}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &systemdunit.Resource{Name: "app.service", Template: tmpl}
+	r.SetTemplateFunc(func(text string) (string, error) {
+		return strings.Replace(text, "{{ .bin }}", "/usr/local/bin/app", -1), nil
+	})
+	if got := content(t, r); got != "[Service]\nExecStart=/usr/local/bin/app\n" {
+		t.Errorf("got %q", got)
+	}
+
+	r.Sections = map[string]map[string]interface{}{}
+	if _, err := r.States(); err == nil {
+		t.Errorf("got no error with both of sections and template")
+	}
+}
@@ -0,0 +1,498 @@
+/*
+Package archive implements the states of the extracted archives.
+
+The following formats are supported:
+
+	- tar ... the uncompressed tar archive
+	- tar.gz ... the tar archive compressed by gzip
+	- tar.xz ... the tar archive compressed by xz
+	- zip ... the zip archive
+
+The archive is extracted into the destination directory, and the stamp file is
+written there. The stamp records the checksum of the archive and the options
+of the extraction, so the archive is extracted again only if they are changed.
+*/
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/harukasan/orchestra-pit/opit/logger"
+	"github.com/harukasan/orchestra-pit/state"
+	"github.com/harukasan/orchestra-pit/state/file"
+	"github.com/ulikunitz/xz"
+)
+
+// StampName specifies the name of the stamp file in the destination
+// directory.
+var StampName = ".opit-archive"
+
+// Formats returns the supported formats of the archives.
+func Formats() []string {
+	return []string{"tar", "tar.gz", "tar.xz", "zip"}
+}
+
+// FormatOf returns the format of the archive by the extension of the name. It
+// returns an empty string if the extension is unknown.
+func FormatOf(name string) string {
+	switch {
+	case strings.HasSuffix(name, ".tar"):
+		return "tar"
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return "tar.gz"
+	case strings.HasSuffix(name, ".tar.xz"), strings.HasSuffix(name, ".txz"):
+		return "tar.xz"
+	case strings.HasSuffix(name, ".zip"):
+		return "zip"
+	}
+	return ""
+}
+
+// Extracted manages the archive extracted into the directory.
+//
+// Archive specifies the path of the archive file, and Format specifies its
+// format. Dest specifies the directory to extract the archive into. The files
+// in the directory which are not in the archive are kept.
+//
+// StripComponents specifies the number of the leading components to remove
+// from the paths in the archive. The entries which have no more components
+// are skipped.
+//
+// Checksum specifies the checksum of the archive in the form of
+// "sha256:<hex>". If it is not empty, the archive is verified before extracted,
+// and Test does not need to read the archive.
+//
+// Owner and Group specify the names or the IDs of the owner and the group of
+// the extracted files. If they are empty, they are not changed. Mode specifies
+// the mode in the manner of chmod, which is applied to the modes in the
+// archive, e.g. go-w.
+//
+// The entries which have the absolute paths, the paths out of Dest, or the
+// links to out of Dest are refused.
+type Extracted struct {
+	Archive         string
+	Format          string
+	Dest            string
+	StripComponents int
+	Checksum        string
+	Owner           string
+	Group           string
+	Mode            string
+}
+
+// Apply tries to extract the archive, and writes the stamp file after all of
+// the entries are extracted.
+func (s *Extracted) Apply() error {
+	sum, err := s.sum()
+	if err != nil {
+		return err
+	}
+	if s.Checksum != "" {
+		got, err := fileSum(state.RootPath(s.Archive))
+		if err != nil {
+			return err
+		}
+		if got != sum {
+			return fmt.Errorf("the checksum of %s is sha256:%s, expected sha256:%s", s.Archive, got, sum)
+		}
+	}
+
+	x, err := s.extractor()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(x.dest, 0755); err != nil {
+		return err
+	}
+	if err := x.extract(state.RootPath(s.Archive), s.Format); err != nil {
+		return err
+	}
+	stamp := filepath.Join(x.root, StampName)
+	if err := remove(stamp); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(stamp, []byte(s.stamp(sum)), 0644)
+}
+
+// Test tests whether the stamp file records the same archive and the same
+// options.
+func (s *Extracted) Test() error {
+	sum, err := s.sum()
+	if err != nil {
+		return err
+	}
+	stamp, err := ioutil.ReadFile(filepath.Join(state.RootPath(s.Dest), StampName))
+	if err != nil {
+		return err
+	}
+	if string(stamp) != s.stamp(sum) {
+		return fmt.Errorf("%s is not extracted into %s", s.Archive, s.Dest)
+	}
+	return nil
+}
+
+func (s *Extracted) String() string {
+	return fmt.Sprintf("extract %s into %s", s.Archive, s.Dest)
+}
+
+// sum returns the hex digest of the archive, which is the checksum if it is
+// specified.
+func (s *Extracted) sum() (string, error) {
+	if s.Checksum != "" {
+		return file.ParseChecksum(s.Checksum)
+	}
+	return fileSum(state.RootPath(s.Archive))
+}
+
+// stamp returns the content of the stamp file.
+func (s *Extracted) stamp(sum string) string {
+	return fmt.Sprintf("sha256:%s strip_components=%d owner=%s group=%s mode=%s\n",
+		sum, s.StripComponents, s.Owner, s.Group, s.Mode)
+}
+
+func (s *Extracted) extractor() (*extractor, error) {
+	x := &extractor{
+		dest:  state.RootPath(s.Dest),
+		strip: s.StripComponents,
+		mode:  s.Mode,
+		uid:   -1,
+		gid:   -1,
+	}
+	if s.Owner != "" {
+		id, err := file.LookupUID(s.Owner)
+		if err != nil {
+			return nil, err
+		}
+		x.uid = int(id)
+	}
+	if s.Group != "" {
+		id, err := file.LookupGID(s.Group)
+		if err != nil {
+			return nil, err
+		}
+		x.gid = int(id)
+	}
+	return x, nil
+}
+
+func fileSum(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// entry is the entry of the archive.
+type entry struct {
+	name     string
+	mode     os.FileMode
+	link     string
+	hardlink bool
+}
+
+// extractor extracts the entries of the archive into the dest directory.
+type extractor struct {
+	dest  string
+	strip int
+
+	// root is the real path of dest, which the extracted files must be in.
+	root string
+
+	mode string
+	uid  int
+	gid  int
+
+	// dirs are the modes of the extracted directories, which are changed
+	// after their contents are extracted.
+	dirs map[string]os.FileMode
+}
+
+func (x *extractor) extract(name string, format string) error {
+	x.dirs = map[string]os.FileMode{}
+	root, err := filepath.EvalSymlinks(x.dest)
+	if err != nil {
+		return err
+	}
+	x.root = root
+	switch format {
+	case "zip":
+		err = x.extractZip(name)
+	case "tar", "tar.gz", "tar.xz":
+		err = x.extractTar(name, format)
+	default:
+		return fmt.Errorf(`unknown format "%s"`, format)
+	}
+	if err != nil {
+		return err
+	}
+	for dir, mode := range x.dirs {
+		if err := os.Chmod(dir, mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (x *extractor) extractTar(name string, format string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	switch format {
+	case "tar.gz":
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	case "tar.xz":
+		r, err = xz.NewReader(f)
+		if err != nil {
+			return err
+		}
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		e := entry{name: h.Name, mode: h.FileInfo().Mode(), link: h.Linkname}
+		switch h.Typeflag {
+		case tar.TypeReg, tar.TypeDir, tar.TypeSymlink:
+		case tar.TypeLink:
+			e.hardlink = true
+		default:
+			logger.Debugf("skip the entry %s of the type %q", h.Name, h.Typeflag)
+			continue
+		}
+		if err := x.put(e, tr); err != nil {
+			return err
+		}
+	}
+}
+
+func (x *extractor) extractZip(name string) error {
+	zr, err := zip.OpenReader(name)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		e := entry{name: f.Name, mode: f.Mode()}
+		r, err := f.Open()
+		if err != nil {
+			return err
+		}
+		if e.mode&os.ModeSymlink != 0 {
+			link, err := ioutil.ReadAll(r)
+			if err != nil {
+				r.Close()
+				return err
+			}
+			e.link = string(link)
+		}
+		err = x.put(e, r)
+		r.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// path returns the path of the entry in the dest directory, or an empty
+// string if the entry is stripped.
+func (x *extractor) path(name string) (string, error) {
+	if path.IsAbs(name) {
+		return "", fmt.Errorf("the archive has the absolute path %s", name)
+	}
+	for _, c := range strings.Split(name, "/") {
+		if c == ".." {
+			return "", fmt.Errorf("the archive has the path %s out of the destination", name)
+		}
+	}
+	cs := strings.Split(strings.Trim(path.Clean(name), "/"), "/")
+	if len(cs) <= x.strip || (len(cs) == 1 && cs[0] == ".") {
+		return "", nil
+	}
+	return filepath.Join(x.dest, filepath.FromSlash(path.Join(cs[x.strip:]...))), nil
+}
+
+// put writes the entry into the dest directory.
+func (x *extractor) put(e entry, r io.Reader) error {
+	name, err := x.path(e.name)
+	if err != nil || name == "" {
+		return err
+	}
+	mode := e.mode.Perm()
+	if x.mode != "" {
+		mode, err = file.ParseMode(x.mode, mode)
+		if err != nil {
+			return err
+		}
+	}
+	dir, err := x.mkdir(filepath.Dir(name))
+	if err != nil {
+		return err
+	}
+	name = filepath.Join(dir, filepath.Base(name))
+
+	switch {
+	case e.mode.IsDir():
+		if name, err = x.mkdir(name); err != nil {
+			return err
+		}
+		x.dirs[name] = mode
+	case e.hardlink:
+		target, err := x.path(e.link)
+		if err != nil {
+			return err
+		}
+		if target == "" {
+			return fmt.Errorf("the hard link %s refers to the stripped entry %s", e.name, e.link)
+		}
+		rel, err := filepath.Rel(x.dest, target)
+		if err != nil {
+			return err
+		}
+		if target = resolve(x.root, rel); !x.contains(target) {
+			return fmt.Errorf("the hard link %s refers to %s out of the destination", e.name, e.link)
+		}
+		if err := remove(name); err != nil {
+			return err
+		}
+		if err := os.Link(target, name); err != nil {
+			return err
+		}
+	case e.mode&os.ModeSymlink != 0:
+		if path.IsAbs(e.link) || !x.contains(resolve(dir, e.link)) {
+			return fmt.Errorf("the symbolic link %s refers to %s out of the destination", e.name, e.link)
+		}
+		if err := remove(name); err != nil {
+			return err
+		}
+		if err := os.Symlink(e.link, name); err != nil {
+			return err
+		}
+	default:
+		if err := remove(name); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(f, r); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		if err := os.Chmod(name, mode); err != nil {
+			return err
+		}
+	}
+
+	if x.uid >= 0 || x.gid >= 0 {
+		if err := os.Lchown(name, x.uid, x.gid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mkdir makes the directory in the destination with its parents, and returns
+// its real path. The symbolic links which are already extracted are followed,
+// and it returns an error if the directory is out of the destination through
+// them.
+func (x *extractor) mkdir(dir string) (string, error) {
+	rel, err := filepath.Rel(x.dest, dir)
+	if err != nil {
+		return "", err
+	}
+	real := x.root
+	for _, c := range strings.Split(rel, string(filepath.Separator)) {
+		if c == "." {
+			continue
+		}
+		real = resolve(real, c)
+		if !x.contains(real) {
+			return "", fmt.Errorf("the path %s is out of the destination through the symbolic link", dir)
+		}
+		info, err := os.Stat(real)
+		if os.IsNotExist(err) {
+			if err := os.Mkdir(real, 0755); err != nil {
+				return "", err
+			}
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if !info.IsDir() {
+			return "", fmt.Errorf("the path %s is not a directory", dir)
+		}
+	}
+	return real, nil
+}
+
+// contains tests whether the real path is in the destination.
+func (x *extractor) contains(real string) bool {
+	return real == x.root || strings.HasPrefix(real, x.root+string(filepath.Separator))
+}
+
+// resolve returns the real path of the name relative to the real directory
+// dir. The symbolic links on the path are followed if they exist, and the
+// rest of the path is joined as it is.
+func resolve(dir string, name string) string {
+	for _, c := range strings.Split(filepath.ToSlash(name), "/") {
+		switch c {
+		case "", ".":
+			continue
+		case "..":
+			dir = filepath.Dir(dir)
+			continue
+		}
+		dir = filepath.Join(dir, c)
+		if real, err := filepath.EvalSymlinks(dir); err == nil {
+			dir = real
+		}
+	}
+	return dir
+}
+
+// remove removes the existing file, not to write through the existing links.
+func remove(name string) error {
+	if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,251 @@
+package archive_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/harukasan/orchestra-pit/state"
+	"github.com/harukasan/orchestra-pit/state/archive"
+	"github.com/ulikunitz/xz"
+)
+
+// withRoot changes state.Root to the temporary directory, and returns the
+// directory and the function to restore state.Root and to remove it.
+func withRoot(t *testing.T) (string, func()) {
+	root, err := ioutil.TempDir("", "archive_test_root_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := state.Root
+	state.Root = root
+	return root, func() {
+		state.Root = orig
+		os.RemoveAll(root)
+	}
+}
+
+type testEntry struct {
+	name string
+	body string
+	link string
+	dir  bool
+}
+
+var testEntries = []testEntry{
+	{name: "tool-1.0/", dir: true},
+	{name: "tool-1.0/bin/", dir: true},
+	{name: "tool-1.0/bin/tool", body: "#!/bin/sh\n"},
+	{name: "tool-1.0/README", body: "hello\n"},
+	{name: "tool-1.0/bin/t", link: "tool"},
+}
+
+func writeTar(t *testing.T, w io.Writer, entries []testEntry) {
+	tw := tar.NewWriter(w)
+	for _, e := range entries {
+		h := &tar.Header{Name: e.name, Mode: 0755, Size: int64(len(e.body)), Typeflag: tar.TypeReg}
+		switch {
+		case e.dir:
+			h.Typeflag = tar.TypeDir
+		case e.link != "":
+			h.Typeflag = tar.TypeSymlink
+			h.Linkname = e.link
+		}
+		if err := tw.WriteHeader(h); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeArchive(t *testing.T, name string, format string, entries []testEntry) {
+	buf := &bytes.Buffer{}
+	switch format {
+	case "tar":
+		writeTar(t, buf, entries)
+	case "tar.gz":
+		gz := gzip.NewWriter(buf)
+		writeTar(t, gz, entries)
+		gz.Close()
+	case "tar.xz":
+		xw, err := xz.NewWriter(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeTar(t, xw, entries)
+		xw.Close()
+	case "zip":
+		zw := zip.NewWriter(buf)
+		for _, e := range entries {
+			h := &zip.FileHeader{Name: e.name}
+			h.SetMode(0755)
+			body := e.body
+			if e.link != "" {
+				h.SetMode(os.ModeSymlink | 0777)
+				body = e.link
+			}
+			w, err := zw.CreateHeader(h)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w.Write([]byte(body))
+		}
+		zw.Close()
+	}
+	if err := ioutil.WriteFile(name, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExtracted(t *testing.T) {
+	for _, format := range archive.Formats() {
+		root, cleanup := withRoot(t)
+		defer cleanup()
+		writeArchive(t, path.Join(root, "tool."+format), format, testEntries)
+
+		s := &archive.Extracted{
+			Archive:         "/tool." + format,
+			Format:          format,
+			Dest:            "/opt/tool",
+			StripComponents: 1,
+			Mode:            "go-w",
+		}
+		if err := s.Test(); err == nil {
+			t.Errorf("%s: got no error before extracted", format)
+		}
+		if err := s.Apply(); err != nil {
+			t.Fatalf("%s: got error on apply: %v", format, err)
+		}
+		if err := s.Test(); err != nil {
+			t.Errorf("%s: got error on test: %v", format, err)
+		}
+
+		dest := path.Join(root, "opt/tool")
+		if data, _ := ioutil.ReadFile(path.Join(dest, "README")); string(data) != "hello\n" {
+			t.Errorf("%s: got README %q", format, data)
+		}
+		if info, err := os.Stat(path.Join(dest, "bin/tool")); err != nil || info.Mode().Perm() != 0755 {
+			t.Errorf("%s: got %v, %v, expected the mode 0755", format, info, err)
+		}
+		if link, err := os.Readlink(path.Join(dest, "bin/t")); err != nil || link != "tool" {
+			t.Errorf("%s: got the link %q, %v", format, link, err)
+		}
+
+		s.StripComponents = 0
+		if err := s.Test(); err == nil {
+			t.Errorf("%s: got no error for the different options", format)
+		}
+	}
+}
+
+func TestExtractedChecksum(t *testing.T) {
+	root, cleanup := withRoot(t)
+	defer cleanup()
+	name := path.Join(root, "tool.tar")
+	writeArchive(t, name, "tar", testEntries)
+	data, _ := ioutil.ReadFile(name)
+	sum := sha256.Sum256(data)
+
+	s := &archive.Extracted{
+		Archive:  "/tool.tar",
+		Format:   "tar",
+		Dest:     "/opt/tool",
+		Checksum: "sha256:" + hex.EncodeToString(sum[:]),
+	}
+	if err := s.Apply(); err != nil {
+		t.Fatalf("got error on apply: %v", err)
+	}
+	// Test does not read the archive if the checksum is specified.
+	os.Remove(name)
+	if err := s.Test(); err != nil {
+		t.Errorf("got error on test: %v", err)
+	}
+
+	writeArchive(t, name, "tar", testEntries[:2])
+	if err := s.Apply(); err == nil {
+		t.Errorf("got no error for the checksum mismatch")
+	}
+}
+
+func TestExtractedTraversal(t *testing.T) {
+	invalid := [][]testEntry{
+		{{name: "../evil", body: "x"}},
+		{{name: "a/../../evil", body: "x"}},
+		{{name: "/etc/evil", body: "x"}},
+		{{name: "evil", link: "/etc/passwd"}},
+		{{name: "a/evil", link: "../../etc/passwd"}},
+	}
+	for _, entries := range invalid {
+		root, cleanup := withRoot(t)
+		defer cleanup()
+		writeArchive(t, path.Join(root, "evil.tar"), "tar", entries)
+		s := &archive.Extracted{Archive: "/evil.tar", Format: "tar", Dest: "/opt/evil"}
+		if err := s.Apply(); err == nil {
+			t.Errorf("got no error for %+v", entries[0])
+		}
+		if _, err := os.Stat(path.Join(root, "opt", archive.StampName)); !os.IsNotExist(err) {
+			t.Errorf("got %v, expected no stamp for %+v", err, entries[0])
+		}
+	}
+
+	// the links which are extracted before lead the later entries out of the
+	// destination.
+	chained := []struct {
+		entries []testEntry
+		escaped string
+	}{
+		{[]testEntry{{name: "a/b", link: ".."}, {name: "a/c", link: "b/.."}, {name: "a/c/evil", body: "x"}}, "opt/evil"},
+		{[]testEntry{{name: "a/b", link: ".."}, {name: "a/c", link: "b/../.."}, {name: "a/c/evil", body: "x"}}, "evil"},
+	}
+	for _, test := range chained {
+		root, cleanup := withRoot(t)
+		defer cleanup()
+		writeArchive(t, path.Join(root, "evil.tar"), "tar", test.entries)
+		s := &archive.Extracted{Archive: "/evil.tar", Format: "tar", Dest: "/opt/tool"}
+		if err := s.Apply(); err == nil {
+			t.Errorf("got no error for %+v", test.entries)
+		}
+		if _, err := os.Stat(path.Join(root, test.escaped)); !os.IsNotExist(err) {
+			t.Errorf("got %v, expected no file out of the destination for %+v", err, test.entries)
+		}
+	}
+
+	// the symbolic link on the disk leads the entry out of the destination.
+	root, cleanup := withRoot(t)
+	defer cleanup()
+	writeArchive(t, path.Join(root, "evil.tar"), "tar", []testEntry{{name: "link/evil", body: "x"}})
+	os.MkdirAll(path.Join(root, "opt/evil"), 0755)
+	os.Mkdir(path.Join(root, "outside"), 0755)
+	if err := os.Symlink(path.Join(root, "outside"), path.Join(root, "opt/evil/link")); err != nil {
+		t.Fatal(err)
+	}
+	s := &archive.Extracted{Archive: "/evil.tar", Format: "tar", Dest: "/opt/evil"}
+	if err := s.Apply(); err == nil {
+		t.Errorf("got no error for the existing link out of the destination")
+	}
+	if _, err := os.Stat(path.Join(root, "outside/evil")); !os.IsNotExist(err) {
+		t.Errorf("got %v, expected no file through the link", err)
+	}
+
+	// the link in the stripped component is out of the destination.
+	root, cleanup = withRoot(t)
+	defer cleanup()
+	writeArchive(t, path.Join(root, "evil.tar"), "tar", []testEntry{{name: "a/evil", link: "../b"}})
+	s = &archive.Extracted{Archive: "/evil.tar", Format: "tar", Dest: "/opt/evil", StripComponents: 1}
+	if err := s.Apply(); err == nil {
+		t.Errorf("got no error for the link out of the destination after stripped")
+	}
+}
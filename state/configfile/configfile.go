@@ -0,0 +1,226 @@
+/*
+Package configfile implements the states of the values in the structured
+configuration files.
+
+Following formats are supported:
+
+	- ini  ... the lines of "key = value" in the [section]s
+	- json ... JSON, whose objects keep the order of the keys
+	- yaml ... YAML, whose comments and the order of the keys are kept
+
+The key of JSON and YAML is the path of the keys separated by ".", and the
+numeric key refers the element of the list. The key of INI is the name of the
+key in the section. The lines and the keys which are not edited are kept as
+they are.
+
+Following states are provided:
+
+	- Value   ... the key has the value
+	- Absence ... the file does not have the key
+*/
+package configfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+
+	"github.com/harukasan/orchestra-pit/state"
+	"github.com/harukasan/orchestra-pit/state/file"
+)
+
+// editor edits the values in the configuration file of the format. value
+// converts the requested value into the value which get returns.
+type editor interface {
+	value(v interface{}) (interface{}, error)
+	get(data []byte, section string, key string) (value interface{}, found bool, err error)
+	set(data []byte, section string, key string, value interface{}) ([]byte, error)
+	remove(data []byte, section string, key string) ([]byte, error)
+}
+
+var editors = map[string]editor{
+	"ini":  iniEditor{},
+	"json": jsonEditor{},
+	"yaml": yamlEditor{},
+}
+
+// Formats returns the supported formats.
+func Formats() []string {
+	return []string{"ini", "json", "yaml"}
+}
+
+// FormatOf returns the format of the file by its extension. It returns an
+// empty string if the format is unknown.
+func FormatOf(name string) string {
+	switch path.Ext(name) {
+	case ".ini":
+		return "ini"
+	case ".json":
+		return "json"
+	case ".yaml", ".yml":
+		return "yaml"
+	}
+	return ""
+}
+
+func editorOf(format string) (editor, error) {
+	e, ok := editors[format]
+	if !ok {
+		return nil, fmt.Errorf(`unknown format "%s"`, format)
+	}
+	return e, nil
+}
+
+// readFile reads the named file. The file which does not exist is empty.
+func readFile(name string) ([]byte, error) {
+	data, err := ioutil.ReadFile(state.RootPath(name))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Value is a state that the key in the configuration file has the value.
+//
+// Name specifies the file name, and Format specifies its format. Section
+// specifies the section of INI. Key specifies the key, see the package
+// document.
+//
+// If the file or the key does not exist, it is created. If the Backup value is
+// not empty, the original file is copied to the backup file before the file is
+// changed.
+type Value struct {
+	Name    string
+	Format  string
+	Section string
+	Key     string
+	Value   interface{}
+	Backup  string
+}
+
+// Apply tries to set the value to the key.
+func (s *Value) Apply() error {
+	e, err := editorOf(s.Format)
+	if err != nil {
+		return err
+	}
+	data, err := readFile(s.Name)
+	if err != nil {
+		return err
+	}
+	result, err := e.set(data, s.Section, s.Key, s.Value)
+	if err != nil {
+		return err
+	}
+	return (&file.Content{Name: s.Name, Content: result, Backup: s.Backup}).Apply()
+}
+
+// Test tests whether the key has the same value.
+func (s *Value) Test() error {
+	e, err := editorOf(s.Format)
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadFile(state.RootPath(s.Name))
+	if err != nil {
+		return err
+	}
+	v, found, err := e.get(data, s.Section, s.Key)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf(`the key "%s" is not found`, s.Key)
+	}
+	want, err := e.value(s.Value)
+	if err != nil {
+		return err
+	}
+	if !equal(v, want) {
+		return fmt.Errorf(`the value of the key "%s" is different from the requested`, s.Key)
+	}
+	return nil
+}
+
+// Absence is a state that the configuration file does not have the key.
+type Absence struct {
+	Name    string
+	Format  string
+	Section string
+	Key     string
+	Backup  string
+}
+
+// Apply tries to remove the key.
+func (s *Absence) Apply() error {
+	e, err := editorOf(s.Format)
+	if err != nil {
+		return err
+	}
+	data, err := readFile(s.Name)
+	if err != nil {
+		return err
+	}
+	result, err := e.remove(data, s.Section, s.Key)
+	if err != nil {
+		return err
+	}
+	return (&file.Content{Name: s.Name, Content: result, Backup: s.Backup}).Apply()
+}
+
+// Test tests whether the file does not have the key. The file which does not
+// exist has no keys.
+func (s *Absence) Test() error {
+	e, err := editorOf(s.Format)
+	if err != nil {
+		return err
+	}
+	data, err := readFile(s.Name)
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	_, found, err := e.get(data, s.Section, s.Key)
+	if err != nil {
+		return err
+	}
+	if found {
+		return fmt.Errorf(`the key "%s" exists`, s.Key)
+	}
+	return nil
+}
+
+// equal returns whether the values are the same as JSON values, so the
+// numbers of the different types are compared by their values.
+func equal(a, b interface{}) bool {
+	na, err := normalize(a)
+	if err != nil {
+		return false
+	}
+	nb, err := normalize(b)
+	if err != nil {
+		return false
+	}
+	return reflect.DeepEqual(na, nb)
+}
+
+func normalize(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var n interface{}
+	err = json.Unmarshal(b, &n)
+	return n, err
+}
+
+// splitKey splits the key path into the keys.
+func splitKey(key string) []string {
+	return strings.Split(key, ".")
+}
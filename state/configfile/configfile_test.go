@@ -0,0 +1,203 @@
+package configfile_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/harukasan/orchestra-pit/state"
+	"github.com/harukasan/orchestra-pit/state/configfile"
+)
+
+// edit writes the data into the file, applies the state, and returns the
+// result.
+func edit(t *testing.T, name string, data string, s state.State) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "configfile_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	switch s := s.(type) {
+	case *configfile.Value:
+		s.Name = path
+	case *configfile.Absence:
+		s.Name = path
+	}
+
+	if err := s.Test(); err == nil {
+		t.Errorf("got no error before apply")
+	}
+	if err := s.Apply(); err != nil {
+		t.Fatalf("got error on apply: %v", err)
+	}
+	if err := s.Test(); err != nil {
+		t.Errorf("got error after apply: %v", err)
+	}
+	result, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(result)
+}
+
+const phpINI = `[PHP]
+; the memory limit
+memory_limit = 128M
+
+[Date]
+;date.timezone =
+`
+
+func TestINI(t *testing.T) {
+	got := edit(t, "php.ini", phpINI, &configfile.Value{Format: "ini", Section: "Date", Key: "date.timezone", Value: "Asia/Tokyo"})
+	expected := "[PHP]\n; the memory limit\nmemory_limit = 128M\n\n[Date]\n;date.timezone =\ndate.timezone = Asia/Tokyo\n"
+	if got != expected {
+		t.Errorf("got %q, expected %q", got, expected)
+	}
+
+	got = edit(t, "php.ini", phpINI, &configfile.Value{Format: "ini", Section: "PHP", Key: "memory_limit", Value: 256})
+	if expected := "[PHP]\n; the memory limit\nmemory_limit = 256\n\n[Date]\n;date.timezone =\n"; got != expected {
+		t.Errorf("got %q, expected %q", got, expected)
+	}
+
+	got = edit(t, "php.ini", phpINI, &configfile.Value{Format: "ini", Section: "opcache", Key: "opcache.enable", Value: true})
+	if expected := phpINI + "\n[opcache]\nopcache.enable = true\n"; got != expected {
+		t.Errorf("got %q, expected %q", got, expected)
+	}
+
+	got = edit(t, "php.ini", phpINI, &configfile.Absence{Format: "ini", Section: "PHP", Key: "memory_limit"})
+	if expected := "[PHP]\n; the memory limit\n\n[Date]\n;date.timezone =\n"; got != expected {
+		t.Errorf("got %q, expected %q", got, expected)
+	}
+}
+
+func TestINILargeNumber(t *testing.T) {
+	got := edit(t, "www.conf", "[www]\n", &configfile.Value{Format: "ini", Section: "www", Key: "pm.max_requests", Value: float64(1048576)})
+	if expected := "[www]\npm.max_requests = 1048576\n"; got != expected {
+		t.Errorf("got %q, expected %q", got, expected)
+	}
+}
+
+func TestINIInlineComment(t *testing.T) {
+	dir, err := ioutil.TempDir("", "configfile_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "php.ini")
+	data := "[Date]\ndate.timezone = UTC ; tz\nmessage = \"a ; b\" # quoted\n"
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &configfile.Value{Name: path, Format: "ini", Section: "Date", Key: "date.timezone", Value: "UTC"}
+	if err := s.Test(); err != nil {
+		t.Errorf("got error for the value with the comment: %v", err)
+	}
+	s = &configfile.Value{Name: path, Format: "ini", Section: "Date", Key: "message", Value: "a ; b"}
+	if err := s.Test(); err != nil {
+		t.Errorf("got error for the quoted value: %v", err)
+	}
+
+	got := edit(t, "php.ini", data, &configfile.Value{Format: "ini", Section: "Date", Key: "date.timezone", Value: "Asia/Tokyo"})
+	if expected := "[Date]\ndate.timezone = Asia/Tokyo ; tz\nmessage = \"a ; b\" # quoted\n"; got != expected {
+		t.Errorf("got %q, expected %q", got, expected)
+	}
+}
+
+const daemonJSON = `{
+    "log-driver": "json-file",
+    "log-opts": {
+        "max-size": "10m"
+    },
+    "dns": ["192.0.2.53"]
+}
+`
+
+func TestJSON(t *testing.T) {
+	got := edit(t, "daemon.json", daemonJSON, &configfile.Value{Format: "json", Key: "log-opts.max-file", Value: "3"})
+	expected := `{
+    "log-driver": "json-file",
+    "log-opts": {
+        "max-size": "10m",
+        "max-file": "3"
+    },
+    "dns": [
+        "192.0.2.53"
+    ]
+}
+`
+	if got != expected {
+		t.Errorf("got %s, expected %s", got, expected)
+	}
+
+	got = edit(t, "daemon.json", daemonJSON, &configfile.Value{Format: "json", Key: "dns.0", Value: "192.0.2.1"})
+	if configfile.FormatOf("daemon.json") != "json" || !strings.Contains(got, `"192.0.2.1"`) {
+		t.Errorf("got %s", got)
+	}
+
+	got = edit(t, "daemon.json", daemonJSON, &configfile.Value{Format: "json", Key: "registry-mirrors", Value: []interface{}{"https://mirror.example.com/?a=1&b=<2>"}})
+	if !strings.Contains(got, `"https://mirror.example.com/?a=1&b=<2>"`) {
+		t.Errorf("got %s, expected the URL not escaped", got)
+	}
+
+	got = edit(t, "daemon.json", daemonJSON, &configfile.Absence{Format: "json", Key: "log-opts"})
+	if strings.Contains(got, "max-size") || !strings.Contains(got, "log-driver") {
+		t.Errorf("got %s", got)
+	}
+}
+
+const netplan = `# the network of the host
+network:
+  version: 2
+  ethernets:
+    eth0:
+      dhcp4: true # by the router
+`
+
+func TestYAMLLargeNumber(t *testing.T) {
+	got := edit(t, "app.yaml", "server:\n  port: 80\n", &configfile.Value{Format: "yaml", Key: "server.max_body", Value: float64(10485760)})
+	if expected := "server:\n  port: 80\n  max_body: 10485760\n"; got != expected {
+		t.Errorf("got %q, expected %q", got, expected)
+	}
+}
+
+func TestYAML(t *testing.T) {
+	got := edit(t, "netplan.yaml", netplan, &configfile.Value{Format: "yaml", Key: "network.ethernets.eth0.dhcp4", Value: false})
+	expected := `# the network of the host
+network:
+  version: 2
+  ethernets:
+    eth0:
+      dhcp4: false # by the router
+`
+	if got != expected {
+		t.Errorf("got %s, expected %s", got, expected)
+	}
+
+	got = edit(t, "netplan.yaml", netplan, &configfile.Value{Format: "yaml", Key: "network.ethernets.eth0.addresses", Value: []interface{}{"192.0.2.10/24"}})
+	expected = `# the network of the host
+network:
+  version: 2
+  ethernets:
+    eth0:
+      dhcp4: true # by the router
+      addresses:
+        - 192.0.2.10/24
+`
+	if got != expected {
+		t.Errorf("got %s, expected %s", got, expected)
+	}
+
+	got = edit(t, "netplan.yaml", netplan, &configfile.Absence{Format: "yaml", Key: "network.version"})
+	if strings.Contains(got, "version") || !strings.Contains(got, "# by the router") {
+		t.Errorf("got %s", got)
+	}
+}
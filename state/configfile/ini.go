@@ -0,0 +1,149 @@
+package configfile
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	iniSection = regexp.MustCompile(`^\s*\[([^\]]*)\]\s*$`)
+	// iniKey matches the key, the value and the inline comment of the line.
+	// The comment starts with ";" or "#" after the spaces, and the quoted value
+	// can contain them.
+	iniKey = regexp.MustCompile(`^(\s*([^=;#\[\s][^=]*?)\s*=\s*)("(?:[^"\\]|\\.)*"|.*?)(\s+[;#].*?)?\s*$`)
+)
+
+// iniEditor edits the INI files. The keys before the first section are in the
+// section of the empty name.
+type iniEditor struct{}
+
+// iniLines returns the lines of the data, and the range of the lines of the
+// section. The range is -1 if the data does not have the section.
+func iniLines(data []byte, section string) (lines []string, start int, end int) {
+	s := strings.TrimSuffix(string(data), "\n")
+	if s != "" {
+		lines = strings.Split(s, "\n")
+	}
+	start, end = -1, -1
+	if section == "" {
+		start = 0
+	}
+	for i, l := range lines {
+		m := iniSection.FindStringSubmatch(l)
+		if m == nil {
+			continue
+		}
+		if start >= 0 {
+			return lines, start, i
+		}
+		if strings.TrimSpace(m[1]) == section {
+			start = i + 1
+		}
+	}
+	if start >= 0 {
+		end = len(lines)
+	}
+	return lines, start, end
+}
+
+func (iniEditor) value(v interface{}) (interface{}, error) {
+	return iniFormat(v)
+}
+
+func (iniEditor) get(data []byte, section string, key string) (interface{}, bool, error) {
+	lines, start, end := iniLines(data, section)
+	if start < 0 {
+		return nil, false, nil
+	}
+	var value interface{}
+	found := false
+	for _, l := range lines[start:end] {
+		if m := iniKey.FindStringSubmatch(l); m != nil && m[2] == key {
+			value, found = iniUnquote(m[3]), true
+		}
+	}
+	return value, found, nil
+}
+
+func (iniEditor) set(data []byte, section string, key string, value interface{}) ([]byte, error) {
+	v, err := iniFormat(value)
+	if err != nil {
+		return nil, err
+	}
+	lines, start, end := iniLines(data, section)
+	if start < 0 {
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, "["+section+"]", key+" = "+v)
+		return iniJoin(lines), nil
+	}
+
+	found := false
+	for i := start; i < end; i++ {
+		if m := iniKey.FindStringSubmatch(lines[i]); m != nil && m[2] == key {
+			lines[i] = m[1] + v + m[4]
+			found = true
+		}
+	}
+	if !found {
+		// insert after the last line of the section, before the blank lines.
+		at := end
+		for at > start && strings.TrimSpace(lines[at-1]) == "" {
+			at--
+		}
+		lines = append(lines[:at], append([]string{key + " = " + v}, lines[at:]...)...)
+	}
+	return iniJoin(lines), nil
+}
+
+func (iniEditor) remove(data []byte, section string, key string) ([]byte, error) {
+	lines, start, end := iniLines(data, section)
+	if start < 0 {
+		return data, nil
+	}
+	result := append([]string{}, lines[:start]...)
+	for _, l := range lines[start:end] {
+		if m := iniKey.FindStringSubmatch(l); m == nil || m[2] != key {
+			result = append(result, l)
+		}
+	}
+	return iniJoin(append(result, lines[end:]...)), nil
+}
+
+func iniJoin(lines []string) []byte {
+	if len(lines) == 0 {
+		return []byte{}
+	}
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
+
+// iniUnquote returns the value without the double quotes.
+func iniUnquote(v string) string {
+	if len(v) >= 2 && strings.HasPrefix(v, `"`) && strings.HasSuffix(v, `"`) {
+		if s, err := strconv.Unquote(v); err == nil {
+			return s
+		}
+	}
+	return v
+}
+
+// iniFormat formats the scalar value in INI.
+func iniFormat(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		if strings.ContainsAny(v, "\r\n") {
+			return "", fmt.Errorf("the value of INI must be a line")
+		}
+		return v, nil
+	case float64:
+		// the numbers decoded from the recipe are float64, which are formatted
+		// without the exponent.
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool, int, int64:
+		return fmt.Sprint(v), nil
+	}
+	return "", fmt.Errorf("the value of INI must be a string, a number or a boolean")
+}
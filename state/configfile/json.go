@@ -0,0 +1,298 @@
+package configfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonEditor edits the JSON files. The objects are decoded into jsonObject to
+// keep the order of the keys.
+type jsonEditor struct{}
+
+// jsonObject is the JSON object which keeps the order of the keys.
+type jsonObject []jsonMember
+
+type jsonMember struct {
+	Key   string
+	Value interface{}
+}
+
+func (o jsonObject) index(key string) int {
+	for i, m := range o {
+		if m.Key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func (jsonEditor) value(v interface{}) (interface{}, error) {
+	return v, nil
+}
+
+func (jsonEditor) get(data []byte, section string, key string) (interface{}, bool, error) {
+	v, err := decodeJSON(data)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, k := range splitKey(key) {
+		switch c := v.(type) {
+		case jsonObject:
+			i := c.index(k)
+			if i < 0 {
+				return nil, false, nil
+			}
+			v = c[i].Value
+		case []interface{}:
+			i, err := strconv.Atoi(k)
+			if err != nil || i < 0 || i >= len(c) {
+				return nil, false, nil
+			}
+			v = c[i]
+		default:
+			return nil, false, nil
+		}
+	}
+	return plainJSON(v), true, nil
+}
+
+func (jsonEditor) set(data []byte, section string, key string, value interface{}) ([]byte, error) {
+	v, err := decodeJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		v = jsonObject{}
+	}
+	v, err = setJSON(v, splitKey(key), value)
+	if err != nil {
+		return nil, fmt.Errorf(`can not set the key "%s": %s`, key, err)
+	}
+	return encodeJSON(v, jsonIndent(data)), nil
+}
+
+func (jsonEditor) remove(data []byte, section string, key string) ([]byte, error) {
+	v, err := decodeJSON(data)
+	if err != nil || v == nil {
+		return data, err
+	}
+	v, found, err := removeJSON(v, splitKey(key))
+	if err != nil {
+		return nil, fmt.Errorf(`can not remove the key "%s": %s`, key, err)
+	}
+	if !found {
+		return data, nil
+	}
+	return encodeJSON(v, jsonIndent(data)), nil
+}
+
+// removeJSON removes the value at the keys in v, and returns the result and
+// whether the value is found.
+func removeJSON(v interface{}, keys []string) (interface{}, bool, error) {
+	switch c := v.(type) {
+	case jsonObject:
+		i := c.index(keys[0])
+		if i < 0 {
+			return v, false, nil
+		}
+		if len(keys) == 1 {
+			return append(c[:i:i], c[i+1:]...), true, nil
+		}
+		child, found, err := removeJSON(c[i].Value, keys[1:])
+		c[i].Value = child
+		return c, found, err
+	case []interface{}:
+		if len(keys) == 1 {
+			return nil, false, fmt.Errorf("can not remove the element of the list")
+		}
+		i, err := strconv.Atoi(keys[0])
+		if err != nil || i < 0 || i >= len(c) {
+			return v, false, nil
+		}
+		child, found, err := removeJSON(c[i], keys[1:])
+		c[i] = child
+		return c, found, err
+	}
+	return v, false, nil
+}
+
+// setJSON sets the value at the keys in v, and returns the result. The objects
+// on the keys are created if they do not exist.
+func setJSON(v interface{}, keys []string, value interface{}) (interface{}, error) {
+	if len(keys) == 0 {
+		return value, nil
+	}
+	switch c := v.(type) {
+	case jsonObject:
+		i := c.index(keys[0])
+		if i < 0 {
+			child, err := setJSON(jsonObject{}, keys[1:], value)
+			if err != nil {
+				return nil, err
+			}
+			return append(c, jsonMember{Key: keys[0], Value: child}), nil
+		}
+		child, err := setJSON(c[i].Value, keys[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		c[i].Value = child
+		return c, nil
+	case []interface{}:
+		i, err := strconv.Atoi(keys[0])
+		if err != nil || i < 0 || i >= len(c) {
+			return nil, fmt.Errorf("the index %q is out of the list", keys[0])
+		}
+		child, err := setJSON(c[i], keys[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		c[i] = child
+		return c, nil
+	}
+	return nil, fmt.Errorf("the key %q is not in an object", keys[0])
+}
+
+// decodeJSON decodes the data. It returns nil if the data is empty.
+func decodeJSON(data []byte) (interface{}, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	v, err := decodeJSONValue(d)
+	if err != nil {
+		return nil, fmt.Errorf("can not parse the JSON file: %s", err)
+	}
+	return v, nil
+}
+
+func decodeJSONValue(d *json.Decoder) (interface{}, error) {
+	t, err := d.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t {
+	case json.Delim('{'):
+		o := jsonObject{}
+		for d.More() {
+			k, err := d.Token()
+			if err != nil {
+				return nil, err
+			}
+			v, err := decodeJSONValue(d)
+			if err != nil {
+				return nil, err
+			}
+			o = append(o, jsonMember{Key: k.(string), Value: v})
+		}
+		_, err := d.Token()
+		return o, err
+	case json.Delim('['):
+		a := []interface{}{}
+		for d.More() {
+			v, err := decodeJSONValue(d)
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, v)
+		}
+		_, err := d.Token()
+		return a, err
+	}
+	return t, nil
+}
+
+// plainJSON converts the jsonObjects in v into the maps.
+func plainJSON(v interface{}) interface{} {
+	switch c := v.(type) {
+	case jsonObject:
+		m := map[string]interface{}{}
+		for _, member := range c {
+			m[member.Key] = plainJSON(member.Value)
+		}
+		return m
+	case []interface{}:
+		a := make([]interface{}, len(c))
+		for i, e := range c {
+			a[i] = plainJSON(e)
+		}
+		return a
+	}
+	return v
+}
+
+// jsonIndent returns the indent of the data, which is two spaces by default.
+func jsonIndent(data []byte) string {
+	for _, l := range strings.Split(string(data), "\n") {
+		if t := strings.TrimLeft(l, " \t"); t != "" && len(t) < len(l) {
+			return l[:len(l)-len(t)]
+		}
+	}
+	return "  "
+}
+
+// encodeJSON encodes v with the indent.
+func encodeJSON(v interface{}, indent string) []byte {
+	buf := &bytes.Buffer{}
+	writeJSON(buf, v, indent, 0)
+	buf.WriteString("\n")
+	return buf.Bytes()
+}
+
+func writeJSON(buf *bytes.Buffer, v interface{}, indent string, depth int) {
+	prefix := strings.Repeat(indent, depth)
+	switch c := v.(type) {
+	case jsonObject:
+		if len(c) == 0 {
+			buf.WriteString("{}")
+			return
+		}
+		buf.WriteString("{\n")
+		for i, m := range c {
+			buf.WriteString(prefix + indent)
+			buf.Write(marshalJSON(m.Key, prefix+indent, indent))
+			buf.WriteString(": ")
+			writeJSON(buf, m.Value, indent, depth+1)
+			if i < len(c)-1 {
+				buf.WriteString(",")
+			}
+			buf.WriteString("\n")
+		}
+		buf.WriteString(prefix + "}")
+	case []interface{}:
+		if len(c) == 0 {
+			buf.WriteString("[]")
+			return
+		}
+		buf.WriteString("[\n")
+		for i, e := range c {
+			buf.WriteString(prefix + indent)
+			writeJSON(buf, e, indent, depth+1)
+			if i < len(c)-1 {
+				buf.WriteString(",")
+			}
+			buf.WriteString("\n")
+		}
+		buf.WriteString(prefix + "]")
+	default:
+		buf.Write(marshalJSON(c, prefix, indent))
+	}
+}
+
+// marshalJSON encodes v with the indent. Unlike json.MarshalIndent, the
+// characters such as "&" and "<" are not escaped, so the strings are written
+// as they are. It returns null if v can not be encoded.
+func marshalJSON(v interface{}, prefix string, indent string) []byte {
+	buf := &bytes.Buffer{}
+	e := json.NewEncoder(buf)
+	e.SetEscapeHTML(false)
+	e.SetIndent(prefix, indent)
+	if err := e.Encode(v); err != nil {
+		return []byte("null")
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
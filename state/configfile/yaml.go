@@ -0,0 +1,190 @@
+package configfile
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlEditor edits the YAML files. The files are edited as the node trees of
+// yaml.v3 to keep the comments and the order of the keys.
+type yamlEditor struct{}
+
+func (yamlEditor) value(v interface{}) (interface{}, error) {
+	return yamlValue(v), nil
+}
+
+// yamlValue converts the whole-number floats in v into the integers. The
+// numbers decoded from the recipe are float64, and YAML reads the float as
+// !!float even if it is a whole number.
+func yamlValue(v interface{}) interface{} {
+	switch c := v.(type) {
+	case float64:
+		if c == math.Trunc(c) && math.Abs(c) < 1<<53 {
+			return int64(c)
+		}
+	case []interface{}:
+		a := make([]interface{}, len(c))
+		for i, e := range c {
+			a[i] = yamlValue(e)
+		}
+		return a
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(c))
+		for k, e := range c {
+			m[k] = yamlValue(e)
+		}
+		return m
+	}
+	return v
+}
+
+// parseYAML parses the data into the document node. The empty data is the
+// document of an empty mapping.
+func parseYAML(data []byte) (*yaml.Node, error) {
+	doc := &yaml.Node{}
+	if err := yaml.Unmarshal(data, doc); err != nil {
+		return nil, fmt.Errorf("can not parse the YAML file: %s", err)
+	}
+	if doc.Kind == 0 || len(doc.Content) == 0 {
+		doc = &yaml.Node{
+			Kind:    yaml.DocumentNode,
+			Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}},
+		}
+	}
+	return doc, nil
+}
+
+// child returns the child node of the key. It returns -1 as the index if the
+// node does not have the key.
+func child(n *yaml.Node, key string) (*yaml.Node, int) {
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value == key {
+				return n.Content[i+1], i
+			}
+		}
+	case yaml.SequenceNode:
+		i, err := strconv.Atoi(key)
+		if err == nil && i >= 0 && i < len(n.Content) {
+			return n.Content[i], i
+		}
+	case yaml.AliasNode:
+		return child(n.Alias, key)
+	}
+	return nil, -1
+}
+
+func (yamlEditor) get(data []byte, section string, key string) (interface{}, bool, error) {
+	doc, err := parseYAML(data)
+	if err != nil {
+		return nil, false, err
+	}
+	n := doc.Content[0]
+	for _, k := range splitKey(key) {
+		if n, _ = child(n, k); n == nil {
+			return nil, false, nil
+		}
+	}
+	var v interface{}
+	if err := n.Decode(&v); err != nil {
+		return nil, false, err
+	}
+	return v, true, nil
+}
+
+func (yamlEditor) set(data []byte, section string, key string, value interface{}) ([]byte, error) {
+	doc, err := parseYAML(data)
+	if err != nil {
+		return nil, err
+	}
+	v := &yaml.Node{}
+	if err := v.Encode(yamlValue(value)); err != nil {
+		return nil, err
+	}
+
+	n := doc.Content[0]
+	keys := splitKey(key)
+	for i, k := range keys {
+		c, _ := child(n, k)
+		if c != nil {
+			n = c
+			continue
+		}
+		if n.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf(`can not set the key "%s": %q is not in a mapping`, key, k)
+		}
+		c = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		if i == len(keys)-1 {
+			c = v
+		}
+		n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: k}, c)
+		n = c
+	}
+	if n != v {
+		v.HeadComment, v.LineComment, v.FootComment = n.HeadComment, n.LineComment, n.FootComment
+		*n = *v
+	}
+	return encodeYAML(doc, data)
+}
+
+func (yamlEditor) remove(data []byte, section string, key string) ([]byte, error) {
+	doc, err := parseYAML(data)
+	if err != nil {
+		return nil, err
+	}
+	n := doc.Content[0]
+	keys := splitKey(key)
+	for _, k := range keys[:len(keys)-1] {
+		if n, _ = child(n, k); n == nil {
+			return data, nil
+		}
+	}
+	if n.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf(`can not remove the key "%s" which is not in a mapping`, key)
+	}
+	_, i := child(n, keys[len(keys)-1])
+	if i < 0 {
+		return data, nil
+	}
+	n.Content = append(n.Content[:i], n.Content[i+2:]...)
+	return encodeYAML(doc, data)
+}
+
+// encodeYAML encodes the document in the indent of the original data.
+func encodeYAML(doc *yaml.Node, data []byte) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	enc := yaml.NewEncoder(buf)
+	enc.SetIndent(yamlIndent(data))
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// yamlIndent returns the smallest indent of the data, which is two spaces by
+// default.
+func yamlIndent(data []byte) int {
+	indent := 0
+	for _, l := range strings.Split(string(data), "\n") {
+		t := strings.TrimLeft(l, " ")
+		if t == "" || strings.HasPrefix(t, "#") || strings.HasPrefix(t, "- ") {
+			continue
+		}
+		if n := len(l) - len(t); n > 0 && (indent == 0 || n < indent) {
+			indent = n
+		}
+	}
+	if indent == 0 {
+		return 2
+	}
+	return indent
+}
@@ -0,0 +1,307 @@
+/*
+Package cron implements the states of the cron jobs.
+
+The jobs are written in the files under /etc/cron.d, or in the crontab of the
+user. Each job which is managed by opit is the block of the lines beginning
+with the marker comment:
+
+	# opit: backup
+	MAILTO=admin@example.com
+	0 3 * * * root /usr/local/bin/backup
+
+The block has the environment variables of the job, and ends at the schedule
+line of the job. The lines out of the blocks are not modified. Note that cron
+applies the environment variables to all of the following lines.
+
+Following states are provided:
+
+	- Job     ... the job is in the table
+	- Absence ... the job is not in the table
+*/
+package cron
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/harukasan/orchestra-pit/state"
+	"github.com/harukasan/orchestra-pit/state/exec"
+)
+
+// CronDir specifies the directory of the cron tables of the system.
+var CronDir = "/etc/cron.d"
+
+// CrontabPath specifies the path of crontab command.
+var CrontabPath = "/usr/bin/crontab"
+
+// Runner specifies the CommandRunner to run crontab command.
+var Runner = exec.DefaultRunner
+
+// Marker is the prefix of the comment which begins the block of the job.
+const Marker = "# opit: "
+
+var envPattern = regexp.MustCompile(`^\s*[A-Za-z_][A-Za-z0-9_]*\s*=`)
+
+// Entry represents the job in the cron table. User is the user to run the
+// command, which is written in the tables of the system.
+type Entry struct {
+	Minute  string
+	Hour    string
+	Day     string
+	Month   string
+	Weekday string
+	User    string
+	Command string
+	Env     map[string]string
+}
+
+// Table specifies the cron table. If File is not empty, it is the path of the
+// table of the system, otherwise the table is the crontab of User.
+type Table struct {
+	File string
+	User string
+}
+
+func (t Table) system() bool {
+	return t.File != ""
+}
+
+// read returns the lines of the table. The table which does not exist has no
+// lines.
+func (t Table) read() ([]string, error) {
+	var data []byte
+	if t.system() {
+		b, err := ioutil.ReadFile(state.RootPath(t.File))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		data = b
+	} else {
+		if state.IsAlternateRoot() {
+			return nil, errors.New("can not manage the crontab under the alternate root")
+		}
+		stdout, stderr, err := Runner.Run(exec.Command(CrontabPath, "-l", "-u", t.User))
+		if _, ok := err.(*exec.ExitError); ok && bytes.Contains(stderr, []byte("no crontab for")) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		data = stdout
+	}
+	s := strings.TrimSuffix(string(data), "\n")
+	if s == "" {
+		return nil, nil
+	}
+	return strings.Split(s, "\n"), nil
+}
+
+// write replaces the table with the lines.
+func (t Table) write(lines []string) error {
+	var data []byte
+	if len(lines) > 0 {
+		data = []byte(strings.Join(lines, "\n") + "\n")
+	}
+	if !t.system() {
+		cmd := exec.Command(CrontabPath, "-u", t.User, "-")
+		cmd.Stdin = bytes.NewReader(data)
+		_, _, err := Runner.Run(cmd)
+		return err
+	}
+
+	name := state.RootPath(t.File)
+	tmp, err := ioutil.TempFile(filepath.Dir(name), "."+filepath.Base(name))
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	// cron ignores the tables which are writable by the group or the others.
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// findBlock returns the range of the block of the named job in the lines. It
+// returns -1 if the job is not found.
+func findBlock(lines []string, name string) (start int, end int) {
+	for i, l := range lines {
+		if strings.TrimSpace(l) != Marker+name {
+			continue
+		}
+		end = i + 1
+		for end < len(lines) && envPattern.MatchString(lines[end]) {
+			end++
+		}
+		if end < len(lines) {
+			end++
+		}
+		return i, end
+	}
+	return -1, -1
+}
+
+// lines returns the lines of the block of the named job. The environment
+// variables are sorted by the names.
+func (e *Entry) lines(name string, system bool) []string {
+	lines := []string{Marker + name}
+	keys := []string{}
+	for k := range e.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		lines = append(lines, k+"="+e.Env[k])
+	}
+	fields := []string{e.Minute, e.Hour, e.Day, e.Month, e.Weekday}
+	if system {
+		fields = append(fields, e.User)
+	}
+	fields = append(fields, e.Command)
+	return append(lines, strings.Join(fields, " "))
+}
+
+// parseBlock parses the lines of the block of the job.
+func parseBlock(lines []string, system bool) (*Entry, error) {
+	e := &Entry{Env: map[string]string{}}
+	if len(lines) < 2 {
+		return nil, errors.New("the job has no schedule")
+	}
+	for _, l := range lines[1 : len(lines)-1] {
+		kv := strings.SplitN(l, "=", 2)
+		e.Env[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	n := 6
+	if system {
+		n = 7
+	}
+	fields := strings.Fields(lines[len(lines)-1])
+	if len(fields) < n {
+		return nil, fmt.Errorf("can not parse the schedule: %s", lines[len(lines)-1])
+	}
+	e.Minute, e.Hour, e.Day, e.Month, e.Weekday = fields[0], fields[1], fields[2], fields[3], fields[4]
+	if system {
+		e.User = fields[5]
+	}
+	e.Command = strings.Join(fields[n-1:], " ")
+	return e, nil
+}
+
+// equal returns whether the entries are the same in the table.
+func (e *Entry) equal(o *Entry, system bool) bool {
+	if e.Minute != o.Minute || e.Hour != o.Hour || e.Day != o.Day ||
+		e.Month != o.Month || e.Weekday != o.Weekday ||
+		strings.Join(strings.Fields(e.Command), " ") != o.Command {
+		return false
+	}
+	if system && e.User != o.User {
+		return false
+	}
+	if len(e.Env) != len(o.Env) {
+		return false
+	}
+	for k, v := range e.Env {
+		if ov, ok := o.Env[k]; !ok || ov != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Job is a state that the job is in the table.
+//
+// Name specifies the name of the job, which is written in the marker comment.
+// Entry specifies the schedule and the command of the job.
+type Job struct {
+	Name  string
+	Table Table
+	Entry Entry
+}
+
+// Apply tries to write the job into the table. The block of the job is
+// replaced if the table has it, otherwise the block is appended.
+func (s *Job) Apply() error {
+	lines, err := s.Table.read()
+	if err != nil {
+		return err
+	}
+	block := s.Entry.lines(s.Name, s.Table.system())
+	start, end := findBlock(lines, s.Name)
+	if start < 0 {
+		lines = append(lines, block...)
+	} else {
+		lines = append(lines[:start], append(block, lines[end:]...)...)
+	}
+	return s.Table.write(lines)
+}
+
+// Test tests whether the table has the same job.
+func (s *Job) Test() error {
+	lines, err := s.Table.read()
+	if err != nil {
+		return err
+	}
+	start, end := findBlock(lines, s.Name)
+	if start < 0 {
+		return fmt.Errorf(`the job "%s" is not found`, s.Name)
+	}
+	e, err := parseBlock(lines[start:end], s.Table.system())
+	if err != nil {
+		return err
+	}
+	if !s.Entry.equal(e, s.Table.system()) {
+		return fmt.Errorf(`the job "%s" is different from the requested`, s.Name)
+	}
+	return nil
+}
+
+// Absence is a state that the job is not in the table.
+type Absence struct {
+	Name  string
+	Table Table
+}
+
+// Apply tries to remove the block of the job from the table.
+func (s *Absence) Apply() error {
+	lines, err := s.Table.read()
+	if err != nil {
+		return err
+	}
+	start, end := findBlock(lines, s.Name)
+	if start < 0 {
+		return nil
+	}
+	return s.Table.write(append(lines[:start], lines[end:]...))
+}
+
+// Test tests whether the table does not have the job.
+func (s *Absence) Test() error {
+	lines, err := s.Table.read()
+	if err != nil {
+		return err
+	}
+	if start, _ := findBlock(lines, s.Name); start >= 0 {
+		return fmt.Errorf(`the job "%s" exists`, s.Name)
+	}
+	return nil
+}
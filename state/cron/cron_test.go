@@ -0,0 +1,100 @@
+package cron_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/harukasan/orchestra-pit/state/cron"
+	"github.com/harukasan/orchestra-pit/state/exec/testutil"
+)
+
+func TestJobInFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cron_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "backup")
+	orig := "SHELL=/bin/sh\n30 1 * * * root /usr/local/bin/cleanup\n# opit: backup\nMAILTO=root\n0 2 * * * root /usr/local/bin/backup\n*/5 * * * * www-data /usr/local/bin/poll\n"
+	if err := ioutil.WriteFile(name, []byte(orig), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	table := cron.Table{File: name}
+	s := &cron.Job{
+		Name:  "backup",
+		Table: table,
+		Entry: cron.Entry{
+			Minute: "0", Hour: "2", Day: "*", Month: "*", Weekday: "*",
+			User:    "root",
+			Command: "/usr/local/bin/backup",
+			Env:     map[string]string{"MAILTO": "root"},
+		},
+	}
+	if err := s.Test(); err != nil {
+		t.Errorf("got error for the same job: %v", err)
+	}
+
+	s.Entry.Hour = "3"
+	s.Entry.Env = nil
+	if err := s.Test(); err == nil {
+		t.Errorf("got no error for the different job")
+	}
+	if err := s.Apply(); err != nil {
+		t.Fatalf("got error on apply: %v", err)
+	}
+	if err := s.Test(); err != nil {
+		t.Errorf("got error after apply: %v", err)
+	}
+	expected := "SHELL=/bin/sh\n30 1 * * * root /usr/local/bin/cleanup\n# opit: backup\n0 3 * * * root /usr/local/bin/backup\n*/5 * * * * www-data /usr/local/bin/poll\n"
+	if data, _ := ioutil.ReadFile(name); string(data) != expected {
+		t.Errorf("got table %q, expected %q", data, expected)
+	}
+
+	absence := &cron.Absence{Name: "backup", Table: table}
+	if err := absence.Test(); err == nil {
+		t.Errorf("got no error for the existing job")
+	}
+	if err := absence.Apply(); err != nil {
+		t.Fatalf("got error on apply: %v", err)
+	}
+	if err := absence.Test(); err != nil {
+		t.Errorf("got error after apply: %v", err)
+	}
+	expected = "SHELL=/bin/sh\n30 1 * * * root /usr/local/bin/cleanup\n*/5 * * * * www-data /usr/local/bin/poll\n"
+	if data, _ := ioutil.ReadFile(name); string(data) != expected {
+		t.Errorf("got table %q, expected %q", data, expected)
+	}
+}
+
+func TestJobInCrontab(t *testing.T) {
+	r := testutil.NewFakeRunner()
+	r.On("/usr/bin/crontab", "-l", "-u", "alice").Return("", "no crontab for alice\n", 1)
+	r.On("/usr/bin/crontab", "-u", "alice", "-").Return("", "", 0)
+	orig := cron.Runner
+	cron.Runner = r
+	defer func() { cron.Runner = orig }()
+
+	s := &cron.Job{
+		Name:  "sync",
+		Table: cron.Table{User: "alice"},
+		Entry: cron.Entry{Minute: "*/10", Hour: "*", Day: "*", Month: "*", Weekday: "*", User: "alice", Command: "rsync -a src dst"},
+	}
+	if err := s.Test(); err == nil {
+		t.Errorf("got no error for the empty crontab")
+	}
+	if err := s.Apply(); err != nil {
+		t.Fatalf("got error on apply: %v", err)
+	}
+	last := r.Calls[len(r.Calls)-1]
+	if last.Stdin != "# opit: sync\n*/10 * * * * rsync -a src dst\n" {
+		t.Errorf("got crontab %q", last.Stdin)
+	}
+
+	r.On("/usr/bin/crontab", "-l", "-u", "alice").Return(last.Stdin, "", 0)
+	if err := s.Test(); err != nil {
+		t.Errorf("got error for the installed job: %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2015 MICHII Shunsuke. All rights reserved.
+
+package exec
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// CommandRunner is interface to run the external commands.
+//
+// Run starts the command and waits for it to complete. It returns the contents
+// of the standard output and the standard error of the command. If the command
+// exits with non-zero status, Run returns an *ExitError.
+//
+// The packages which execute the external commands have the Runner variable
+// of CommandRunner, so the commands can be replaced in the tests.
+type CommandRunner interface {
+	Run(c *Cmd) (stdout []byte, stderr []byte, err error)
+}
+
+// ExitError reports that the command exits with non-zero status.
+type ExitError struct {
+	Args     []string
+	ExitCode int
+	Stderr   []byte
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("%s: exit status %d", strings.Join(e.Args, " "), e.ExitCode)
+}
+
+// HostRunner is a CommandRunner which runs the commands on the host.
+type HostRunner struct{}
+
+// Run runs the command on the host.
+func (r *HostRunner) Run(c *Cmd) ([]byte, []byte, error) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	if c.Stdout == nil {
+		c.Stdout = stdout
+	}
+	if c.Stderr == nil {
+		c.Stderr = stderr
+	}
+	err := c.Cmd.Run()
+	if e, ok := err.(*exec.ExitError); ok {
+		return stdout.Bytes(), stderr.Bytes(), &ExitError{
+			Args:     c.Args,
+			ExitCode: e.ExitCode(),
+			Stderr:   stderr.Bytes(),
+		}
+	}
+	return stdout.Bytes(), stderr.Bytes(), err
+}
+
+// DefaultRunner is the CommandRunner which is used by default.
+var DefaultRunner CommandRunner = &HostRunner{}
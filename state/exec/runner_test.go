@@ -0,0 +1,66 @@
+// Copyright 2015 MICHII Shunsuke. All rights reserved.
+
+package exec_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/harukasan/orchestra-pit/state/exec"
+	"github.com/harukasan/orchestra-pit/state/exec/testutil"
+)
+
+func TestHostRunner(t *testing.T) {
+	r := &exec.HostRunner{}
+
+	out, _, err := r.Run(exec.Command("echo", "hello"))
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if got := string(out); got != "hello\n" {
+		t.Errorf("got %q, expected %q", got, "hello\n")
+	}
+
+	_, _, err = r.Run(exec.Command("sh", "-c", "exit 3"))
+	e, ok := err.(*exec.ExitError)
+	if !ok {
+		t.Fatalf("got %v, expected an ExitError", err)
+	}
+	if e.ExitCode != 3 {
+		t.Errorf("got exit status %d, expected 3", e.ExitCode)
+	}
+}
+
+func TestFakeRunner(t *testing.T) {
+	r := testutil.NewFakeRunner()
+	r.On("/bin/cmd", "a").Return("first", "", 0)
+	r.On("/bin/cmd", "a").Return("second", "failed", 1)
+
+	out, _, err := r.Run(exec.Command("/bin/cmd", "a"))
+	if err != nil || string(out) != "first" {
+		t.Errorf("got %q, %v, expected the first result", out, err)
+	}
+	for i := 0; i < 2; i++ {
+		out, stderr, err := r.Run(exec.Command("/bin/cmd", "a"))
+		if err == nil || string(out) != "second" || string(stderr) != "failed" {
+			t.Errorf("got %q, %q, %v, expected the second result", out, stderr, err)
+		}
+	}
+
+	c := exec.Command("/bin/cmd", "b")
+	c.Stdin = strings.NewReader("input")
+	_, _, err = r.Run(c)
+	if e, ok := err.(*exec.ExitError); !ok || e.ExitCode != 127 {
+		t.Errorf("got %v, expected exit status 127 for the unexpected command", err)
+	}
+
+	if got := len(r.Calls); got != 4 {
+		t.Fatalf("got %d calls, expected 4", got)
+	}
+	if r.Calls[3].Stdin != "input" {
+		t.Errorf("got Stdin %q, expected %q", r.Calls[3].Stdin, "input")
+	}
+	if got := r.Commands()[3]; got != "/bin/cmd b" {
+		t.Errorf("got %q, expected %q", got, "/bin/cmd b")
+	}
+}
@@ -0,0 +1,148 @@
+/*
+Package testutil provides the utility to test the packages which execute the
+external commands without running the commands.
+*/
+package testutil
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	"github.com/harukasan/orchestra-pit/state/exec"
+)
+
+// FakeRunner is a CommandRunner which records the commands and replays the
+// scripted results instead of running the commands.
+//
+// The results are registered by On. If several results are registered for the
+// same command, they are replayed in the registered order, and the last result
+// is replayed repeatedly. The command which has no results fails with the exit
+// status 127.
+type FakeRunner struct {
+	mu      sync.Mutex
+	scripts []*Script
+
+	// Calls records the executed commands in order.
+	Calls []Call
+}
+
+// Script describes the result of the command.
+type Script struct {
+	Args     []string
+	Stdout   string
+	Stderr   string
+	ExitCode int
+	used     bool
+}
+
+// Call describes the executed command.
+type Call struct {
+	Args  []string
+	Env   []string
+	Stdin string
+}
+
+// String returns the command line of the call.
+func (c Call) String() string {
+	return strings.Join(c.Args, " ")
+}
+
+// NewFakeRunner returns a new FakeRunner.
+func NewFakeRunner() *FakeRunner {
+	return &FakeRunner{}
+}
+
+// On registers the result of the command which has the given arguments. The
+// arguments include the command name.
+func (r *FakeRunner) On(args ...string) *Script {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := &Script{Args: args}
+	r.scripts = append(r.scripts, s)
+	return s
+}
+
+// Return sets the standard output, the standard error and the exit status of
+// the command.
+func (s *Script) Return(stdout string, stderr string, code int) *Script {
+	s.Stdout = stdout
+	s.Stderr = stderr
+	s.ExitCode = code
+	return s
+}
+
+// Run records the command and returns the registered result.
+func (r *FakeRunner) Run(c *exec.Cmd) ([]byte, []byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	call := Call{
+		Args: c.Args,
+		Env:  c.Env,
+	}
+	if c.Stdin != nil {
+		b, err := ioutil.ReadAll(c.Stdin)
+		if err != nil {
+			return nil, nil, err
+		}
+		call.Stdin = string(b)
+	}
+	r.Calls = append(r.Calls, call)
+
+	s := r.match(c.Args)
+	if s == nil {
+		return nil, nil, &exec.ExitError{
+			Args:     c.Args,
+			ExitCode: 127,
+			Stderr:   []byte(fmt.Sprintf("unexpected command: %s", call)),
+		}
+	}
+	if s.ExitCode != 0 {
+		return []byte(s.Stdout), []byte(s.Stderr), &exec.ExitError{
+			Args:     c.Args,
+			ExitCode: s.ExitCode,
+			Stderr:   []byte(s.Stderr),
+		}
+	}
+	return []byte(s.Stdout), []byte(s.Stderr), nil
+}
+
+func (r *FakeRunner) match(args []string) *Script {
+	var last *Script
+	for _, s := range r.scripts {
+		if !equal(s.Args, args) {
+			continue
+		}
+		if !s.used {
+			s.used = true
+			return s
+		}
+		last = s
+	}
+	return last
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Commands returns the command lines of the recorded calls.
+func (r *FakeRunner) Commands() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cmds := make([]string, len(r.Calls))
+	for i, c := range r.Calls {
+		cmds[i] = c.String()
+	}
+	return cmds
+}
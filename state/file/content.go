@@ -0,0 +1,105 @@
+package file
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/harukasan/orchestra-pit/state"
+)
+
+// Content manages the file whose content is the given bytes.
+//
+// Name specifies the requesting file name. Content keeps the content of the
+// file to the Content value. The file is replaced atomically, and the new file
+// is created with the mode 0644.
+//
+// If the Backup value is not empty, the original file is copied to the backup
+// file before the file is replaced.
+type Content struct {
+	Name    string
+	Content []byte
+	Backup  string
+}
+
+// Apply tries to write the content to the file. The mode of the existing file
+// is kept.
+func (s *Content) Apply() error {
+	FileInfoCache.Lock()
+	defer FileInfoCache.ClearAndUnlock(state.RootPath(s.Name))
+	return writeFile(s.Name, s.Content, s.Backup)
+}
+
+// Test tests whether the file has the same content.
+func (s *Content) Test() error {
+	content, err := ioutil.ReadFile(state.RootPath(s.Name))
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(content, s.Content) {
+		return errors.New("content of the file is different from the requested")
+	}
+	return nil
+}
+
+// writeFile replaces the named file with the data atomically, see
+// replaceFile.
+func writeFile(name string, data []byte, backup string) error {
+	return replaceFile(name, backup, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// replaceFile replaces the named file atomically with the content written by
+// the write function. The mode and the owner of the existing file are kept,
+// and the new file has the mode 0644. If backup is not empty, the existing
+// file is copied to the backup file. If write fails, the file is not changed.
+func replaceFile(name string, backup string, write func(w io.Writer) error) error {
+	name = state.RootPath(name)
+	mode := os.FileMode(0644)
+	uid, gid := -1, -1
+	if info, err := os.Stat(name); err == nil {
+		mode = info.Mode().Perm()
+		uid, gid = fileOwner(info)
+		if backup != "" {
+			orig, err := ioutil.ReadFile(name)
+			if err != nil {
+				return err
+			}
+			if err := ioutil.WriteFile(state.RootPath(backup), orig, mode); err != nil {
+				return err
+			}
+		}
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(name), "."+filepath.Base(name))
+	if err != nil {
+		return err
+	}
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chown(tmp.Name(), uid, gid); err != nil && !os.IsPermission(err) {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package file_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/harukasan/orchestra-pit/state/file"
+)
+
+func TestContent(t *testing.T) {
+	root, cleanup := withRoot(t)
+	defer cleanup()
+	name := path.Join(root, "motd")
+
+	s := &file.Content{Name: "/motd", Content: []byte("hello\n")}
+	if err := s.Test(); err == nil {
+		t.Errorf("got no error for the missing file")
+	}
+	if err := s.Apply(); err != nil {
+		t.Fatalf("got error on apply: %v", err)
+	}
+	if err := s.Test(); err != nil {
+		t.Errorf("got error on test: %v", err)
+	}
+	if info, err := os.Stat(name); err != nil || info.Mode().Perm() != 0644 {
+		t.Errorf("got %v, %v, expected the mode 0644", info, err)
+	}
+
+	if err := os.Chmod(name, 0600); err != nil {
+		t.Fatal(err)
+	}
+	s.Content = []byte("bye\n")
+	if err := s.Test(); err == nil {
+		t.Errorf("got no error for the different content")
+	}
+	if err := s.Apply(); err != nil {
+		t.Fatalf("got error on apply: %v", err)
+	}
+	if data, _ := ioutil.ReadFile(name); string(data) != "bye\n" {
+		t.Errorf("got content %q", data)
+	}
+	if info, err := os.Stat(name); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("got %v, %v, expected the mode to be kept", info, err)
+	}
+}
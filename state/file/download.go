@@ -0,0 +1,182 @@
+package file
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/harukasan/orchestra-pit/opit/logger"
+	"github.com/harukasan/orchestra-pit/state"
+)
+
+// CacheDir specifies the directory to cache the downloaded files by their
+// checksums. The cache is on the host which runs opit, even if the root
+// directory is changed. If the directory can not be created, e.g. opit does not
+// run as root, the files are downloaded without the cache.
+var CacheDir = "/var/cache/opit"
+
+// HTTPClient specifies the client to download the files. The download fails
+// if it does not finish in the timeout.
+var HTTPClient = &http.Client{Timeout: 10 * time.Minute}
+
+// ParseChecksum parses the checksum in the form of "sha256:<hex>", and returns
+// the hex digest in lower case.
+func ParseChecksum(checksum string) (string, error) {
+	c := strings.SplitN(checksum, ":", 2)
+	if len(c) != 2 || c[0] != "sha256" {
+		return "", fmt.Errorf(`invalid checksum "%s": it must be in the form of sha256:<hex>`, checksum)
+	}
+	sum := strings.ToLower(c[1])
+	if b, err := hex.DecodeString(sum); err != nil || len(b) != sha256.Size {
+		return "", fmt.Errorf(`invalid checksum "%s": it is not a SHA-256 digest`, checksum)
+	}
+	return sum, nil
+}
+
+// Download manages the file whose content is downloaded from the URL.
+//
+// Name specifies the file name. URL specifies the URL of HTTP or HTTPS to
+// download the file. Checksum specifies the checksum of the file in the form of
+// "sha256:<hex>".
+//
+// The file is downloaded to the cache directory, and is put into place
+// atomically after the checksum is verified. The cached file is used instead
+// of downloading, if it has the same checksum.
+//
+// If the Backup value is not empty, the original file is copied to the backup
+// file before the file is replaced.
+type Download struct {
+	Name     string
+	URL      string
+	Checksum string
+	Backup   string
+}
+
+// Apply tries to put the downloaded file into place.
+func (s *Download) Apply() error {
+	sum, err := ParseChecksum(s.Checksum)
+	if err != nil {
+		return err
+	}
+	cache := filepath.Join(CacheDir, "sha256", sum)
+	if err := verifyFile(cache, sum); err != nil {
+		logger.Debugf("downloading %s", s.URL)
+		name, cached, err := s.fetch(cache, sum)
+		if err != nil {
+			return err
+		}
+		if !cached {
+			defer os.Remove(name)
+		}
+		cache = name
+	} else {
+		logger.Debugf("using the cached file %s", cache)
+	}
+
+	FileInfoCache.Lock()
+	defer FileInfoCache.ClearAndUnlock(state.RootPath(s.Name))
+	return replaceFile(s.Name, s.Backup, func(w io.Writer) error {
+		r, err := os.Open(cache)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		_, err = io.Copy(w, r)
+		return err
+	})
+}
+
+// Test tests whether the file has the checksum.
+func (s *Download) Test() error {
+	sum, err := ParseChecksum(s.Checksum)
+	if err != nil {
+		return err
+	}
+	return verifyFile(state.RootPath(s.Name), sum)
+}
+
+// fetch downloads the file, and verifies the checksum. It returns the name of
+// the downloaded file and whether it is stored in the cache. If the cache
+// directory can not be used, the file is downloaded into a temporary file,
+// which the caller removes.
+func (s *Download) fetch(cache string, sum string) (string, bool, error) {
+	tmp, err := cacheTempFile(cache, sum)
+	cached := err == nil
+	if !cached {
+		logger.Debugf("can not use the cache directory, download without the cache: %s", err)
+		tmp, err = ioutil.TempFile("", "opit_download_")
+		if err != nil {
+			return "", false, err
+		}
+	}
+	if err := s.download(tmp, sum); err != nil {
+		os.Remove(tmp.Name())
+		return "", false, err
+	}
+	if !cached {
+		return tmp.Name(), false, nil
+	}
+	if err := os.Rename(tmp.Name(), cache); err != nil {
+		os.Remove(tmp.Name())
+		return "", false, err
+	}
+	return cache, true, nil
+}
+
+// cacheTempFile creates the temporary file in the directory of the cache.
+func cacheTempFile(cache string, sum string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(cache), 0755); err != nil {
+		return nil, err
+	}
+	return ioutil.TempFile(filepath.Dir(cache), "."+sum)
+}
+
+// download writes the content of the URL into the file, and closes it. It
+// returns an error if the checksum of the content is not sum.
+func (s *Download) download(f *os.File, sum string) error {
+	defer f.Close()
+	resp, err := HTTPClient.Get(s.URL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("can not download %s: %s", s.URL, resp.Status)
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(f, h), resp.Body); err != nil {
+		return fmt.Errorf("can not download %s: %s", s.URL, err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != sum {
+		return fmt.Errorf("the checksum of %s is sha256:%s, expected sha256:%s", s.URL, got, sum)
+	}
+	return nil
+}
+
+// verifyFile tests whether the SHA-256 digest of the file is sum.
+func verifyFile(name string, sum string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	if hex.EncodeToString(h.Sum(nil)) != sum {
+		return fmt.Errorf("the checksum of %s is different from the requested", name)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package file_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/harukasan/orchestra-pit/state/file"
+)
+
+func TestDownload(t *testing.T) {
+	root, cleanup := withRoot(t)
+	defer cleanup()
+	cache, err := ioutil.TempDir("", "file_test_cache_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(cache)
+	orig := file.CacheDir
+	file.CacheDir = cache
+	defer func() { file.CacheDir = orig }()
+
+	body := "#!/bin/sh\necho hello\n"
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/hello.sh" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer ts.Close()
+
+	sum := sha256.Sum256([]byte(body))
+	s := &file.Download{
+		Name:     "/hello.sh",
+		URL:      ts.URL + "/hello.sh",
+		Checksum: "sha256:" + hex.EncodeToString(sum[:]),
+	}
+	if err := s.Test(); err == nil {
+		t.Errorf("got no error for the missing file")
+	}
+	if err := s.Apply(); err != nil {
+		t.Fatalf("got error on apply: %v", err)
+	}
+	if err := s.Test(); err != nil {
+		t.Errorf("got error on test: %v", err)
+	}
+	if data, _ := ioutil.ReadFile(path.Join(root, "hello.sh")); string(data) != body {
+		t.Errorf("got content %q", data)
+	}
+
+	// the second download is served from the cache.
+	if err := ioutil.WriteFile(path.Join(root, "hello.sh"), []byte("changed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Test(); err == nil {
+		t.Errorf("got no error for the changed file")
+	}
+	if err := s.Apply(); err != nil {
+		t.Fatalf("got error on apply: %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("got %d requests, expected 1", requests)
+	}
+
+	// the file is not changed if the checksum does not match.
+	bad := &file.Download{
+		Name:     "/hello.sh",
+		URL:      ts.URL + "/hello.sh",
+		Checksum: "sha256:" + hex.EncodeToString(make([]byte, sha256.Size)),
+	}
+	if err := bad.Apply(); err == nil {
+		t.Errorf("got no error for the checksum mismatch")
+	}
+	if err := s.Test(); err != nil {
+		t.Errorf("got error on test after the mismatch: %v", err)
+	}
+
+	missing := &file.Download{Name: "/missing", URL: ts.URL + "/missing", Checksum: s.Checksum}
+	os.RemoveAll(cache)
+	if err := missing.Apply(); err == nil {
+		t.Errorf("got no error for the missing URL")
+	}
+	if _, err := os.Stat(path.Join(root, "missing")); !os.IsNotExist(err) {
+		t.Errorf("got %v, expected the file not to be created", err)
+	}
+}
+
+func TestDownloadWithoutCache(t *testing.T) {
+	root, cleanup := withRoot(t)
+	defer cleanup()
+	// the cache directory can not be created under the regular file.
+	if err := ioutil.WriteFile(path.Join(root, "cache"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	orig := file.CacheDir
+	file.CacheDir = path.Join(root, "cache/opit")
+	defer func() { file.CacheDir = orig }()
+
+	body := "hello\n"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer ts.Close()
+
+	sum := sha256.Sum256([]byte(body))
+	s := &file.Download{Name: "/hello", URL: ts.URL + "/hello", Checksum: "sha256:" + hex.EncodeToString(sum[:])}
+	if err := s.Apply(); err != nil {
+		t.Fatalf("got error on apply: %v", err)
+	}
+	if data, _ := ioutil.ReadFile(path.Join(root, "hello")); string(data) != body {
+		t.Errorf("got content %q", data)
+	}
+}
+
+func TestParseChecksum(t *testing.T) {
+	sum := "E3B0C44298FC1C149AFBF4C8996FB92427AE41E4649B934CA495991B7852B855"
+	got, err := file.ParseChecksum("sha256:" + sum)
+	if err != nil || got != "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" {
+		t.Errorf("got %q, %v", got, err)
+	}
+	for _, c := range []string{"", sum, "md5:" + sum, "sha256:abc", "sha256:" + sum[1:] + "x"} {
+		if _, err := file.ParseChecksum(c); err == nil {
+			t.Errorf("got no error for %q", c)
+		}
+	}
+}
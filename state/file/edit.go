@@ -0,0 +1,282 @@
+package file
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/harukasan/orchestra-pit/state"
+)
+
+// DefaultMarker is the marker of the block if the marker is not specified.
+const DefaultMarker = "opit managed block"
+
+// Line manages the line in the file which is shared with the others, such as
+// the packages.
+//
+// Name specifies the file name. Line specifies the content of the line.
+//
+// Match specifies the regular expression of the lines to replace. The first
+// line which matches Match, or is the same as Line, is replaced with Line,
+// and the following matched lines are removed. If no lines match, Line is
+// appended to the file. If the file does not exist, it is created.
+//
+// If the Backup value is not empty, the original file is copied to the backup
+// file before the file is changed.
+type Line struct {
+	Name   string
+	Line   string
+	Match  string
+	Backup string
+}
+
+// Apply tries to put the line into the file.
+func (s *Line) Apply() error {
+	return editFile(s.Name, s.Backup, s.edit)
+}
+
+// Test tests whether the file has the line, and has no other matched lines.
+func (s *Line) Test() error {
+	return testFile(s.Name, s.edit, "the line is not in the file")
+}
+
+func (s *Line) edit(lines []string) ([]string, error) {
+	match, err := lineMatcher(s.Line, s.Match)
+	if err != nil {
+		return nil, err
+	}
+	result := []string{}
+	placed := false
+	for _, l := range lines {
+		if !match(l) {
+			result = append(result, l)
+			continue
+		}
+		if !placed {
+			result = append(result, s.Line)
+			placed = true
+		}
+	}
+	if !placed {
+		result = append(result, s.Line)
+	}
+	return result, nil
+}
+
+// LineAbsence manages that the file has no lines which match Match, or are
+// the same as Line.
+type LineAbsence struct {
+	Name   string
+	Line   string
+	Match  string
+	Backup string
+}
+
+// Apply tries to remove the lines from the file.
+func (s *LineAbsence) Apply() error {
+	return editFile(s.Name, s.Backup, s.edit)
+}
+
+// Test tests whether the file has no lines to remove. The file which does not
+// exist has no lines.
+func (s *LineAbsence) Test() error {
+	if _, err := os.Stat(state.RootPath(s.Name)); os.IsNotExist(err) {
+		return nil
+	}
+	return testFile(s.Name, s.edit, "the line is in the file")
+}
+
+func (s *LineAbsence) edit(lines []string) ([]string, error) {
+	match, err := lineMatcher(s.Line, s.Match)
+	if err != nil {
+		return nil, err
+	}
+	result := []string{}
+	for _, l := range lines {
+		if !match(l) {
+			result = append(result, l)
+		}
+	}
+	return result, nil
+}
+
+// lineMatcher returns the function which matches the lines to edit.
+func lineMatcher(line string, match string) (func(string) bool, error) {
+	if match == "" {
+		return func(l string) bool { return l == line }, nil
+	}
+	re, err := regexp.Compile(match)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression %q: %s", match, err)
+	}
+	return func(l string) bool { return l == line || re.MatchString(l) }, nil
+}
+
+// Block manages the block of the lines in the file which is shared with the
+// others.
+//
+// Name specifies the file name. Content specifies the lines of the block.
+//
+// Marker specifies the name of the block. The block is delimited by the
+// comments "# BEGIN <marker>" and "# END <marker>". If the file does not have
+// the block, the block is appended to the file.
+//
+// If the Backup value is not empty, the original file is copied to the backup
+// file before the file is changed.
+type Block struct {
+	Name    string
+	Content string
+	Marker  string
+	Backup  string
+}
+
+// Apply tries to put the block into the file.
+func (s *Block) Apply() error {
+	return editFile(s.Name, s.Backup, s.edit)
+}
+
+// Test tests whether the file has the block of the same content.
+func (s *Block) Test() error {
+	return testFile(s.Name, s.edit, "the block is different from the requested")
+}
+
+func (s *Block) edit(lines []string) ([]string, error) {
+	begin, end := markers(s.Marker)
+	block := []string{begin}
+	if s.Content != "" {
+		block = append(block, strings.Split(strings.TrimSuffix(s.Content, "\n"), "\n")...)
+	}
+	block = append(block, end)
+
+	start, stop, err := findBlock(lines, s.Marker)
+	if err != nil {
+		return nil, err
+	}
+	if start < 0 {
+		return append(lines, block...), nil
+	}
+	result := append([]string{}, lines[:start]...)
+	result = append(result, block...)
+	return append(result, lines[stop:]...), nil
+}
+
+// BlockAbsence manages that the file does not have the block of Marker.
+type BlockAbsence struct {
+	Name   string
+	Marker string
+	Backup string
+}
+
+// Apply tries to remove the block from the file.
+func (s *BlockAbsence) Apply() error {
+	return editFile(s.Name, s.Backup, s.edit)
+}
+
+// Test tests whether the file does not have the block. The file which does not
+// exist has no blocks.
+func (s *BlockAbsence) Test() error {
+	if _, err := os.Stat(state.RootPath(s.Name)); os.IsNotExist(err) {
+		return nil
+	}
+	return testFile(s.Name, s.edit, "the block is in the file")
+}
+
+func (s *BlockAbsence) edit(lines []string) ([]string, error) {
+	start, stop, err := findBlock(lines, s.Marker)
+	if err != nil || start < 0 {
+		return lines, err
+	}
+	return append(append([]string{}, lines[:start]...), lines[stop:]...), nil
+}
+
+func markers(marker string) (begin string, end string) {
+	if marker == "" {
+		marker = DefaultMarker
+	}
+	return "# BEGIN " + marker, "# END " + marker
+}
+
+// findBlock returns the range of the block including the markers. It returns
+// -1 if the lines do not have the block.
+func findBlock(lines []string, marker string) (start int, stop int, err error) {
+	begin, end := markers(marker)
+	start = -1
+	for i, l := range lines {
+		switch strings.TrimSpace(l) {
+		case begin:
+			if start < 0 {
+				start = i
+			}
+		case end:
+			if start >= 0 {
+				return start, i + 1, nil
+			}
+		}
+	}
+	if start >= 0 {
+		return 0, 0, fmt.Errorf("the block %q is not closed", marker)
+	}
+	return -1, -1, nil
+}
+
+// readLines reads the lines of the named file. The file which does not exist
+// has no lines.
+func readLines(name string) ([]string, error) {
+	data, err := ioutil.ReadFile(state.RootPath(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+	s := strings.TrimSuffix(string(data), "\n")
+	if s == "" {
+		return []string{}, nil
+	}
+	return strings.Split(s, "\n"), nil
+}
+
+func joinLines(lines []string) []byte {
+	if len(lines) == 0 {
+		return []byte{}
+	}
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
+
+// testFile tests whether the edit does not change the named file.
+func testFile(name string, edit func([]string) ([]string, error), msg string) error {
+	if _, err := FileInfoCache.Stat(state.RootPath(name)); err != nil {
+		return err
+	}
+	lines, err := readLines(name)
+	if err != nil {
+		return err
+	}
+	result, err := edit(lines)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(joinLines(lines), joinLines(result)) {
+		return fmt.Errorf("%s", msg)
+	}
+	return nil
+}
+
+// editFile edits the lines of the named file, and replaces the file with the
+// result atomically.
+func editFile(name string, backup string, edit func([]string) ([]string, error)) error {
+	lines, err := readLines(name)
+	if err != nil {
+		return err
+	}
+	result, err := edit(lines)
+	if err != nil {
+		return err
+	}
+	FileInfoCache.Lock()
+	defer FileInfoCache.ClearAndUnlock(state.RootPath(name))
+	return writeFile(name, joinLines(result), backup)
+}
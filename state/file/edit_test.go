@@ -0,0 +1,84 @@
+package file_test
+
+import (
+	"io/ioutil"
+	"path"
+	"testing"
+
+	"github.com/harukasan/orchestra-pit/state"
+	"github.com/harukasan/orchestra-pit/state/file"
+)
+
+// applyAndTest applies the state, and tests the content of the named file.
+func applyAndTest(t *testing.T, s state.State, name string, expected string) {
+	t.Helper()
+	if err := s.Test(); err == nil {
+		t.Errorf("got no error before apply")
+	}
+	if err := s.Apply(); err != nil {
+		t.Fatalf("got error on apply: %v", err)
+	}
+	if err := s.Test(); err != nil {
+		t.Errorf("got error after apply: %v", err)
+	}
+	data, err := ioutil.ReadFile(path.Join(state.Root, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != expected {
+		t.Errorf("got %q, expected %q", data, expected)
+	}
+}
+
+func TestLine(t *testing.T) {
+	root, cleanup := withRoot(t)
+	defer cleanup()
+	config := "Port 22\n#PermitRootLogin yes\nPermitRootLogin prohibit-password\nUsePAM yes\n"
+	if err := ioutil.WriteFile(path.Join(root, "sshd_config"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	applyAndTest(t, &file.Line{
+		Name:   "/sshd_config",
+		Line:   "PermitRootLogin no",
+		Match:  "^#?PermitRootLogin",
+		Backup: "/sshd_config.orig",
+	}, "/sshd_config", "Port 22\nPermitRootLogin no\nUsePAM yes\n")
+	if data, _ := ioutil.ReadFile(path.Join(root, "sshd_config.orig")); string(data) != config {
+		t.Errorf("got backup %q, expected the original", data)
+	}
+
+	applyAndTest(t, &file.Line{Name: "/sshd_config", Line: "X11Forwarding no"},
+		"/sshd_config", "Port 22\nPermitRootLogin no\nUsePAM yes\nX11Forwarding no\n")
+
+	applyAndTest(t, &file.LineAbsence{Name: "/sshd_config", Match: "^Use"},
+		"/sshd_config", "Port 22\nPermitRootLogin no\nX11Forwarding no\n")
+
+	if err := (&file.LineAbsence{Name: "/missing", Line: "a"}).Test(); err != nil {
+		t.Errorf("got error for the missing file: %v", err)
+	}
+}
+
+func TestBlock(t *testing.T) {
+	root, cleanup := withRoot(t)
+	defer cleanup()
+	hosts := "127.0.0.1 localhost\n"
+	if err := ioutil.WriteFile(path.Join(root, "hosts"), []byte(hosts), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	applyAndTest(t, &file.Block{Name: "/hosts", Content: "192.0.2.10 web1\n"},
+		"/hosts", "127.0.0.1 localhost\n# BEGIN opit managed block\n192.0.2.10 web1\n# END opit managed block\n")
+
+	applyAndTest(t, &file.Block{Name: "/hosts", Content: "192.0.2.10 web1\n192.0.2.11 web2"},
+		"/hosts", "127.0.0.1 localhost\n# BEGIN opit managed block\n192.0.2.10 web1\n192.0.2.11 web2\n# END opit managed block\n")
+
+	applyAndTest(t, &file.BlockAbsence{Name: "/hosts"}, "/hosts", hosts)
+
+	if err := ioutil.WriteFile(path.Join(root, "broken"), []byte("# BEGIN opit managed block\na\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&file.Block{Name: "/broken", Content: "a"}).Apply(); err == nil {
+		t.Errorf("got no error for the block which is not closed")
+	}
+}
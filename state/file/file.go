@@ -13,12 +13,20 @@ Package file implements the commands to manage the state of file.
 Following states are implemented:
 
 	- Copy ... manages the file whose contents is a copy of the source file
+	- Content ... manages the file whose contents is the given bytes
+	- Download ... manages the file downloaded from the URL by its checksum
+	- Line ... manages the line in the file, and LineAbsence removes it
+	- Block ... manages the marked block in the file, and BlockAbsence removes it
   - Directory ... manages the directory existence
   - Hardlink ... manages the hard link file
   - Symlink ... manages the symbolic link file
   - Owner ... manages owner and group of the file
+  - NamedOwner ... manages owner and group of the file by the names
 	- Mode ... manages the file mode and permissions.
 
+The absolute paths of the files are resolved under state.Root, so the states
+can manage the files of the other root directory such as a disk image.
+
 */
 package file
 
@@ -28,6 +36,8 @@ import (
 	"fmt"
 	"io"
 	"os"
+
+	"github.com/harukasan/orchestra-pit/state"
 )
 
 // Copy manages the file whose content is a copy of the src file.
@@ -49,7 +59,7 @@ type Copy struct {
 // not empty, rename the file to given the backup name before the copying file.
 func (s *Copy) Apply() error {
 	FileInfoCache.Lock()
-	defer FileInfoCache.ClearAndUnlock(s.Name)
+	defer FileInfoCache.ClearAndUnlock(state.RootPath(s.Name))
 
 	r, err := os.Open(s.Src)
 	if err != nil {
@@ -57,13 +67,15 @@ func (s *Copy) Apply() error {
 	}
 	defer r.Close()
 
-	if err := os.Rename(s.Name, s.Backup); err != nil {
-		if !os.IsNotExist(err) {
-			return err
+	if s.Backup != "" {
+		if err := os.Rename(state.RootPath(s.Name), state.RootPath(s.Backup)); err != nil {
+			if !os.IsNotExist(err) {
+				return err
+			}
 		}
 	}
 
-	w, err := os.Create(s.Name)
+	w, err := os.Create(state.RootPath(s.Name))
 	if err != nil {
 		return err
 	}
@@ -77,7 +89,7 @@ func (s *Copy) Apply() error {
 
 // Test tests whether the file contains the same contents of the src file.
 func (s *Copy) Test() error {
-	dest, err := os.Open(s.Name)
+	dest, err := os.Open(state.RootPath(s.Name))
 	if err != nil {
 		return err
 	}
@@ -135,18 +147,24 @@ type Directory struct {
 	Name string
 }
 
-// Apply tries to make the named directory. If failed to make a directory, Apply
-// returns an error.
+// Apply tries to make the named directory. If the directory already exists,
+// Apply does nothing. If failed to make a directory, Apply returns an error.
 func (s *Directory) Apply() error {
 	FileInfoCache.Lock()
-	defer FileInfoCache.ClearAndUnlock(s.Name)
+	defer FileInfoCache.ClearAndUnlock(state.RootPath(s.Name))
 
-	return os.Mkdir(s.Name, 0777)
+	err := os.Mkdir(state.RootPath(s.Name), 0777)
+	if os.IsExist(err) {
+		if info, serr := os.Stat(state.RootPath(s.Name)); serr == nil && info.IsDir() {
+			return nil
+		}
+	}
+	return err
 }
 
 // Test tests whether the named file is a directory.
 func (s *Directory) Test() error {
-	info, err := FileInfoCache.Stat(s.Name)
+	info, err := FileInfoCache.Stat(state.RootPath(s.Name))
 	if err != nil {
 		return err
 	}
@@ -167,8 +185,8 @@ type Absence struct {
 // Apply tries to remove the file.
 func (s *Absence) Apply() error {
 	FileInfoCache.Lock()
-	defer FileInfoCache.ClearAndUnlock(s.Name)
-	err := os.Remove(s.Name)
+	defer FileInfoCache.ClearAndUnlock(state.RootPath(s.Name))
+	err := os.Remove(state.RootPath(s.Name))
 	if err != nil && !os.IsNotExist(err) {
 		return err
 	}
@@ -177,7 +195,7 @@ func (s *Absence) Apply() error {
 
 // Test tests whether the named file does not exists.
 func (s *Absence) Test() error {
-	_, err := FileInfoCache.Stat(s.Name)
+	_, err := FileInfoCache.Stat(state.RootPath(s.Name))
 	if err != nil {
 		if !os.IsNotExist(err) {
 			return err
@@ -0,0 +1,11 @@
+// +build !linux,!darwin,!dragonfly,!freebsd,!openbsd,!netbsd,!solaris
+
+package file
+
+import "os"
+
+// fileOwner returns -1 for the owner and the group, because they are unknown
+// on this platform.
+func fileOwner(info os.FileInfo) (uid int, gid int) {
+	return -1, -1
+}
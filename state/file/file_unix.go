@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"os"
 	"syscall"
+
+	"github.com/harukasan/orchestra-pit/state"
 )
 
 // Hardlink manages the hard link existence and where the file points to.
@@ -23,17 +25,17 @@ type Hardlink struct {
 // Apply tries to make a hardlink to src file.
 func (s *Hardlink) Apply() error {
 	FileInfoCache.Lock()
-	defer FileInfoCache.ClearAndUnlock(s.Name)
-	return os.Link(s.Src, s.Name)
+	defer FileInfoCache.ClearAndUnlock(state.RootPath(s.Name))
+	return os.Link(state.RootPath(s.Src), state.RootPath(s.Name))
 }
 
 // Test tests whether the file points to same location as the src file.
 func (s *Hardlink) Test() error {
-	destInfo, err := FileInfoCache.Stat(s.Name)
+	destInfo, err := FileInfoCache.Stat(state.RootPath(s.Name))
 	if err != nil {
 		return err
 	}
-	srcInfo, err := FileInfoCache.Stat(s.Src)
+	srcInfo, err := FileInfoCache.Stat(state.RootPath(s.Src))
 	if err != nil {
 		return err
 	}
@@ -63,14 +65,14 @@ type Symlink struct {
 // Apply tries to make a symbolic link which is linked to the Src.
 func (s *Symlink) Apply() error {
 	FileInfoCache.Lock()
-	defer FileInfoCache.ClearAndUnlock(s.Name)
+	defer FileInfoCache.ClearAndUnlock(state.RootPath(s.Name))
 
-	return os.Symlink(s.Src, s.Name)
+	return os.Symlink(s.Src, state.RootPath(s.Name))
 }
 
 // Test tests whether the file points to the Src.
 func (s *Symlink) Test() error {
-	fact, err := os.Readlink(s.Name)
+	fact, err := os.Readlink(state.RootPath(s.Name))
 	if err != nil {
 		return err
 	}
@@ -96,14 +98,14 @@ type Owner struct {
 // Apply tries to change the file owner and group.
 func (s *Owner) Apply() error {
 	FileInfoCache.Lock()
-	defer FileInfoCache.ClearAndUnlock(s.Name)
+	defer FileInfoCache.ClearAndUnlock(state.RootPath(s.Name))
 
-	return os.Chown(s.Name, int(s.Uid), int(s.Gid))
+	return os.Chown(state.RootPath(s.Name), int(s.Uid), int(s.Gid))
 }
 
 // Test tests whether the owner and group of the is requested.
 func (s *Owner) Test() error {
-	info, err := FileInfoCache.Stat(s.Name)
+	info, err := FileInfoCache.Stat(state.RootPath(s.Name))
 	if err != nil {
 		return fmt.Errorf("faild to get stat on testing file owner: %v", err)
 	}
@@ -131,9 +133,9 @@ type Mode struct {
 // Apply tries to keep the file mode to the requested mode.
 func (s *Mode) Apply() error {
 	FileInfoCache.Lock()
-	defer FileInfoCache.ClearAndUnlock(s.Name)
+	defer FileInfoCache.ClearAndUnlock(state.RootPath(s.Name))
 
-	fi, err := os.Stat(s.Name)
+	fi, err := os.Stat(state.RootPath(s.Name))
 	if err != nil {
 		return err
 	}
@@ -141,12 +143,12 @@ func (s *Mode) Apply() error {
 	if err != nil {
 		return err
 	}
-	return os.Chmod(s.Name, mode)
+	return os.Chmod(state.RootPath(s.Name), mode)
 }
 
 // Test tests whether the file mode is requested.
 func (s *Mode) Test() error {
-	fi, err := FileInfoCache.Stat(s.Name)
+	fi, err := FileInfoCache.Stat(state.RootPath(s.Name))
 	if err != nil {
 		return err
 	}
@@ -156,3 +158,77 @@ func (s *Mode) Test() error {
 	}
 	return nil
 }
+
+// NamedOwner manages owner and group of the named file by the names.
+//
+// Name specifies the file name.
+//
+// Owner and Group specifies the names or the IDs of owner and group of the
+// file. The names are looked up from the user and group databases under
+// state.Root. If Owner or Group is empty, it is not changed.
+type NamedOwner struct {
+	Name  string
+	Owner string
+	Group string
+}
+
+func (s *NamedOwner) ids() (uid int, gid int, err error) {
+	uid, gid = -1, -1
+	if s.Owner != "" {
+		id, err := LookupUID(s.Owner)
+		if err != nil {
+			return 0, 0, err
+		}
+		uid = int(id)
+	}
+	if s.Group != "" {
+		id, err := LookupGID(s.Group)
+		if err != nil {
+			return 0, 0, err
+		}
+		gid = int(id)
+	}
+	return uid, gid, nil
+}
+
+// Apply tries to change the file owner and group.
+func (s *NamedOwner) Apply() error {
+	FileInfoCache.Lock()
+	defer FileInfoCache.ClearAndUnlock(state.RootPath(s.Name))
+
+	uid, gid, err := s.ids()
+	if err != nil {
+		return err
+	}
+	return os.Chown(state.RootPath(s.Name), uid, gid)
+}
+
+// Test tests whether the owner and group of the file are requested.
+func (s *NamedOwner) Test() error {
+	uid, gid, err := s.ids()
+	if err != nil {
+		return err
+	}
+	info, err := FileInfoCache.Stat(state.RootPath(s.Name))
+	if err != nil {
+		return fmt.Errorf("faild to get stat on testing file owner: %v", err)
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		if uid >= 0 && stat.Uid != uint32(uid) {
+			return fmt.Errorf("wrong owner, requested: %s, but %d", s.Owner, stat.Uid)
+		}
+		if gid >= 0 && stat.Gid != uint32(gid) {
+			return fmt.Errorf("wrong group, requested: %s, but %d", s.Group, stat.Gid)
+		}
+	}
+	return nil
+}
+
+// fileOwner returns the owner and the group of the file. It returns -1 if they
+// are unknown.
+func fileOwner(info os.FileInfo) (uid int, gid int) {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return int(st.Uid), int(st.Gid)
+	}
+	return -1, -1
+}
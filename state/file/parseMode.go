@@ -141,8 +141,8 @@ func parseModeSymbolic(s string, base os.FileMode) (os.FileMode, error) {
 
 			switch op {
 			case '-':
-				perm = perm - (whom & mod)
-				sbits = sbits - smod
+				perm = perm &^ (whom & mod)
+				sbits = sbits &^ smod
 			case '+':
 				perm = perm | (whom & mod)
 				sbits = sbits | smod
@@ -24,6 +24,21 @@ var patterns = []testPattern{
 		base:     os.FileMode(0777),
 		expected: os.FileMode(0755),
 	},
+	testPattern{
+		input:    "go-w",
+		base:     os.FileMode(0755),
+		expected: os.FileMode(0755),
+	},
+	testPattern{
+		input:    "a-x",
+		base:     os.FileMode(0644),
+		expected: os.FileMode(0644),
+	},
+	testPattern{
+		input:    "o-t",
+		base:     os.FileMode(0755),
+		expected: os.FileMode(0755),
+	},
 	testPattern{
 		input:    "=rw,+X",
 		base:     os.FileMode(0000),
@@ -0,0 +1,104 @@
+// Copyright 2015 MICHII Shunsuke. All rights reserved.
+
+package file
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/harukasan/orchestra-pit/state"
+)
+
+// PasswdPath and GroupPath specify the file paths of the user and group
+// databases. The paths are resolved under state.Root.
+var (
+	PasswdPath = "/etc/passwd"
+	GroupPath  = "/etc/group"
+)
+
+// LookupUID returns the user ID of the named user. The user is looked up from
+// the passwd file under state.Root instead of the user database of the host.
+// If the name is a number, it is returned as the user ID.
+func LookupUID(name string) (uint32, error) {
+	return lookupID(PasswdPath, name)
+}
+
+// LookupGID returns the group ID of the named group. The group is looked up
+// from the group file under state.Root instead of the group database of the
+// host. If the name is a number, it is returned as the group ID.
+func LookupGID(name string) (uint32, error) {
+	return lookupID(GroupPath, name)
+}
+
+// User is the entry of the user in the passwd file.
+type User struct {
+	Name string
+	UID  uint32
+	GID  uint32
+	Home string
+}
+
+// LookupUser returns the named user from the passwd file under state.Root. If
+// the name is a number, the user is looked up by the user ID.
+func LookupUser(name string) (*User, error) {
+	file, err := os.Open(state.RootPath(PasswdPath))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ":")
+		if len(fields) < 6 || (fields[0] != name && fields[2] != name) {
+			continue
+		}
+		uid, err := strconv.ParseUint(fields[2], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid id of %s in %s: %v", name, PasswdPath, err)
+		}
+		gid, err := strconv.ParseUint(fields[3], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid group id of %s in %s: %v", name, PasswdPath, err)
+		}
+		return &User{Name: fields[0], UID: uint32(uid), GID: uint32(gid), Home: fields[5]}, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("%s is not found in %s", name, PasswdPath)
+}
+
+// lookupID reads the file which has the lines such as "name:x:id:...", and
+// returns the id of the named entry.
+func lookupID(db string, name string) (uint32, error) {
+	if id, err := strconv.ParseUint(name, 10, 32); err == nil {
+		return uint32(id), nil
+	}
+
+	file, err := os.Open(state.RootPath(db))
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ":")
+		if len(fields) < 3 || fields[0] != name {
+			continue
+		}
+		id, err := strconv.ParseUint(fields[2], 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("invalid id of %s in %s: %v", name, db, err)
+		}
+		return uint32(id), nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("%s is not found in %s", name, db)
+}
@@ -0,0 +1,91 @@
+package file_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/harukasan/orchestra-pit/state"
+	"github.com/harukasan/orchestra-pit/state/file"
+)
+
+// withRoot changes state.Root to the temporary directory, and returns the
+// directory and the function to restore state.Root and to remove it.
+func withRoot(t *testing.T) (string, func()) {
+	root, err := ioutil.TempDir("", "file_test_root_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := state.Root
+	state.Root = root
+	return root, func() {
+		state.Root = orig
+		os.RemoveAll(root)
+	}
+}
+
+func TestLookupID(t *testing.T) {
+	root, cleanup := withRoot(t)
+	defer cleanup()
+	if err := os.Mkdir(path.Join(root, "etc"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	passwd := "root:x:0:0:root:/root:/bin/sh\nwww-data:x:33:33:www-data:/var/www:/usr/sbin/nologin\n"
+	if err := ioutil.WriteFile(path.Join(root, "etc/passwd"), []byte(passwd), 0644); err != nil {
+		t.Fatal(err)
+	}
+	group := "root:x:0:\nadm:x:4:syslog\n"
+	if err := ioutil.WriteFile(path.Join(root, "etc/group"), []byte(group), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if uid, err := file.LookupUID("www-data"); err != nil || uid != 33 {
+		t.Errorf("LookupUID: got %d, %v, expected 33", uid, err)
+	}
+	if uid, err := file.LookupUID("1000"); err != nil || uid != 1000 {
+		t.Errorf("LookupUID: got %d, %v, expected 1000", uid, err)
+	}
+	if _, err := file.LookupUID("nobody"); err == nil {
+		t.Errorf("LookupUID: got no error when the user is not found")
+	}
+	if gid, err := file.LookupGID("adm"); err != nil || gid != 4 {
+		t.Errorf("LookupGID: got %d, %v, expected 4", gid, err)
+	}
+
+	expected := &file.User{Name: "www-data", UID: 33, GID: 33, Home: "/var/www"}
+	for _, name := range []string{"www-data", "33"} {
+		if u, err := file.LookupUser(name); err != nil || *u != *expected {
+			t.Errorf("LookupUser: got %+v, %v, expected %+v", u, err, expected)
+		}
+	}
+	if _, err := file.LookupUser("nobody"); err == nil {
+		t.Errorf("LookupUser: got no error when the user is not found")
+	}
+}
+
+func TestAlternateRoot(t *testing.T) {
+	root, cleanup := withRoot(t)
+	defer cleanup()
+	src := d.MakeDummyFile("test_root_copy_")
+
+	dir := &file.Directory{
+		Name: "/etc",
+	}
+	if err := dir.Apply(); err != nil {
+		t.Errorf("got error on apply: %v", err)
+	}
+	s := &file.Copy{
+		Name: "/etc/motd",
+		Src:  src,
+	}
+	if err := s.Apply(); err != nil {
+		t.Errorf("got error on apply: %v", err)
+	}
+	if err := s.Test(); err != nil {
+		t.Errorf("got error on test: %v", err)
+	}
+	if _, err := os.Stat(path.Join(root, "etc/motd")); err != nil {
+		t.Errorf("the file is not created under the root: %v", err)
+	}
+}
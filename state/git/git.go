@@ -0,0 +1,226 @@
+/*
+Package git implements the state of the working tree checked out from the git
+repository.
+
+The states are tested and applied by git command. The working tree is fetched
+from the origin, and the requested revision is checked out as a detached HEAD.
+*/
+package git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/harukasan/orchestra-pit/state"
+	"github.com/harukasan/orchestra-pit/state/exec"
+	"github.com/harukasan/orchestra-pit/state/file"
+)
+
+// GitPath specifies the path of git command.
+var GitPath = "/usr/bin/git"
+
+// Runner specifies the CommandRunner to run git command.
+var Runner = exec.DefaultRunner
+
+var commitPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+var hexPattern = regexp.MustCompile(`^[0-9a-f]{4,40}$`)
+
+// Checkout manages the working tree which checks out the revision of the
+// repository.
+//
+// Repository specifies the URL or the path of the repository, which is the
+// origin of the working tree. Revision specifies the branch, the tag, or the
+// commit ID to check out. Dest specifies the directory of the working tree.
+//
+// Depth specifies the number of the commits to fetch. If it is 0, the whole
+// history is fetched.
+//
+// User specifies the name or the ID of the user to run git command. If it is
+// empty, git runs as the current user.
+type Checkout struct {
+	Repository string
+	Revision   string
+	Dest       string
+	Depth      int
+	User       string
+}
+
+// Apply tries to fetch the revision and to check out it. The repository is
+// initialized if the directory is not a working tree.
+func (s *Checkout) Apply() error {
+	dest := state.RootPath(s.Dest)
+	commit, ref, err := s.resolve()
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(filepath.Join(dest, ".git")); os.IsNotExist(err) {
+		if err := s.makeDest(dest); err != nil {
+			return err
+		}
+		if _, err := s.git("", "init", "-q", dest); err != nil {
+			return err
+		}
+		if _, err := s.git(dest, "remote", "add", "origin", s.Repository); err != nil {
+			return err
+		}
+	} else if _, err := s.git(dest, "remote", "set-url", "origin", s.Repository); err != nil {
+		return err
+	}
+
+	args := []string{"fetch", "-q"}
+	if s.Depth > 0 {
+		args = append(args, fmt.Sprintf("--depth=%d", s.Depth))
+	}
+	target := "FETCH_HEAD"
+	if ref == "" {
+		// the abbreviated commit ID can not be fetched, so fetch the branches
+		// and the tags to find it.
+		args = append(args, "--tags", "origin", "+refs/heads/*:refs/remotes/origin/*")
+		target = s.Revision + "^{commit}"
+	} else {
+		args = append(args, "origin", ref)
+	}
+	if _, err := s.git(dest, args...); err != nil {
+		return err
+	}
+	if _, err := s.git(dest, "checkout", "-q", "--force", "--detach", target); err != nil {
+		return err
+	}
+
+	if commit != "" {
+		return s.testHead(dest, commit)
+	}
+	return nil
+}
+
+// Test tests whether the origin of the working tree is the repository, and
+// HEAD is the commit of the revision in the repository.
+func (s *Checkout) Test() error {
+	dest := state.RootPath(s.Dest)
+	if _, err := os.Stat(filepath.Join(dest, ".git")); err != nil {
+		return err
+	}
+	url, err := s.git(dest, "config", "--get", "remote.origin.url")
+	if err != nil {
+		return err
+	}
+	if url != s.Repository {
+		return fmt.Errorf("the origin of %s is %s, not %s", s.Dest, url, s.Repository)
+	}
+
+	commit, _, err := s.resolve()
+	if err != nil {
+		return err
+	}
+	if commit == "" {
+		commit, err = s.git(dest, "rev-parse", "--verify", "-q", s.Revision+"^{commit}")
+		if err != nil {
+			return fmt.Errorf("%s is not fetched into %s", s.Revision, s.Dest)
+		}
+	}
+	return s.testHead(dest, commit)
+}
+
+func (s *Checkout) String() string {
+	return fmt.Sprintf("checkout %s of %s into %s", s.Revision, s.Repository, s.Dest)
+}
+
+// makeDest creates the directory of the working tree for the user, because
+// the user may not be able to create it in the parent directory. The directory
+// is left as it is if it exists, or the user is not specified.
+func (s *Checkout) makeDest(dest string) error {
+	if s.User == "" {
+		return nil
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		return err
+	}
+	u, err := file.LookupUser(s.User)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return err
+	}
+	return os.Chown(dest, int(u.UID), int(u.GID))
+}
+
+func (s *Checkout) testHead(dest string, commit string) error {
+	head, err := s.git(dest, "rev-parse", "HEAD")
+	if err != nil {
+		return err
+	}
+	if head != commit {
+		return fmt.Errorf("HEAD of %s is %s, but %s is %s", s.Dest, head, s.Revision, commit)
+	}
+	return nil
+}
+
+// resolve returns the commit ID of the revision in the repository, and the ref
+// to fetch it. If the revision is the abbreviated commit ID, both of them are
+// empty, because they can not be resolved without fetching.
+func (s *Checkout) resolve() (commit string, ref string, err error) {
+	if commitPattern.MatchString(s.Revision) {
+		return s.Revision, s.Revision, nil
+	}
+
+	out, err := s.git("", "ls-remote", s.Repository, s.Revision, s.Revision+"^{}")
+	if err != nil {
+		return "", "", err
+	}
+	refs := map[string]string{}
+	for _, line := range strings.Split(out, "\n") {
+		f := strings.Fields(line)
+		if len(f) == 2 {
+			refs[f[1]] = f[0]
+		}
+	}
+	candidates := []string{s.Revision}
+	if !strings.HasPrefix(s.Revision, "refs/") && s.Revision != "HEAD" {
+		candidates = []string{"refs/tags/" + s.Revision, "refs/heads/" + s.Revision}
+	}
+	for _, c := range candidates {
+		if id, ok := refs[c+"^{}"]; ok {
+			return id, c, nil
+		}
+		if id, ok := refs[c]; ok {
+			return id, c, nil
+		}
+	}
+	if hexPattern.MatchString(s.Revision) {
+		return "", "", nil
+	}
+	return "", "", fmt.Errorf("%s is not found in %s", s.Revision, s.Repository)
+}
+
+// git runs git command in the directory, and returns the trimmed standard
+// output. If the directory is empty, it runs in the current directory.
+func (s *Checkout) git(dir string, args ...string) (string, error) {
+	if dir != "" {
+		args = append([]string{"-C", dir}, args...)
+	}
+	c := exec.Command(GitPath, args...)
+	c.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if s.User != "" {
+		u, err := file.LookupUser(s.User)
+		if err != nil {
+			return "", err
+		}
+		if err := setCredential(c, u); err != nil {
+			return "", err
+		}
+		c.Env = append(c.Env, "HOME="+u.Home, "USER="+u.Name)
+	}
+	stdout, stderr, err := Runner.Run(c)
+	if err != nil {
+		if len(stderr) > 0 {
+			return "", fmt.Errorf("%s: %s", err, strings.TrimSpace(string(stderr)))
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(stdout)), nil
+}
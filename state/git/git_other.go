@@ -0,0 +1,16 @@
+// +build !linux,!darwin,!dragonfly,!freebsd,!openbsd,!netbsd,!solaris
+
+package git
+
+import (
+	"errors"
+
+	"github.com/harukasan/orchestra-pit/state/exec"
+	"github.com/harukasan/orchestra-pit/state/file"
+)
+
+// setCredential returns an error, because the command can not run as the
+// other user on this platform.
+func setCredential(c *exec.Cmd, u *file.User) error {
+	return errors.New("running git as the other user is not supported on this platform")
+}
@@ -0,0 +1,184 @@
+package git_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	osexec "os/exec"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/harukasan/orchestra-pit/state"
+	"github.com/harukasan/orchestra-pit/state/exec/testutil"
+	"github.com/harukasan/orchestra-pit/state/git"
+)
+
+// setup creates the bare repository which has the main branch and the tag
+// v1, and returns the paths of the bare repository and its working tree, and
+// the function to remove them.
+func setup(t *testing.T) (string, string, func()) {
+	p, err := osexec.LookPath("git")
+	if err != nil {
+		t.Skip("git is not found")
+	}
+	dir, err := ioutil.TempDir("", "git_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := git.GitPath
+	git.GitPath = p
+	cleanup := func() {
+		git.GitPath = orig
+		os.RemoveAll(dir)
+	}
+
+	bare := path.Join(dir, "repo.git")
+	work := path.Join(dir, "work")
+	run(t, "", "init", "-q", "--bare", bare)
+	run(t, "", "init", "-q", work)
+	run(t, work, "symbolic-ref", "HEAD", "refs/heads/main")
+	run(t, work, "remote", "add", "origin", bare)
+	commit(t, work, "first")
+	run(t, work, "tag", "-a", "-m", "v1", "v1")
+	commit(t, work, "second")
+	run(t, work, "push", "-q", "origin", "main", "v1")
+	run(t, bare, "symbolic-ref", "HEAD", "refs/heads/main")
+	return bare, work, cleanup
+}
+
+func run(t *testing.T, dir string, args ...string) string {
+	if dir != "" {
+		args = append([]string{"-C", dir}, args...)
+	}
+	args = append([]string{"-c", "user.name=opit", "-c", "user.email=opit@example.com"}, args...)
+	out, err := osexec.Command(git.GitPath, args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v: %s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func commit(t *testing.T, work string, content string) string {
+	if err := ioutil.WriteFile(path.Join(work, "file"), []byte(content+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	run(t, work, "add", "file")
+	run(t, work, "commit", "-q", "-m", content)
+	return run(t, work, "rev-parse", "HEAD")
+}
+
+func content(t *testing.T, dest string) string {
+	data, err := ioutil.ReadFile(path.Join(dest, "file"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func TestCheckout(t *testing.T) {
+	bare, work, cleanup := setup(t)
+	defer cleanup()
+	first := run(t, work, "rev-parse", "v1^{commit}")
+
+	tests := []struct {
+		revision string
+		expected string
+	}{
+		{"HEAD", "second"},
+		{"main", "second"},
+		{"v1", "first"},
+		{first, "first"},
+		{first[:10], "first"},
+	}
+	for _, test := range tests {
+		dest := path.Join(path.Dir(bare), "dest-"+test.revision)
+		s := &git.Checkout{Repository: bare, Revision: test.revision, Dest: dest, Depth: 1}
+		if err := s.Test(); err == nil {
+			t.Errorf("%s: got no error before checked out", test.revision)
+		}
+		if err := s.Apply(); err != nil {
+			t.Fatalf("%s: got error on apply: %v", test.revision, err)
+		}
+		if err := s.Test(); err != nil {
+			t.Errorf("%s: got error on test: %v", test.revision, err)
+		}
+		if c := content(t, dest); c != test.expected {
+			t.Errorf("%s: got content %q, expected %q", test.revision, c, test.expected)
+		}
+	}
+
+	// the pushed commit is fetched on the next apply.
+	dest := path.Join(path.Dir(bare), "dest-main")
+	s := &git.Checkout{Repository: bare, Revision: "main", Dest: dest, Depth: 1}
+	commit(t, work, "third")
+	run(t, work, "push", "-q", "origin", "main")
+	if err := s.Test(); err == nil {
+		t.Errorf("got no error for the outdated working tree")
+	}
+	if err := s.Apply(); err != nil {
+		t.Fatalf("got error on apply: %v", err)
+	}
+	if c := content(t, dest); c != "third" {
+		t.Errorf("got content %q, expected %q", c, "third")
+	}
+
+	s.Revision = "v1"
+	if err := s.Apply(); err != nil {
+		t.Fatalf("got error on apply: %v", err)
+	}
+	if c := content(t, dest); c != "first" {
+		t.Errorf("got content %q, expected %q", c, "first")
+	}
+
+	missing := &git.Checkout{Repository: bare, Revision: "no-such-branch", Dest: dest}
+	if err := missing.Apply(); err == nil {
+		t.Errorf("got no error for the missing revision")
+	}
+	other := &git.Checkout{Repository: work, Revision: "v1", Dest: dest}
+	if err := other.Test(); err == nil {
+		t.Errorf("got no error for the different origin")
+	}
+}
+
+func TestCheckoutUser(t *testing.T) {
+	root, err := ioutil.TempDir("", "git_test_root_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	os.Mkdir(path.Join(root, "etc"), 0755)
+	// the user is the current user, so that the directory can be owned by the
+	// user without the privilege.
+	passwd := fmt.Sprintf("deploy:x:%d:%d::/home/deploy:/bin/sh\n", os.Getuid(), os.Getgid())
+	if err := ioutil.WriteFile(path.Join(root, "etc/passwd"), []byte(passwd), 0644); err != nil {
+		t.Fatal(err)
+	}
+	origRoot, origRunner := state.Root, git.Runner
+	defer func() { state.Root, git.Runner = origRoot, origRunner }()
+	state.Root = root
+	r := testutil.NewFakeRunner()
+	git.Runner = r
+	r.On(git.GitPath, "ls-remote", "https://example.com/tool.git", "main", "main^{}").
+		Return("0123456789012345678901234567890123456789\trefs/heads/main\n", "", 0)
+
+	s := &git.Checkout{Repository: "https://example.com/tool.git", Revision: "main", Dest: "/srv/tool", User: "deploy"}
+	if err := s.Apply(); err == nil {
+		t.Fatalf("got no error for the unscripted commands")
+	}
+	if len(r.Calls) == 0 || !contains(r.Calls[0].Env, "HOME=/home/deploy") || !contains(r.Calls[0].Env, "USER=deploy") {
+		t.Errorf("got calls %+v, expected the environment of the user", r.Calls)
+	}
+	if info, err := os.Stat(path.Join(root, "srv/tool")); err != nil || !info.IsDir() {
+		t.Errorf("got %v, expected the directory to be created before git init", err)
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
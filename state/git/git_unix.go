@@ -0,0 +1,18 @@
+// +build linux darwin dragonfly freebsd openbsd netbsd solaris
+
+package git
+
+import (
+	"syscall"
+
+	"github.com/harukasan/orchestra-pit/state/exec"
+	"github.com/harukasan/orchestra-pit/state/file"
+)
+
+// setCredential sets the command to run as the user.
+func setCredential(c *exec.Cmd, u *file.User) error {
+	c.SysProcAttr = &syscall.SysProcAttr{
+		Credential: &syscall.Credential{Uid: u.UID, Gid: u.GID},
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+// Copyright 2015 MICHII Shunsuke. All rights reserved.
+
+package packagemanager
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PreferencesDir specifies the directory of the preferences of APT.
+var PreferencesDir = "/etc/apt/preferences.d"
+
+// APTPreference manages the preference file of APT which pins the version or
+// the origin of packages.
+//
+// Name specifies the name of the preference. The preference is written to the
+// file named Name under PreferencesDir.
+//
+// Package specifies the names of the packages to pin. It can contain the
+// wildcards such as "nginx*".
+//
+// Pin specifies the target of pinning such as "version 1.14.*" or
+// "release a=stable".
+//
+// Priority specifies the priority of the pin. The priority higher than 1000
+// allows to downgrade the package.
+type APTPreference struct {
+	Name     string
+	Package  string
+	Pin      string
+	Priority int
+}
+
+// Apply tries to write the preference file. If failed to write the file, it
+// returns an error.
+func (s *APTPreference) Apply() error {
+	return s.apply()
+}
+
+// Test tests whether the preference file has the requested content.
+func (s *APTPreference) Test() error {
+	return s.test()
+}
+
+// Path returns the file path of the preference.
+func (s *APTPreference) Path() string {
+	return strings.TrimRight(PreferencesDir, "/") + "/" + s.Name
+}
+
+// Content returns the content of the preference file.
+func (s *APTPreference) Content() []byte {
+	return []byte(fmt.Sprintf("Package: %s\nPin: %s\nPin-Priority: %d\n", s.Package, s.Pin, s.Priority))
+}
+
+// Debconf manages the answers to the questions of debconf which are asked on
+// installing the package.
+//
+// Package specifies the name of the package which owns the questions.
+//
+// Selections specifies the answers to the questions. The answers should be set
+// before installing the package to install the package unattended.
+type Debconf struct {
+	Package    string
+	Selections []DebconfSelection
+}
+
+// DebconfSelection represents the answer to the question of debconf.
+//
+// Question specifies the name of the question such as "tzdata/Areas".
+//
+// Type specifies the type of the question such as "string", "boolean",
+// "select" or "password".
+//
+// Value specifies the answer to the question.
+type DebconfSelection struct {
+	Question string
+	Type     string
+	Value    string
+}
+
+// Apply tries to set the answers to the questions. If failed to set the
+// answers, it returns an error.
+func (s *Debconf) Apply() error {
+	return s.apply()
+}
+
+// Test tests whether the questions have the requested answers. The answers to
+// the password questions can not be retrieved, so Test only checks whether the
+// password questions are registered.
+func (s *Debconf) Test() error {
+	return s.test()
+}
@@ -7,14 +7,41 @@ package apt
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"os"
+	"strings"
 
+	"github.com/harukasan/orchestra-pit/state"
 	"github.com/harukasan/orchestra-pit/state/exec"
+	"github.com/harukasan/orchestra-pit/state/packagemanager/version"
 )
 
+// Runner specifies the CommandRunner to run the commands of APT.
+var Runner = exec.DefaultRunner
+
 // APTGetPath specifies the file path of the apt-get command
 var APTGetPath = "/usr/bin/apt-get"
 
+// APTCachePath specifies the file path of the apt-cache command
+var APTCachePath = "/usr/bin/apt-cache"
+
+// APTMarkPath specifies the file path of the apt-mark command
+var APTMarkPath = "/usr/bin/apt-mark"
+
+// DebconfSetSelectionsPath specifies the file path of the
+// debconf-set-selections command
+var DebconfSetSelectionsPath = "/usr/bin/debconf-set-selections"
+
+// DebconfShowPath specifies the file path of the debconf-show command
+var DebconfShowPath = "/usr/bin/debconf-show"
+
+// DPKGReconfigurePath specifies the file path of the dpkg-reconfigure command
+var DPKGReconfigurePath = "/usr/sbin/dpkg-reconfigure"
+
+// ChrootPath specifies the file path of the chroot command, which runs the
+// commands of debconf in the alternate root.
+var ChrootPath = "/usr/sbin/chroot"
+
 // DPKGQueryPath specifies the file path of the dpkg-query command
 var DPKGQueryPath = "/usr/bin/dpkg-query"
 
@@ -24,57 +51,167 @@ var InstallOptions = []string{
 	"-o Dpkg::Options::='--force-confold'",
 }
 
+// aptCommand returns the command of APT. If state.Root is the alternate root,
+// the options are added to operate the packages under the root.
+func aptCommand(name string, arg ...string) *exec.Cmd {
+	if state.IsAlternateRoot() {
+		arg = append([]string{
+			"-o", "RootDir=" + state.Root,
+			"-o", "DPkg::Options::=--root=" + state.Root,
+		}, arg...)
+	}
+	return exec.Command(name, arg...)
+}
+
+// dpkgCommand returns the command of dpkg. If state.Root is the alternate
+// root, the option is added to operate the packages under the root.
+func dpkgCommand(name string, arg ...string) *exec.Cmd {
+	if state.IsAlternateRoot() {
+		arg = append([]string{"--root=" + state.Root}, arg...)
+	}
+	return exec.Command(name, arg...)
+}
+
+// debconfCommand returns the command of debconf. Debconf does not have the
+// option to change the root, so the command runs with chroot if state.Root is
+// the alternate root.
+func debconfCommand(name string, arg ...string) *exec.Cmd {
+	if state.IsAlternateRoot() {
+		return exec.Command(ChrootPath, append([]string{state.Root, name}, arg...)...)
+	}
+	return exec.Command(name, arg...)
+}
+
+// Package specifies the name and version of the package to install. The
+// version can be a constraint such as ">= 2.4, < 3". See the version package
+// for the syntax of constraints.
+type Package struct {
+	Name    string
+	Version string
+}
+
 // Install executes the apt-get command to install the named package. If the
-// version is specified, the version is also passed to apt-get command.
+// version is specified, the version is also passed to apt-get command. If the
+// version is a constraint, the newest candidate which satisfies the constraint
+// is installed.
 func Install(name string, version string) error {
+	return InstallPackages([]Package{{Name: name, Version: version}})
+}
+
+// InstallPackages executes the apt-get command once to install all of the
+// given packages in one transaction.
+func InstallPackages(pkgs []Package) error {
 	args := []string{"install", "-y"}
 	args = append(args, InstallOptions...)
-	if version != "" {
-		args = append(args, exec.ShellEscape(name+"="+version))
-	} else {
-		args = append(args, exec.ShellEscape(name))
+	for _, p := range pkgs {
+		v, err := resolveVersion(p.Name, p.Version)
+		if err != nil {
+			return err
+		}
+		if v != "" {
+			args = append(args, p.Name+"="+v)
+		} else {
+			args = append(args, p.Name)
+		}
 	}
-	cmd := exec.Command(APTGetPath, args...)
+	cmd := aptCommand(APTGetPath, args...)
 	cmd.Env = append(cmd.Env, os.Environ()...)
 	cmd.Env = append(cmd.Env, "DEBIAN_FRONTEND=noninteractive")
-	return cmd.Run()
+	_, _, err := Runner.Run(cmd)
+	return err
+}
+
+// resolveVersion returns the version to pass to apt-get command. If the version
+// is a constraint, it returns the newest candidate which satisfies the
+// constraint.
+func resolveVersion(name string, v string) (string, error) {
+	if !version.IsConstraint(v) {
+		return strings.TrimSpace(strings.TrimLeft(v, "=")), nil
+	}
+	c, err := version.ParseConstraint(v)
+	if err != nil {
+		return "", err
+	}
+	candidates, err := Candidates(name)
+	if err != nil {
+		return "", err
+	}
+	latest, ok := c.Latest(candidates, version.CompareDebian)
+	if !ok {
+		return "", fmt.Errorf("no candidate of the package %s satisfies the version: %s", name, c)
+	}
+	return latest, nil
+}
+
+// Candidates returns the versions of the named package which are available to
+// install.
+func Candidates(name string) ([]string, error) {
+	cmd := aptCommand(APTCachePath, "madison", name)
+	cmd.Env = append(cmd.Env, os.Environ()...)
+	out, _, err := Runner.Run(cmd)
+	if err != nil {
+		return nil, err
+	}
+	return parseMadison(out), nil
+}
+
+// parseMadison parses the output of apt-cache madison command, which has the
+// lines such as "name | version | source".
+func parseMadison(out []byte) []string {
+	versions := []string{}
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Split(line, "|")
+		if len(fields) < 3 {
+			continue
+		}
+		versions = append(versions, strings.TrimSpace(fields[1]))
+	}
+	return versions
 }
 
 // Remove executes the apt-get command to remove the named package.
 func Remove(name string) error {
-	cmd := exec.Command(APTGetPath, "remove", "-y", exec.ShellEscape(name))
+	cmd := aptCommand(APTGetPath, "remove", "-y", name)
 	cmd.Env = append(cmd.Env, os.Environ()...)
 	cmd.Env = append(cmd.Env, "DEBIAN_FRONTEND=noninteractive")
-	return cmd.Run()
+	_, _, err := Runner.Run(cmd)
+	return err
 }
 
 // IsInstalled tests whether the named package is installed on the system.
 // If the named package is not installed, or the execution fails, it returns
 // an error.
 //
-// If the version is specified, it checks whether the specified version is
-// linked.
+// If the version is specified, it checks whether the installed version
+// satisfies the version. The version can be a constraint, and the versions are
+// compared in the manner of dpkg.
 //
 // To test whether the package is NOT installed, Use IsNotInstalled function
 // instead of this.
-func IsInstalled(name string, version string) error {
-	cmd := exec.Command(DPKGQueryPath, "--showformat=${Status}\\n${Version}", "--show", exec.ShellEscape(name))
+func IsInstalled(name string, v string) error {
+	cmd := dpkgCommand(DPKGQueryPath, "--showformat=${Status}\\n${Version}", "--show", name)
 	cmd.Env = append(cmd.Env, os.Environ()...)
-	out, err := cmd.Output()
+	out, _, err := Runner.Run(cmd)
 	if err != nil {
 		return err
 	}
 	if !bytes.HasPrefix(out, []byte("install ok installed\n")) {
 		return errors.New("the package is not installed")
 	}
-	if version != "" {
-		if i := bytes.IndexRune(out, '\n'); i > 0 {
-			if !bytes.HasPrefix(out[i+1:], []byte(version)) {
-				return errors.New("the different version is installed")
-			}
-		} else {
-			return errors.New("failed to parse the result of dpkg-query")
-		}
+	if v == "" {
+		return nil
+	}
+	c, err := version.ParseConstraint(v)
+	if err != nil {
+		return err
+	}
+	i := bytes.IndexRune(out, '\n')
+	if i < 0 {
+		return errors.New("failed to parse the result of dpkg-query")
+	}
+	installed := string(bytes.TrimSpace(out[i+1:]))
+	if !c.Match(installed, version.CompareDebian) {
+		return fmt.Errorf("the version %s is installed, but %s is requested", installed, c)
 	}
 	return nil
 }
@@ -82,14 +219,14 @@ func IsInstalled(name string, version string) error {
 // IsNotInstalled tests whether the named package is not installed on the system.
 // If the package is installed or execution fails, it returns an error.
 func IsNotInstalled(name string) error {
-	cmd := exec.Command(DPKGQueryPath, "--show", exec.ShellEscape(name))
+	cmd := dpkgCommand(DPKGQueryPath, "--show", name)
 	cmd.Env = append(cmd.Env, os.Environ()...)
-	out, err := cmd.CombinedOutput()
+	out, stderr, err := Runner.Run(cmd)
 	if err != nil {
 		// TODO:
 		// I could not find the apt/dpkg command to retrieve status of uninstalled
 		// package. just check output message.
-		if bytes.Contains(out, []byte("no packages found")) {
+		if bytes.Contains(out, []byte("no packages found")) || bytes.Contains(stderr, []byte("no packages found")) {
 			return nil
 		}
 		return err
@@ -99,5 +236,88 @@ func IsNotInstalled(name string) error {
 
 // Update executes updating lists of apt packages.
 func Update() error {
-	return exec.Command(APTGetPath, "update").Run()
+	_, _, err := Runner.Run(aptCommand(APTGetPath, "update"))
+	return err
+}
+
+// Hold executes the apt-mark command to hold the named package, which prevents
+// the package from being upgraded or removed automatically.
+func Hold(name string) error {
+	_, _, err := Runner.Run(aptCommand(APTMarkPath, "hold", name))
+	return err
+}
+
+// Unhold executes the apt-mark command to cancel the hold of the named package.
+func Unhold(name string) error {
+	_, _, err := Runner.Run(aptCommand(APTMarkPath, "unhold", name))
+	return err
+}
+
+// IsHeld tests whether the named package is held.
+func IsHeld(name string) (bool, error) {
+	out, _, err := Runner.Run(aptCommand(APTMarkPath, "showhold"))
+	if err != nil {
+		return false, err
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.TrimSpace(line) == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Selection represents the answer to the question of debconf.
+type Selection struct {
+	Package  string
+	Question string
+	Type     string
+	Value    string
+}
+
+// SetSelections executes the debconf-set-selections command to preseed the
+// answers to the questions of debconf.
+func SetSelections(sels []Selection) error {
+	buf := &bytes.Buffer{}
+	for _, s := range sels {
+		fmt.Fprintf(buf, "%s %s %s %s\n", s.Package, s.Question, s.Type, s.Value)
+	}
+	cmd := debconfCommand(DebconfSetSelectionsPath)
+	cmd.Stdin = buf
+	_, _, err := Runner.Run(cmd)
+	return err
+}
+
+// Reconfigure executes the dpkg-reconfigure command to configure the installed
+// package again with the current answers to the questions of debconf.
+func Reconfigure(name string) error {
+	_, _, err := Runner.Run(debconfCommand(DPKGReconfigurePath, "-f", "noninteractive", name))
+	return err
+}
+
+// ShowSelections executes the debconf-show command and returns the answers to
+// the questions of the named package. The values of the password questions
+// are omitted by debconf-show, so they are returned as "(password omitted)".
+func ShowSelections(name string) (map[string]string, error) {
+	out, _, err := Runner.Run(debconfCommand(DebconfShowPath, name))
+	if err != nil {
+		return nil, err
+	}
+	return parseSelections(out), nil
+}
+
+// parseSelections parses the output of debconf-show command, which has the
+// lines such as "* question: value". The asterisk means that the question has
+// been asked.
+func parseSelections(out []byte) map[string]string {
+	m := map[string]string{}
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimLeft(line, "* ")
+		i := strings.Index(line, ":")
+		if i < 0 {
+			continue
+		}
+		m[line[:i]] = strings.TrimSpace(line[i+1:])
+	}
+	return m
 }
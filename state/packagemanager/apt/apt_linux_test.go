@@ -7,6 +7,7 @@ package apt_test
 import (
 	"testing"
 
+	"github.com/harukasan/orchestra-pit/state/exec/testutil"
 	"github.com/harukasan/orchestra-pit/state/packagemanager/apt"
 )
 
@@ -41,3 +42,41 @@ func TestAPTWithVersion(t *testing.T) {
 		t.Errorf("IsNotInstalled: %v", err)
 	}
 }
+
+func TestSelections(t *testing.T) {
+	r := testutil.NewFakeRunner()
+	orig := apt.Runner
+	apt.Runner = r
+	defer func() {
+		apt.Runner = orig
+	}()
+
+	r.On(apt.DebconfSetSelectionsPath).Return("", "", 0)
+	r.On(apt.DebconfShowPath, "tzdata").Return(
+		"* tzdata/Areas: Asia\n"+
+			"* tzdata/Zones/Asia: Tokyo\n"+
+			"  tzdata/Zones/Etc: UTC\n", "", 0)
+
+	err := apt.SetSelections([]apt.Selection{
+		{Package: "tzdata", Question: "tzdata/Areas", Type: "select", Value: "Asia"},
+		{Package: "tzdata", Question: "tzdata/Zones/Asia", Type: "select", Value: "Tokyo"},
+	})
+	if err != nil {
+		t.Errorf("SetSelections: %v", err)
+	}
+	expected := "tzdata tzdata/Areas select Asia\ntzdata tzdata/Zones/Asia select Tokyo\n"
+	if got := r.Calls[0].Stdin; got != expected {
+		t.Errorf("got stdin %q, expected %q", got, expected)
+	}
+
+	m, err := apt.ShowSelections("tzdata")
+	if err != nil {
+		t.Fatalf("ShowSelections: %v", err)
+	}
+	if m["tzdata/Zones/Asia"] != "Tokyo" {
+		t.Errorf("got %q, expected Tokyo", m["tzdata/Zones/Asia"])
+	}
+	if m["tzdata/Zones/Etc"] != "UTC" {
+		t.Errorf("got %q, expected UTC", m["tzdata/Zones/Etc"])
+	}
+}
@@ -0,0 +1,23 @@
+// Copyright 2015 MICHII Shunsuke. All rights reserved.
+
+// +build darwin
+
+package packagemanager
+
+import "errors"
+
+func (s *APTPreference) apply() error {
+	return errors.New("unsupported platform")
+}
+
+func (s *APTPreference) test() error {
+	return errors.New("unsupported platform")
+}
+
+func (s *Debconf) apply() error {
+	return errors.New("unsupported platform")
+}
+
+func (s *Debconf) test() error {
+	return errors.New("unsupported platform")
+}
@@ -0,0 +1,97 @@
+// Copyright 2015 MICHII Shunsuke. All rights reserved.
+
+// +build linux
+
+package packagemanager
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/harukasan/orchestra-pit/state/packagemanager/apt"
+	"github.com/harukasan/orchestra-pit/state/platform"
+)
+
+// supportedForDebian returns an error if the platform is not Debian or its
+// derivatives.
+func supportedForDebian() error {
+	p, err := platform.Identify()
+	if err != nil {
+		return err
+	}
+	if platform.Family(p.Get("family")) != platform.FamilyDebian {
+		return errors.New("unsupported platform")
+	}
+	return nil
+}
+
+func (s *APTPreference) apply() error {
+	if err := supportedForDebian(); err != nil {
+		return err
+	}
+	return writeFile(s.Path(), s.Content(), 0644)
+}
+
+func (s *APTPreference) test() error {
+	if err := supportedForDebian(); err != nil {
+		return err
+	}
+	content, err := ioutil.ReadFile(s.Path())
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(content, s.Content()) {
+		return errors.New("the preference is different from the requested")
+	}
+	return nil
+}
+
+func (s *Debconf) apply() error {
+	if err := supportedForDebian(); err != nil {
+		return err
+	}
+	sels := make([]apt.Selection, len(s.Selections))
+	for i, sel := range s.Selections {
+		sels[i] = apt.Selection{
+			Package:  s.Package,
+			Question: sel.Question,
+			Type:     sel.Type,
+			Value:    sel.Value,
+		}
+	}
+	if err := apt.SetSelections(sels); err != nil {
+		return err
+	}
+
+	// the installed package does not read the answers until it is configured
+	// again.
+	if apt.IsInstalled(s.Package, "") == nil {
+		return apt.Reconfigure(s.Package)
+	}
+	return nil
+}
+
+func (s *Debconf) test() error {
+	if err := supportedForDebian(); err != nil {
+		return err
+	}
+	m, err := apt.ShowSelections(s.Package)
+	if err != nil {
+		return err
+	}
+	for _, sel := range s.Selections {
+		v, ok := m[sel.Question]
+		if !ok {
+			return fmt.Errorf("the question %s is not answered", sel.Question)
+		}
+		if sel.Type == "password" {
+			continue
+		}
+		if v != sel.Value {
+			return fmt.Errorf("the question %s is answered %q, but %q is requested", sel.Question, v, sel.Value)
+		}
+	}
+	return nil
+}
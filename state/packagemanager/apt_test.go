@@ -0,0 +1,26 @@
+// Copyright 2015 MICHII Shunsuke. All rights reserved.
+
+package packagemanager_test
+
+import (
+	"testing"
+
+	"github.com/harukasan/orchestra-pit/state/packagemanager"
+)
+
+func TestAPTPreferenceContent(t *testing.T) {
+	s := &packagemanager.APTPreference{
+		Name:     "nginx",
+		Package:  "nginx*",
+		Pin:      "version 1.14.*",
+		Priority: 1001,
+	}
+
+	expected := "Package: nginx*\nPin: version 1.14.*\nPin-Priority: 1001\n"
+	if got := string(s.Content()); got != expected {
+		t.Errorf("got %q, expected %q", got, expected)
+	}
+	if got := s.Path(); got != "/etc/apt/preferences.d/nginx" {
+		t.Errorf("got Path %v, expected /etc/apt/preferences.d/nginx", got)
+	}
+}
@@ -12,7 +12,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"os/exec"
+
+	"github.com/harukasan/orchestra-pit/state/exec"
 )
 
 // Package describes information of the package.
@@ -51,9 +52,12 @@ type PackageOption struct {
 // Path specifies the file path of Homebrew binary.
 var Path = "/usr/local/bin/brew"
 
+// Runner specifies the CommandRunner to run the brew command.
+var Runner = exec.DefaultRunner
+
 // Info returns information of the named package.
 func Info(name string) (*Package, error) {
-	out, err := exec.Command(Path, "info", "--json=v1", name).CombinedOutput()
+	out, _, err := Runner.Run(exec.Command(Path, "info", "--json=v1", name))
 	if err != nil {
 		return nil, err
 	}
@@ -84,7 +88,18 @@ func IsExist(name string) (bool, error) {
 func Install(name string, options []string) error {
 	options = append([]string{"install", name}, options...)
 	cmd := exec.Command(Path, options...)
-	if err := cmd.Run(); err != nil {
+	if _, _, err := Runner.Run(cmd); err != nil {
+		return err
+	}
+	return nil
+}
+
+// InstallPackages executes the command once to install all of the named
+// packages.
+func InstallPackages(names []string) error {
+	args := append([]string{"install"}, names...)
+	cmd := exec.Command(Path, args...)
+	if _, _, err := Runner.Run(cmd); err != nil {
 		return err
 	}
 	return nil
@@ -93,7 +108,7 @@ func Install(name string, options []string) error {
 // Uninstall executes the command to uninstall the named package.
 func Uninstall(name string) error {
 	cmd := exec.Command(Path, "uninstall", name)
-	if err := cmd.Run(); err != nil {
+	if _, _, err := Runner.Run(cmd); err != nil {
 		return err
 	}
 	return nil
@@ -179,7 +194,7 @@ func IsNotInstalled(name string) error {
 // repository is not tapped.
 func Tap(name string) error {
 	cmd := exec.Command(Path, "tap", name)
-	if err := cmd.Run(); err != nil {
+	if _, _, err := Runner.Run(cmd); err != nil {
 		return err
 	}
 	return nil
@@ -189,7 +204,7 @@ func Tap(name string) error {
 // named repository is tapped.
 func Untap(name string) error {
 	cmd := exec.Command(Path, "untap", name)
-	if err := cmd.Run(); err != nil {
+	if _, _, err := Runner.Run(cmd); err != nil {
 		return err
 	}
 	return nil
@@ -197,7 +212,7 @@ func Untap(name string) error {
 
 // IsTapped tests whether the given named repository is tapped.
 func IsTapped(name string) (bool, error) {
-	out, err := exec.Command(Path, "tap").Output()
+	out, _, err := Runner.Run(exec.Command(Path, "tap"))
 	if err != nil {
 		return false, err
 	}
@@ -209,5 +224,6 @@ func IsTapped(name string) (bool, error) {
 
 // Update executes the update of Homebrew.
 func Update() error {
-	return exec.Command(Path, "update").Run()
+	_, _, err := Runner.Run(exec.Command(Path, "update"))
+	return err
 }
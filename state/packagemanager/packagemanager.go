@@ -8,6 +8,7 @@ Following PMSes is supported now:
 
   - Homebrew (Mac OS X)
 	- APT      (Debian and its derivatives)
+	- yum      (Red Hat Enterprise Linux and its derivatives)
 
 */
 package packagemanager
@@ -15,7 +16,9 @@ package packagemanager
 // Installed tries to keep that the named package is installed on the system.
 //
 // Name and Version specifies the name and version of package. If Version is not
-// specified, the latest version should be installed.
+// specified, the latest version should be installed. Version can be a
+// constraint such as ">= 2.4, < 3" or "~> 1.2", then the newest candidate which
+// satisfies the constraint is installed. See the version package for details.
 //
 // Options is passed as arguments of the command of package management system.
 //
@@ -40,6 +43,33 @@ func (s *Installed) Test() error {
 	return s.test()
 }
 
+// InstalledBatch tries to keep that all of the packages are installed on the
+// system.
+//
+// Packages specifies the packages to install. Apply installs the packages in
+// one transaction of the package management system instead of running the
+// command for each package.
+type InstalledBatch struct {
+	Packages []*Installed
+}
+
+// Apply tries to install all of the packages at once. If failed to install the
+// packages, it returns an error.
+func (s *InstalledBatch) Apply() error {
+	return s.apply()
+}
+
+// Test tests whether all of the packages are installed. If any package is not
+// installed, it returns an error.
+func (s *InstalledBatch) Test() error {
+	for _, p := range s.Packages {
+		if err := p.Test(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Removed tries to keep that the named package is removed on the system.
 //
 // Name specifies the name of package.
@@ -58,3 +88,26 @@ func (s *Removed) Apply() error {
 func (s *Removed) Test() error {
 	return s.test()
 }
+
+// Held tries to keep that the named package is held at the installed version.
+// The held package is not upgraded or removed by the package management system
+// automatically.
+//
+// Name specifies the name of package.
+//
+// If Hold is false, Apply cancels the hold of the package.
+type Held struct {
+	Name string
+	Hold bool
+}
+
+// Apply tries to hold or unhold the named package. If failed to change the
+// hold, it returns an error.
+func (s *Held) Apply() error {
+	return s.apply()
+}
+
+// Test tests whether the package is held or not as requested.
+func (s *Held) Test() error {
+	return s.test()
+}
@@ -5,6 +5,7 @@
 package packagemanager
 
 import (
+	"errors"
 	"strings"
 	"sync"
 
@@ -63,6 +64,35 @@ func (s *Installed) test() error {
 	return homebrew.IsInstalled(s.Name, s.Version, s.Options)
 }
 
+func (s *InstalledBatch) apply() error {
+	names := []string{}
+	for _, p := range s.Packages {
+		// the options of Homebrew are applied to all of the given formulae, so
+		// install the package which has options separately.
+		if len(p.Options) > 0 || p.Version != "" {
+			if err := p.apply(); err != nil {
+				return err
+			}
+			continue
+		}
+		if p.Update {
+			if err := update.do(); err != nil {
+				return err
+			}
+		}
+		if i := strings.LastIndex(p.Name, "/"); i > 0 {
+			if err := homebrew.Tap(p.Name[0:i]); err != nil {
+				return err
+			}
+		}
+		names = append(names, p.Name)
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	return homebrew.InstallPackages(names)
+}
+
 func (s *Removed) apply() error {
 	return homebrew.Uninstall(s.Name)
 }
@@ -73,3 +103,11 @@ func (s *Removed) test() error {
 	}
 	return nil
 }
+
+func (s *Held) apply() error {
+	return errors.New("unsupported platform")
+}
+
+func (s *Held) test() error {
+	return errors.New("unsupported platform")
+}
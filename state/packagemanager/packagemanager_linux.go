@@ -6,11 +6,61 @@ package packagemanager
 
 import (
 	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
 
 	"github.com/harukasan/orchestra-pit/state"
 	"github.com/harukasan/orchestra-pit/state/platform"
 )
 
+// updateOnce runs the update function of the package management system just
+// once in the process. When the sources of the packages are changed, expire
+// marks the package lists as outdated to run the update function again.
+type updateOnce struct {
+	sync.RWMutex
+	updated bool
+	expired bool
+	update  func() error
+}
+
+func (u *updateOnce) do() error {
+	u.RLock()
+	if !u.updated {
+		u.RUnlock()
+		u.Lock()
+		defer u.Unlock()
+		if err := u.update(); err != nil {
+			return err
+		}
+		u.updated = true
+		u.expired = false
+		return nil
+	}
+	u.RUnlock()
+	return nil
+}
+
+// doIfExpired runs the update function only if the package lists are expired.
+func (u *updateOnce) doIfExpired() error {
+	u.RLock()
+	expired := u.expired
+	u.RUnlock()
+	if !expired {
+		return nil
+	}
+	return u.do()
+}
+
+// expire marks the package lists as outdated.
+func (u *updateOnce) expire() {
+	u.Lock()
+	defer u.Unlock()
+	u.updated = false
+	u.expired = true
+}
+
 func (s *Installed) stateForSpecificPlatform() (state.State, error) {
 	p, err := platform.Identify()
 	if err != nil {
@@ -19,6 +69,8 @@ func (s *Installed) stateForSpecificPlatform() (state.State, error) {
 	switch platform.Family(p.Get("family")) {
 	case platform.FamilyDebian:
 		return &InstalledForDebian{s}, nil
+	case platform.FamilyRHEL:
+		return &InstalledForRedHat{s}, nil
 	}
 	return nil, errors.New("unsupported platform")
 }
@@ -39,6 +91,28 @@ func (s *Installed) test() error {
 	return ps.Test()
 }
 
+func (s *InstalledBatch) stateForSpecificPlatform() (state.State, error) {
+	p, err := platform.Identify()
+	if err != nil {
+		return nil, err
+	}
+	switch platform.Family(p.Get("family")) {
+	case platform.FamilyDebian:
+		return &InstalledBatchForDebian{s}, nil
+	case platform.FamilyRHEL:
+		return &InstalledBatchForRedHat{s}, nil
+	}
+	return nil, errors.New("unsupported platform")
+}
+
+func (s *InstalledBatch) apply() error {
+	ps, err := s.stateForSpecificPlatform()
+	if err != nil {
+		return err
+	}
+	return ps.Apply()
+}
+
 func (s *Removed) stateForSpecificPlatform() (state.State, error) {
 	p, err := platform.Identify()
 	if err != nil {
@@ -47,6 +121,8 @@ func (s *Removed) stateForSpecificPlatform() (state.State, error) {
 	switch platform.Family(p.Get("family")) {
 	case platform.FamilyDebian:
 		return &RemovedForDebian{s}, nil
+	case platform.FamilyRHEL:
+		return &RemovedForRedHat{s}, nil
 	}
 	return nil, errors.New("unsupported platform")
 }
@@ -66,3 +142,59 @@ func (s *Removed) test() error {
 	}
 	return ps.Test()
 }
+
+func (s *Held) stateForSpecificPlatform() (state.State, error) {
+	p, err := platform.Identify()
+	if err != nil {
+		return nil, err
+	}
+	switch platform.Family(p.Get("family")) {
+	case platform.FamilyDebian:
+		return &HeldForDebian{s}, nil
+	}
+	return nil, errors.New("unsupported platform")
+}
+
+func (s *Held) apply() error {
+	ps, err := s.stateForSpecificPlatform()
+	if err != nil {
+		return err
+	}
+	return ps.Apply()
+}
+
+func (s *Held) test() error {
+	ps, err := s.stateForSpecificPlatform()
+	if err != nil {
+		return err
+	}
+	return ps.Test()
+}
+
+// writeFile writes the content to the named file atomically. The content is
+// written to a temporary file in the same directory, and then the temporary
+// file is renamed to the named file.
+func writeFile(name string, content []byte, perm os.FileMode) error {
+	tmp, err := ioutil.TempFile(path.Dir(name), "."+path.Base(name))
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
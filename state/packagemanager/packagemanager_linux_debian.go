@@ -5,33 +5,12 @@
 package packagemanager
 
 import (
-	"sync"
+	"errors"
 
 	"github.com/harukasan/orchestra-pit/state/packagemanager/apt"
 )
 
-type updateOnce struct {
-	sync.RWMutex
-	updated bool
-}
-
-func (u *updateOnce) do() error {
-	u.RLock()
-	if !u.updated {
-		u.RUnlock()
-		u.Lock()
-		defer u.Unlock()
-		if err := apt.Update(); err != nil {
-			return err
-		}
-		u.updated = true
-		return nil
-	}
-	u.RUnlock()
-	return nil
-}
-
-var update = &updateOnce{}
+var update = &updateOnce{update: apt.Update}
 
 // InstalledForDebian implements state of which the package is installed for
 // the platform of Debian or its derivatives.
@@ -47,6 +26,9 @@ func (s *InstalledForDebian) Apply() error {
 			return err
 		}
 	}
+	if err := update.doIfExpired(); err != nil {
+		return err
+	}
 	return apt.Install(s.Name, s.Version)
 }
 
@@ -56,6 +38,30 @@ func (s *InstalledForDebian) Test() error {
 	return apt.IsInstalled(s.Name, s.Version)
 }
 
+// InstalledBatchForDebian implements state of which all of the packages are
+// installed for the platform of Debian or its derivatives.
+type InstalledBatchForDebian struct {
+	*InstalledBatch
+}
+
+// Apply tries to install all of the packages by running APT once. If the
+// installation fails, it returns an error.
+func (s *InstalledBatchForDebian) Apply() error {
+	pkgs := make([]apt.Package, 0, len(s.Packages))
+	for _, p := range s.Packages {
+		if p.Update {
+			if err := update.do(); err != nil {
+				return err
+			}
+		}
+		pkgs = append(pkgs, apt.Package{Name: p.Name, Version: p.Version})
+	}
+	if err := update.doIfExpired(); err != nil {
+		return err
+	}
+	return apt.InstallPackages(pkgs)
+}
+
 // RemovedForDebian implements state of which the package is removed for the
 // platform of Debian or its derivatives.
 type RemovedForDebian struct {
@@ -72,3 +78,32 @@ func (s *RemovedForDebian) Apply() error {
 func (s *RemovedForDebian) Test() error {
 	return apt.IsNotInstalled(s.Name)
 }
+
+// HeldForDebian implements state of which the package is held for the platform
+// of Debian or its derivatives.
+type HeldForDebian struct {
+	*Held
+}
+
+// Apply tries to hold or unhold the package with apt-mark.
+func (s *HeldForDebian) Apply() error {
+	if s.Hold {
+		return apt.Hold(s.Name)
+	}
+	return apt.Unhold(s.Name)
+}
+
+// Test checks whether the package is held or not as requested with apt-mark.
+func (s *HeldForDebian) Test() error {
+	held, err := apt.IsHeld(s.Name)
+	if err != nil {
+		return err
+	}
+	if held != s.Hold {
+		if held {
+			return errors.New("the package is held")
+		}
+		return errors.New("the package is not held")
+	}
+	return nil
+}
@@ -5,26 +5,52 @@
 package packagemanager_test
 
 import (
+	"io/ioutil"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/harukasan/orchestra-pit/state/exec/testutil"
 	"github.com/harukasan/orchestra-pit/state/packagemanager"
+	"github.com/harukasan/orchestra-pit/state/packagemanager/apt"
 	"github.com/harukasan/orchestra-pit/state/platform"
 )
 
-func TestMain(m *testing.M) {
+// requireDebian skips the test which installs the packages actually, if the
+// platform is not Debian or its derivatives.
+func requireDebian(t *testing.T) {
 	p, err := platform.Identify()
 	if err != nil {
-		return
+		t.Skipf("the platform could not identified: %v", err)
 	}
 	if p.Get("family") != string(platform.FamilyDebian) {
-		return
+		t.Skip("the platform is not Debian or its derivatives")
 	}
+}
+
+// fakeAPT replaces the runner of APT with a FakeRunner, and returns the
+// function to restore it.
+func fakeAPT() (*testutil.FakeRunner, func()) {
+	r := testutil.NewFakeRunner()
+	orig := apt.Runner
+	apt.Runner = r
+	return r, func() { apt.Runner = orig }
+}
 
-	os.Exit(m.Run())
+func installArgs(pkgs ...string) []string {
+	args := []string{apt.APTGetPath, "install", "-y"}
+	args = append(args, apt.InstallOptions...)
+	return append(args, pkgs...)
+}
+
+func dpkgQueryArgs(name string) []string {
+	return []string{apt.DPKGQueryPath, "--showformat=${Status}\\n${Version}", "--show", name}
 }
 
 func TestInstalled(t *testing.T) {
+	requireDebian(t)
+
 	s := &packagemanager.Installed{
 		Name: "debian-faq",
 	}
@@ -41,6 +67,8 @@ func TestInstalled(t *testing.T) {
 }
 
 func TestRemoved(t *testing.T) {
+	requireDebian(t)
+
 	is := &packagemanager.Installed{
 		Name: "debian-faq",
 	}
@@ -58,3 +86,196 @@ func TestRemoved(t *testing.T) {
 		t.Errorf("Test: %v", err)
 	}
 }
+
+func TestInstalledForDebian(t *testing.T) {
+	r, restore := fakeAPT()
+	defer restore()
+	r.On(dpkgQueryArgs("sl")...).Return("", "dpkg-query: no packages found matching sl\n", 1)
+	r.On(installArgs("sl")...).Return("", "", 0)
+	r.On(dpkgQueryArgs("sl")...).Return("install ok installed\n5.02-1", "", 0)
+
+	s := &packagemanager.InstalledForDebian{&packagemanager.Installed{Name: "sl"}}
+	if err := s.Test(); err == nil {
+		t.Errorf("Test: got no error when the package is not installed")
+	}
+	if err := s.Apply(); err != nil {
+		t.Errorf("Apply: %v", err)
+	}
+	if err := s.Test(); err != nil {
+		t.Errorf("Test: %v", err)
+	}
+	if got := len(r.Calls); got != 3 {
+		t.Errorf("got %d commands, expected 3: %v", got, r.Commands())
+	}
+}
+
+func TestInstalledForDebianWithConstraint(t *testing.T) {
+	r, restore := fakeAPT()
+	defer restore()
+	r.On(apt.APTCachePath, "madison", "sl").Return(
+		"        sl |     5.02-1+deb9u1 | http://deb.debian.org/debian stretch/main amd64 Packages\n"+
+			"        sl |     3.03-17+b2 | http://deb.debian.org/debian jessie/main amd64 Packages\n"+
+			"        sl |     5.10-1 | http://deb.debian.org/debian buster/main amd64 Packages\n", "", 0)
+	// the arguments are passed to apt-get as they are, without the shell.
+	r.On(installArgs("sl=5.02-1+deb9u1")...).Return("", "", 0)
+	r.On(dpkgQueryArgs("sl")...).Return("install ok installed\n5.02-1+deb9u1", "", 0)
+
+	s := &packagemanager.InstalledForDebian{&packagemanager.Installed{Name: "sl", Version: ">= 3.1, < 5.10"}}
+	if err := s.Apply(); err != nil {
+		t.Errorf("Apply: %v, commands: %v", err, r.Commands())
+	}
+	if err := s.Test(); err != nil {
+		t.Errorf("Test: %v", err)
+	}
+
+	s.Version = "~> 5.10"
+	if err := s.Test(); err == nil {
+		t.Errorf("Test: got no error when the installed version does not satisfy the constraint")
+	}
+}
+
+func TestInstalledBatchForDebian(t *testing.T) {
+	r, restore := fakeAPT()
+	defer restore()
+	r.On(installArgs("sl", "cowsay")...).Return("", "", 0)
+
+	s := &packagemanager.InstalledBatchForDebian{&packagemanager.InstalledBatch{
+		Packages: []*packagemanager.Installed{{Name: "sl"}, {Name: "cowsay"}},
+	}}
+	if err := s.Apply(); err != nil {
+		t.Errorf("Apply: %v", err)
+	}
+	if got := r.Commands(); len(got) != 1 {
+		t.Errorf("got commands %v, expected just 1 apt-get", got)
+	}
+}
+
+func TestRemovedForDebian(t *testing.T) {
+	r, restore := fakeAPT()
+	defer restore()
+	r.On(apt.DPKGQueryPath, "--show", "sl").Return("sl\t5.02-1\n", "", 0)
+	r.On(apt.APTGetPath, "remove", "-y", "sl").Return("", "", 0)
+	r.On(apt.DPKGQueryPath, "--show", "sl").Return("", "dpkg-query: no packages found matching sl\n", 1)
+
+	s := &packagemanager.RemovedForDebian{&packagemanager.Removed{Name: "sl"}}
+	if err := s.Test(); err == nil {
+		t.Errorf("Test: got no error when the package is installed")
+	}
+	if err := s.Apply(); err != nil {
+		t.Errorf("Apply: %v", err)
+	}
+	if err := s.Test(); err != nil {
+		t.Errorf("Test: %v", err)
+	}
+}
+
+func TestHeldForDebian(t *testing.T) {
+	r, restore := fakeAPT()
+	defer restore()
+	r.On(apt.APTMarkPath, "showhold").Return("linux-image-amd64\n", "", 0)
+	r.On(apt.APTMarkPath, "hold", "postgresql").Return("postgresql set on hold.\n", "", 0)
+	r.On(apt.APTMarkPath, "showhold").Return("linux-image-amd64\npostgresql\n", "", 0)
+
+	s := &packagemanager.HeldForDebian{&packagemanager.Held{Name: "postgresql", Hold: true}}
+	if err := s.Test(); err == nil {
+		t.Errorf("Test: got no error when the package is not held")
+	}
+	if err := s.Apply(); err != nil {
+		t.Errorf("Apply: %v", err)
+	}
+	if err := s.Test(); err != nil {
+		t.Errorf("Test: %v", err)
+	}
+
+	expected := []string{
+		apt.APTMarkPath + " showhold",
+		apt.APTMarkPath + " hold postgresql",
+		apt.APTMarkPath + " showhold",
+	}
+	if got := r.Commands(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("got commands %v, expected %v", got, expected)
+	}
+}
+
+func TestAPTRepositoryAbsenceExpiresLists(t *testing.T) {
+	requireDebian(t)
+	dir, err := ioutil.TempDir("", "sources_list_d_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	orig := packagemanager.SourcesListDir
+	packagemanager.SourcesListDir = dir
+	defer func() { packagemanager.SourcesListDir = orig }()
+
+	s := &packagemanager.APTRepositoryAbsence{Name: "docker"}
+	if err := ioutil.WriteFile(s.Path(), []byte("deb https://download.docker.com/linux/debian stretch stable\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Test(); err == nil {
+		t.Errorf("Test: got no error when the source list exists")
+	}
+	if err := s.Apply(); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if err := s.Test(); err != nil {
+		t.Errorf("Test: %v", err)
+	}
+
+	r, restore := fakeAPT()
+	defer restore()
+	r.On(apt.APTGetPath, "update").Return("", "", 0)
+	r.On(installArgs("sl")...).Return("", "", 0)
+	i := &packagemanager.InstalledForDebian{&packagemanager.Installed{Name: "sl"}}
+	if err := i.Apply(); err != nil {
+		t.Errorf("Apply: %v", err)
+	}
+	expected := []string{apt.APTGetPath + " update", strings.Join(installArgs("sl"), " ")}
+	if got := r.Commands(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("got commands %v, expected %v", got, expected)
+	}
+}
+
+func TestDebconfReconfiguresInstalledPackage(t *testing.T) {
+	requireDebian(t)
+	r, restore := fakeAPT()
+	defer restore()
+	r.On(apt.DebconfSetSelectionsPath).Return("", "", 0)
+	r.On(dpkgQueryArgs("tzdata")...).Return("install ok installed\n2024a-0+deb12u1", "", 0)
+	r.On(apt.DPKGReconfigurePath, "-f", "noninteractive", "tzdata").Return("", "", 0)
+
+	s := &packagemanager.Debconf{
+		Package:    "tzdata",
+		Selections: []packagemanager.DebconfSelection{{Question: "tzdata/Areas", Type: "select", Value: "Asia"}},
+	}
+	if err := s.Apply(); err != nil {
+		t.Errorf("Apply: %v", err)
+	}
+	expected := []string{
+		apt.DebconfSetSelectionsPath,
+		strings.Join(dpkgQueryArgs("tzdata"), " "),
+		apt.DPKGReconfigurePath + " -f noninteractive tzdata",
+	}
+	if got := r.Commands(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("got commands %v, expected %v", got, expected)
+	}
+}
+
+func TestDebconfBeforeInstall(t *testing.T) {
+	requireDebian(t)
+	r, restore := fakeAPT()
+	defer restore()
+	r.On(apt.DebconfSetSelectionsPath).Return("", "", 0)
+	r.On(dpkgQueryArgs("tzdata")...).Return("", "dpkg-query: no packages found matching tzdata\n", 1)
+
+	s := &packagemanager.Debconf{
+		Package:    "tzdata",
+		Selections: []packagemanager.DebconfSelection{{Question: "tzdata/Areas", Type: "select", Value: "Asia"}},
+	}
+	if err := s.Apply(); err != nil {
+		t.Errorf("Apply: %v", err)
+	}
+	if got := len(r.Calls); got != 2 {
+		t.Errorf("got commands %v, expected not to reconfigure", r.Commands())
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2015 MICHII Shunsuke. All rights reserved.
+
+// +build linux
+
+package packagemanager
+
+import (
+	"github.com/harukasan/orchestra-pit/state/packagemanager/yum"
+)
+
+var yumUpdate = &updateOnce{update: yum.Update}
+
+// InstalledForRedHat implements state of which the package is installed for
+// the platform of Red Hat Enterprise Linux or its derivatives.
+type InstalledForRedHat struct {
+	*Installed
+}
+
+// Apply tries to install the package with yum for Red Hat Enterprise Linux or
+// its derivatives. If the installation fails, it returns an error.
+func (s *InstalledForRedHat) Apply() error {
+	if s.Update {
+		if err := yumUpdate.do(); err != nil {
+			return err
+		}
+	}
+	return yum.Install(s.Name, s.Version)
+}
+
+// Test checks whether the package is successfully installed on the platform of
+// Red Hat Enterprise Linux or its derivatives.
+func (s *InstalledForRedHat) Test() error {
+	return yum.IsInstalled(s.Name, s.Version)
+}
+
+// InstalledBatchForRedHat implements state of which all of the packages are
+// installed for the platform of Red Hat Enterprise Linux or its derivatives.
+type InstalledBatchForRedHat struct {
+	*InstalledBatch
+}
+
+// Apply tries to install all of the packages by running yum once. If the
+// installation fails, it returns an error.
+func (s *InstalledBatchForRedHat) Apply() error {
+	pkgs := make([]yum.Package, 0, len(s.Packages))
+	for _, p := range s.Packages {
+		if p.Update {
+			if err := yumUpdate.do(); err != nil {
+				return err
+			}
+		}
+		pkgs = append(pkgs, yum.Package{Name: p.Name, Version: p.Version})
+	}
+	return yum.InstallPackages(pkgs)
+}
+
+// RemovedForRedHat implements state of which the package is removed for the
+// platform of Red Hat Enterprise Linux or its derivatives.
+type RemovedForRedHat struct {
+	*Removed
+}
+
+// Apply tries to remove the package with yum on the Red Hat Enterprise Linux or
+// its derivatives. If the removing the package fails, it returns an error.
+func (s *RemovedForRedHat) Apply() error {
+	return yum.Remove(s.Name)
+}
+
+// Test checks whether the package is absent on the Red Hat Enterprise Linux or
+// its derivatives.
+func (s *RemovedForRedHat) Test() error {
+	return yum.IsNotInstalled(s.Name)
+}
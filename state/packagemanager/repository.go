@@ -0,0 +1,104 @@
+// Copyright 2015 MICHII Shunsuke. All rights reserved.
+
+package packagemanager
+
+import (
+	"strings"
+)
+
+// SourcesListDir specifies the directory of the source lists of APT.
+var SourcesListDir = "/etc/apt/sources.list.d"
+
+// APTRepository manages the source list file of APT which describes the
+// repository of packages.
+//
+// Name specifies the name of the source list. The source list is written to the
+// file named Name + ".list" under SourcesListDir.
+//
+// Type specifies the type of the archive, "deb" or "deb-src". If Type is not
+// specified, "deb" is assumed.
+//
+// URI, Suite and Components specifies the location of the repository.
+//
+// Arch specifies the architectures of the packages to download from the
+// repository. If Arch is empty, APT downloads for the default architectures.
+//
+// SignedBy specifies the path of the keyring file which is used to verify the
+// repository.
+//
+// When the source list is changed, the package lists are updated before the
+// next installation of the packages.
+type APTRepository struct {
+	Name       string
+	Type       string
+	URI        string
+	Suite      string
+	Components []string
+	Arch       []string
+	SignedBy   string
+}
+
+// Apply tries to write the source list. If failed to write the file, it returns
+// an error.
+func (s *APTRepository) Apply() error {
+	return s.apply()
+}
+
+// Test tests whether the source list has the requested content.
+func (s *APTRepository) Test() error {
+	return s.test()
+}
+
+// Path returns the file path of the source list.
+func (s *APTRepository) Path() string {
+	return strings.TrimRight(SourcesListDir, "/") + "/" + s.Name + ".list"
+}
+
+// Content returns the line of the source list.
+func (s *APTRepository) Content() []byte {
+	t := s.Type
+	if t == "" {
+		t = "deb"
+	}
+	line := []string{t}
+
+	options := []string{}
+	if len(s.Arch) > 0 {
+		options = append(options, "arch="+strings.Join(s.Arch, ","))
+	}
+	if s.SignedBy != "" {
+		options = append(options, "signed-by="+s.SignedBy)
+	}
+	if len(options) > 0 {
+		line = append(line, "["+strings.Join(options, " ")+"]")
+	}
+
+	line = append(line, s.URI, s.Suite)
+	line = append(line, s.Components...)
+	return []byte(strings.Join(line, " ") + "\n")
+}
+
+// APTRepositoryAbsence manages the source list file of APT to be absent.
+//
+// Name specifies the name of the source list, see APTRepository.
+//
+// When the source list is removed, the package lists are updated before the
+// next installation of the packages.
+type APTRepositoryAbsence struct {
+	Name string
+}
+
+// Apply tries to remove the source list.
+func (s *APTRepositoryAbsence) Apply() error {
+	return s.apply()
+}
+
+// Test tests whether the source list does not exist.
+func (s *APTRepositoryAbsence) Test() error {
+	return s.test()
+}
+
+// Path returns the file path of the source list.
+func (s *APTRepositoryAbsence) Path() string {
+	return (&APTRepository{Name: s.Name}).Path()
+}
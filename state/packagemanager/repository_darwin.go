@@ -0,0 +1,23 @@
+// Copyright 2015 MICHII Shunsuke. All rights reserved.
+
+// +build darwin
+
+package packagemanager
+
+import "errors"
+
+func (s *APTRepository) apply() error {
+	return errors.New("unsupported platform")
+}
+
+func (s *APTRepository) test() error {
+	return errors.New("unsupported platform")
+}
+
+func (s *APTRepositoryAbsence) apply() error {
+	return errors.New("unsupported platform")
+}
+
+func (s *APTRepositoryAbsence) test() error {
+	return errors.New("unsupported platform")
+}
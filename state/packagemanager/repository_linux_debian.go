@@ -0,0 +1,64 @@
+// Copyright 2015 MICHII Shunsuke. All rights reserved.
+
+// +build linux
+
+package packagemanager
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+func (s *APTRepository) apply() error {
+	if err := supportedForDebian(); err != nil {
+		return err
+	}
+
+	if err := writeFile(s.Path(), s.Content(), 0644); err != nil {
+		return err
+	}
+
+	// the package lists should be updated because the source list is changed.
+	update.expire()
+	return nil
+}
+
+func (s *APTRepository) test() error {
+	if err := supportedForDebian(); err != nil {
+		return err
+	}
+	content, err := ioutil.ReadFile(s.Path())
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(content, s.Content()) {
+		return errors.New("the source list is different from the requested")
+	}
+	return nil
+}
+
+func (s *APTRepositoryAbsence) apply() error {
+	if err := supportedForDebian(); err != nil {
+		return err
+	}
+	if err := os.Remove(s.Path()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	// the package lists should be updated because the source list is removed.
+	update.expire()
+	return nil
+}
+
+func (s *APTRepositoryAbsence) test() error {
+	if err := supportedForDebian(); err != nil {
+		return err
+	}
+	if _, err := os.Lstat(s.Path()); !os.IsNotExist(err) {
+		return fmt.Errorf("the source list %s exists", s.Path())
+	}
+	return nil
+}
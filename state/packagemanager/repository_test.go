@@ -0,0 +1,28 @@
+// Copyright 2015 MICHII Shunsuke. All rights reserved.
+
+package packagemanager_test
+
+import (
+	"testing"
+
+	"github.com/harukasan/orchestra-pit/state/packagemanager"
+)
+
+func TestAPTRepositoryContent(t *testing.T) {
+	s := &packagemanager.APTRepository{
+		Name:       "docker",
+		URI:        "https://download.docker.com/linux/debian",
+		Suite:      "stretch",
+		Components: []string{"stable", "edge"},
+		Arch:       []string{"amd64"},
+		SignedBy:   "/usr/share/keyrings/docker.gpg",
+	}
+
+	expected := "deb [arch=amd64 signed-by=/usr/share/keyrings/docker.gpg] https://download.docker.com/linux/debian stretch stable edge\n"
+	if got := string(s.Content()); got != expected {
+		t.Errorf("got %q, expected %q", got, expected)
+	}
+	if got := s.Path(); got != "/etc/apt/sources.list.d/docker.list" {
+		t.Errorf("got Path %v, expected /etc/apt/sources.list.d/docker.list", got)
+	}
+}
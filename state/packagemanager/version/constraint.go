@@ -0,0 +1,159 @@
+// Copyright 2015 MICHII Shunsuke. All rights reserved.
+
+package version
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Constraint represents the requirement of the package version.
+//
+// A constraint consists of the comma separated terms, and the version should
+// satisfy all of the terms. Each term is the pair of an operator and a version
+// such as ">= 2.4". Following operators are supported:
+//
+//	=   equals to the version (the operator can be omitted)
+//	!=  does not equal to the version
+//	>   newer than the version
+//	>=  newer than or equals to the version
+//	<   older than the version
+//	<=  older than or equals to the version
+//	~>  newer than or equals to the version, but older than the next release
+//	    of the version, e.g., "~> 1.2" means ">= 1.2, < 2"
+type Constraint struct {
+	terms []term
+}
+
+type term struct {
+	op      string
+	version string
+}
+
+var operators = []string{"==", "!=", ">=", "<=", "~>", "=", ">", "<"}
+
+// ParseConstraint parses the string s as a constraint.
+func ParseConstraint(s string) (*Constraint, error) {
+	c := &Constraint{}
+	for _, t := range strings.Split(s, ",") {
+		t = strings.TrimSpace(t)
+		op := "="
+		for _, o := range operators {
+			if strings.HasPrefix(t, o) {
+				op = o
+				t = strings.TrimSpace(t[len(o):])
+				break
+			}
+		}
+		if op == "==" {
+			op = "="
+		}
+		if t == "" || strings.ContainsAny(t, " \t") {
+			return nil, fmt.Errorf("invalid version constraint: %q", s)
+		}
+		if op == "~>" {
+			next, err := nextRelease(t)
+			if err != nil {
+				return nil, err
+			}
+			c.terms = append(c.terms, term{">=", t}, term{"<", next})
+			continue
+		}
+		c.terms = append(c.terms, term{op, t})
+	}
+	return c, nil
+}
+
+// IsConstraint returns whether the string s has any operator. The string
+// which has no operators is a plain version, and it can be used to install the
+// package directly.
+func IsConstraint(s string) bool {
+	for _, t := range strings.Split(s, ",") {
+		t = strings.TrimSpace(t)
+		for _, o := range operators {
+			if strings.HasPrefix(t, o) && o != "=" && o != "==" {
+				return true
+			}
+		}
+	}
+	return strings.Contains(s, ",")
+}
+
+// nextRelease returns the version which increments the second-to-last
+// component of the version v, e.g., "1.2.3" to "1.3", or "1.2" to "2".
+func nextRelease(v string) (string, error) {
+	_, version, _ := splitVersion(v)
+	parts := strings.Split(version, ".")
+	if len(parts) > 1 {
+		parts = parts[:len(parts)-1]
+	}
+	last := parts[len(parts)-1]
+	n := 0
+	for n < len(last) && isDigit(last[n]) {
+		n++
+	}
+	i, err := strconv.Atoi(last[:n])
+	if err != nil {
+		return "", fmt.Errorf("invalid version for ~> operator: %q", v)
+	}
+	parts[len(parts)-1] = strconv.Itoa(i + 1)
+	next := strings.Join(parts, ".")
+	if i := strings.IndexByte(v, ':'); i >= 0 {
+		next = v[:i+1] + next
+	}
+	return next, nil
+}
+
+// Match tests whether the version v satisfies the constraint. The versions are
+// compared by the compare function.
+func (c *Constraint) Match(v string, compare CompareFunc) bool {
+	for _, t := range c.terms {
+		r := compare(v, t.version)
+		var ok bool
+		switch t.op {
+		case "=":
+			ok = r == 0
+		case "!=":
+			ok = r != 0
+		case ">":
+			ok = r > 0
+		case ">=":
+			ok = r >= 0
+		case "<":
+			ok = r < 0
+		case "<=":
+			ok = r <= 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Latest returns the newest version which satisfies the constraint from the
+// candidates. If no candidate satisfies the constraint, it returns false.
+func (c *Constraint) Latest(candidates []string, compare CompareFunc) (string, bool) {
+	latest := ""
+	found := false
+	for _, v := range candidates {
+		if !c.Match(v, compare) {
+			continue
+		}
+		if !found || compare(v, latest) > 0 {
+			latest = v
+			found = true
+		}
+	}
+	return latest, found
+}
+
+// String returns the normalized string of the constraint.
+func (c *Constraint) String() string {
+	terms := make([]string, len(c.terms))
+	for i, t := range c.terms {
+		terms[i] = t.op + " " + t.version
+	}
+	return strings.Join(terms, ", ")
+}
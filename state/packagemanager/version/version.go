@@ -0,0 +1,236 @@
+// Copyright 2015 MICHII Shunsuke. All rights reserved.
+
+/*
+Package version implements comparison of package versions and version
+constraints.
+
+Following comparison semantics are supported:
+
+  - Debian (dpkg)  ... epochs, upstream versions, revisions and tildes
+  - RPM    (rpm)   ... epochs, versions, releases, tildes and carets
+*/
+package version
+
+import (
+	"strings"
+	"unicode"
+)
+
+// CompareFunc compares the version a and b. It returns a negative number when
+// a is older than b, zero when a equals b, or a positive number when a is newer
+// than b.
+type CompareFunc func(a, b string) int
+
+// CompareDebian compares the versions in the manner of dpkg. A version has the
+// form of [epoch:]upstream_version[-debian_revision].
+func CompareDebian(a, b string) int {
+	ae, au, ar := splitVersion(a)
+	be, bu, br := splitVersion(b)
+	if c := compareEpoch(ae, be); c != 0 {
+		return c
+	}
+	if c := compareDebianPart(au, bu); c != 0 {
+		return c
+	}
+	return compareDebianPart(ar, br)
+}
+
+// splitVersion splits the version string into epoch, version and revision
+// (release) parts.
+func splitVersion(v string) (epoch, version, revision string) {
+	version = v
+	if i := strings.IndexByte(version, ':'); i >= 0 {
+		epoch = version[:i]
+		version = version[i+1:]
+	}
+	if i := strings.LastIndexByte(version, '-'); i >= 0 {
+		revision = version[i+1:]
+		version = version[:i]
+	}
+	return
+}
+
+func compareEpoch(a, b string) int {
+	return compareNumbers(strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0"))
+}
+
+// compareNumbers compares the strings which consist of digits without leading
+// zeros.
+func compareNumbers(a, b string) int {
+	if len(a) != len(b) {
+		return len(a) - len(b)
+	}
+	return strings.Compare(a, b)
+}
+
+// debianOrder returns the weight of the character in the non-digit part of the
+// version. The tilde sorts before anything, even the end of the part, and
+// letters sort before non-letters.
+func debianOrder(s string, i int) int {
+	if i >= len(s) {
+		return 0
+	}
+	c := s[i]
+	switch {
+	case c == '~':
+		return -1
+	case isDigit(c):
+		return 0
+	case unicode.IsLetter(rune(c)):
+		return int(c)
+	}
+	return int(c) + 256
+}
+
+// compareDebianPart implements verrevcmp of dpkg.
+func compareDebianPart(a, b string) int {
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		for (i < len(a) && !isDigit(a[i])) || (j < len(b) && !isDigit(b[j])) {
+			ac, bc := debianOrder(a, i), debianOrder(b, j)
+			if ac != bc {
+				return ac - bc
+			}
+			i++
+			j++
+		}
+		si := i
+		for i < len(a) && isDigit(a[i]) {
+			i++
+		}
+		sj := j
+		for j < len(b) && isDigit(b[j]) {
+			j++
+		}
+		if c := compareNumbers(strings.TrimLeft(a[si:i], "0"), strings.TrimLeft(b[sj:j], "0")); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// CompareRPM compares the versions in the manner of rpm. A version has the form
+// of [epoch:]version[-release].
+func CompareRPM(a, b string) int {
+	ae, av, ar := splitVersion(a)
+	be, bv, br := splitVersion(b)
+	if c := compareEpoch(ae, be); c != 0 {
+		return c
+	}
+	if c := compareRPMPart(av, bv); c != 0 {
+		return c
+	}
+	// the release is compared only if the both versions have it.
+	if ar == "" || br == "" {
+		return 0
+	}
+	return compareRPMPart(ar, br)
+}
+
+// compareRPMPart implements rpmvercmp of rpm.
+func compareRPMPart(a, b string) int {
+	if a == b {
+		return 0
+	}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		for i < len(a) && !isAlnum(a[i]) && a[i] != '~' && a[i] != '^' {
+			i++
+		}
+		for j < len(b) && !isAlnum(b[j]) && b[j] != '~' && b[j] != '^' {
+			j++
+		}
+
+		// the tilde sorts before anything.
+		if (i < len(a) && a[i] == '~') || (j < len(b) && b[j] == '~') {
+			if i >= len(a) || a[i] != '~' {
+				return 1
+			}
+			if j >= len(b) || b[j] != '~' {
+				return -1
+			}
+			i++
+			j++
+			continue
+		}
+
+		// the caret sorts after the end of the version, but before anything else.
+		if (i < len(a) && a[i] == '^') || (j < len(b) && b[j] == '^') {
+			if i >= len(a) {
+				return -1
+			}
+			if j >= len(b) {
+				return 1
+			}
+			if a[i] != '^' {
+				return 1
+			}
+			if b[j] != '^' {
+				return -1
+			}
+			i++
+			j++
+			continue
+		}
+
+		if i >= len(a) || j >= len(b) {
+			break
+		}
+
+		si, sj := i, j
+		numeric := isDigit(a[i])
+		if numeric {
+			for i < len(a) && isDigit(a[i]) {
+				i++
+			}
+			for j < len(b) && isDigit(b[j]) {
+				j++
+			}
+		} else {
+			for i < len(a) && isLetter(a[i]) {
+				i++
+			}
+			for j < len(b) && isLetter(b[j]) {
+				j++
+			}
+		}
+
+		// the segments of the different types; numeric one is newer.
+		if sj == j {
+			if numeric {
+				return 1
+			}
+			return -1
+		}
+
+		var c int
+		if numeric {
+			c = compareNumbers(strings.TrimLeft(a[si:i], "0"), strings.TrimLeft(b[sj:j], "0"))
+		} else {
+			c = strings.Compare(a[si:i], b[sj:j])
+		}
+		if c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case i >= len(a) && j >= len(b):
+		return 0
+	case i >= len(a):
+		return -1
+	}
+	return 1
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+func isAlnum(c byte) bool {
+	return isDigit(c) || isLetter(c)
+}
@@ -0,0 +1,138 @@
+// Copyright 2015 MICHII Shunsuke. All rights reserved.
+
+package version_test
+
+import (
+	"testing"
+
+	"github.com/harukasan/orchestra-pit/state/packagemanager/version"
+)
+
+func sign(i int) int {
+	switch {
+	case i < 0:
+		return -1
+	case i > 0:
+		return 1
+	}
+	return 0
+}
+
+func TestCompareDebian(t *testing.T) {
+	cases := []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.0", "1.0", 0},
+		{"1.1", "1.10", -1},
+		{"1.10", "1.9", 1},
+		{"1.0~rc1", "1.0", -1},
+		{"1.0~rc1", "1.0~rc2", -1},
+		{"1.0~~", "1.0~", -1},
+		{"1.0", "1.0-1", -1},
+		{"1.0-1", "1.0-2", -1},
+		{"1:0.9", "2.0", 1},
+		{"0:1.0", "1.0", 0},
+		{"1.0a", "1.0+", -1},
+		{"1.0+dfsg-1", "1.0-1", 1},
+		{"2.4.7-1ubuntu1", "2.4.7-1", 1},
+		{"001.02", "1.2", 0},
+	}
+	for _, c := range cases {
+		if got := sign(version.CompareDebian(c.a, c.b)); got != c.expected {
+			t.Errorf("CompareDebian(%q, %q): got %d, expected %d", c.a, c.b, got, c.expected)
+		}
+	}
+}
+
+func TestCompareRPM(t *testing.T) {
+	cases := []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.0", "1.0", 0},
+		{"1.1", "1.10", -1},
+		{"1.0", "1.0.1", -1},
+		{"1.0a", "1.0", 1},
+		{"1.0~rc1", "1.0", -1},
+		{"1.0^git1", "1.0", 1},
+		{"1.0^git1", "1.0.1", -1},
+		{"1.0-1.el7", "1.0-2.el7", -1},
+		{"1.0", "1.0-2.el7", 0},
+		{"1:1.0", "2.0", 1},
+		{"2.a", "2.1", -1},
+		{"1_0", "1.0", 0},
+	}
+	for _, c := range cases {
+		if got := sign(version.CompareRPM(c.a, c.b)); got != c.expected {
+			t.Errorf("CompareRPM(%q, %q): got %d, expected %d", c.a, c.b, got, c.expected)
+		}
+	}
+}
+
+func TestConstraint(t *testing.T) {
+	cases := []struct {
+		constraint string
+		version    string
+		expected   bool
+	}{
+		{"1.1", "1.1", true},
+		{"1.1", "1.10", false},
+		{"= 1.1", "1.1", true},
+		{">= 2.4", "2.4", true},
+		{">= 2.4", "2.10", true},
+		{">= 2.4", "2.4~rc1", false},
+		{"< 3", "2.99", true},
+		{"< 3", "3.0", false},
+		{">= 2.4, < 3", "2.5-1", true},
+		{">= 2.4, < 3", "3.1", false},
+		{"~> 1.2", "1.9", true},
+		{"~> 1.2", "2.0", false},
+		{"~> 1.2.3", "1.2.10", true},
+		{"~> 1.2.3", "1.3.0", false},
+		{"!= 1.0", "1.0", false},
+		{"> 1:1.0", "2.0", false},
+	}
+	for _, c := range cases {
+		cons, err := version.ParseConstraint(c.constraint)
+		if err != nil {
+			t.Errorf("ParseConstraint(%q): got error: %v", c.constraint, err)
+			continue
+		}
+		if got := cons.Match(c.version, version.CompareDebian); got != c.expected {
+			t.Errorf("%q matches %q: got %v, expected %v", c.constraint, c.version, got, c.expected)
+		}
+	}
+}
+
+func TestConstraintLatest(t *testing.T) {
+	cons, err := version.ParseConstraint("~> 1.2")
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	candidates := []string{"1.1-1", "1.2-1", "1.10-1", "1.9-2", "2.0-1"}
+	latest, ok := cons.Latest(candidates, version.CompareDebian)
+	if !ok {
+		t.Fatalf("no candidate satisfies the constraint")
+	}
+	if latest != "1.10-1" {
+		t.Errorf("got %s, expected 1.10-1", latest)
+	}
+}
+
+func TestIsConstraint(t *testing.T) {
+	cases := map[string]bool{
+		"1.0":        false,
+		"= 1.0":      false,
+		">= 1.0":     true,
+		"~> 1.0":     true,
+		"> 1.0, < 2": true,
+		"1:2.0-1":    false,
+		"!= 1.0-1":   true,
+	}
+	for s, expected := range cases {
+		if got := version.IsConstraint(s); got != expected {
+			t.Errorf("IsConstraint(%q): got %v, expected %v", s, got, expected)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright 2015 MICHII Shunsuke. All rights reserved.
+
+// +build linux
+
+/*
+Package yum provides command interface of yum and rpm commands for Red Hat
+Enterprise Linux and its derivatives.
+*/
+package yum
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/harukasan/orchestra-pit/state"
+	"github.com/harukasan/orchestra-pit/state/exec"
+	"github.com/harukasan/orchestra-pit/state/packagemanager/version"
+)
+
+// Runner specifies the CommandRunner to run the commands of yum and rpm.
+var Runner = exec.DefaultRunner
+
+// YumPath specifies the file path of the yum command
+var YumPath = "/usr/bin/yum"
+
+// RPMPath specifies the file path of the rpm command
+var RPMPath = "/usr/bin/rpm"
+
+// yumCommand returns the command of yum. If state.Root is the alternate root,
+// the option is added to operate the packages under the root.
+func yumCommand(name string, arg ...string) *exec.Cmd {
+	if state.IsAlternateRoot() {
+		arg = append([]string{"--installroot=" + state.Root}, arg...)
+	}
+	return exec.Command(name, arg...)
+}
+
+// rpmCommand returns the command of rpm. If state.Root is the alternate root,
+// the option is added to operate the packages under the root.
+func rpmCommand(name string, arg ...string) *exec.Cmd {
+	if state.IsAlternateRoot() {
+		arg = append([]string{"--root", state.Root}, arg...)
+	}
+	return exec.Command(name, arg...)
+}
+
+// Package specifies the name and version of the package to install. The
+// version can be a constraint such as ">= 2.4, < 3". See the version package
+// for the syntax of constraints.
+type Package struct {
+	Name    string
+	Version string
+}
+
+// Install executes the yum command to install the named package. If the
+// version is a constraint, the newest candidate which satisfies the constraint
+// is installed.
+func Install(name string, v string) error {
+	return InstallPackages([]Package{{Name: name, Version: v}})
+}
+
+// InstallPackages executes the yum command once to install all of the given
+// packages in one transaction.
+func InstallPackages(pkgs []Package) error {
+	args := []string{"install", "-y"}
+	for _, p := range pkgs {
+		v, err := resolveVersion(p.Name, p.Version)
+		if err != nil {
+			return err
+		}
+		if v != "" {
+			args = append(args, p.Name+"-"+v)
+		} else {
+			args = append(args, p.Name)
+		}
+	}
+	cmd := yumCommand(YumPath, args...)
+	cmd.Env = append(cmd.Env, os.Environ()...)
+	_, _, err := Runner.Run(cmd)
+	return err
+}
+
+// resolveVersion returns the version to pass to yum command. If the version is
+// a constraint, it returns the newest candidate which satisfies the
+// constraint.
+func resolveVersion(name string, v string) (string, error) {
+	if !version.IsConstraint(v) {
+		return strings.TrimSpace(strings.TrimLeft(v, "=")), nil
+	}
+	c, err := version.ParseConstraint(v)
+	if err != nil {
+		return "", err
+	}
+	candidates, err := Candidates(name)
+	if err != nil {
+		return "", err
+	}
+	latest, ok := c.Latest(candidates, version.CompareRPM)
+	if !ok {
+		return "", fmt.Errorf("no candidate of the package %s satisfies the version: %s", name, c)
+	}
+	return latest, nil
+}
+
+// Candidates returns the versions of the named package which are available to
+// install.
+func Candidates(name string) ([]string, error) {
+	cmd := yumCommand(YumPath, "list", "available", "--showduplicates", "--quiet", name)
+	cmd.Env = append(cmd.Env, os.Environ()...)
+	out, _, err := Runner.Run(cmd)
+	if err != nil {
+		return nil, err
+	}
+	return parseList(name, out), nil
+}
+
+// parseList parses the output of yum list command, which has the lines such as
+// "name.arch version-release repository".
+func parseList(name string, out []byte) []string {
+	versions := []string{}
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		if i := strings.LastIndexByte(fields[0], '.'); i < 0 || fields[0][:i] != name {
+			continue
+		}
+		versions = append(versions, fields[1])
+	}
+	return versions
+}
+
+// Remove executes the yum command to remove the named package.
+func Remove(name string) error {
+	cmd := yumCommand(YumPath, "remove", "-y", name)
+	cmd.Env = append(cmd.Env, os.Environ()...)
+	_, _, err := Runner.Run(cmd)
+	return err
+}
+
+// IsInstalled tests whether the named package is installed on the system.
+// If the named package is not installed, or the execution fails, it returns
+// an error.
+//
+// If the version is specified, it checks whether the installed version
+// satisfies the version. The version can be a constraint, and the versions are
+// compared in the manner of rpm.
+//
+// To test whether the package is NOT installed, Use IsNotInstalled function
+// instead of this.
+func IsInstalled(name string, v string) error {
+	cmd := rpmCommand(RPMPath, "--query", "--queryformat", "%{EPOCH}:%{VERSION}-%{RELEASE}\\n", name)
+	cmd.Env = append(cmd.Env, os.Environ()...)
+	out, _, err := Runner.Run(cmd)
+	if err != nil {
+		return errors.New("the package is not installed")
+	}
+	if v == "" {
+		return nil
+	}
+	c, err := version.ParseConstraint(v)
+	if err != nil {
+		return err
+	}
+	// multiple versions can be installed for such as kernel packages.
+	installed := []string{}
+	for _, line := range bytes.Split(bytes.TrimSpace(out), []byte("\n")) {
+		iv := strings.TrimPrefix(string(line), "(none):")
+		if c.Match(iv, version.CompareRPM) {
+			return nil
+		}
+		installed = append(installed, iv)
+	}
+	return fmt.Errorf("the version %s is installed, but %s is requested", strings.Join(installed, ", "), c)
+}
+
+// IsNotInstalled tests whether the named package is not installed on the system.
+// If the package is installed or execution fails, it returns an error.
+func IsNotInstalled(name string) error {
+	cmd := rpmCommand(RPMPath, "--query", name)
+	cmd.Env = append(cmd.Env, os.Environ()...)
+	out, stderr, err := Runner.Run(cmd)
+	if err != nil {
+		if bytes.Contains(out, []byte("is not installed")) || bytes.Contains(stderr, []byte("is not installed")) {
+			return nil
+		}
+		return err
+	}
+	return errors.New("the package is installed")
+}
+
+// Update executes updating the metadata cache of yum repositories.
+func Update() error {
+	_, _, err := Runner.Run(yumCommand(YumPath, "makecache"))
+	return err
+}
@@ -5,6 +5,12 @@ Package platform implements gathering information about the platform.
 */
 package platform
 
+import "github.com/harukasan/orchestra-pit/state/exec"
+
+// Runner specifies the CommandRunner to run the commands to retrieve the
+// platform information.
+var Runner = exec.DefaultRunner
+
 // Name represents the platform name.
 type Name string
 
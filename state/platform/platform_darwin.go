@@ -12,7 +12,7 @@ import (
 
 // Identify detects the platform and returns Info of the platform.
 func Identify() (state.Facts, error) {
-	out, err := exec.Command("/usr/bin/sw_vers").Output()
+	out, _, err := Runner.Run(exec.Command("/usr/bin/sw_vers"))
 	if err != nil {
 		return nil, err
 	}
@@ -10,6 +10,7 @@ import (
 	"os"
 	"sync"
 
+	"github.com/harukasan/orchestra-pit/state"
 	"github.com/harukasan/orchestra-pit/state/exec"
 )
 
@@ -17,6 +18,9 @@ import (
 // platform.
 var ErrNotIdentified = errors.New("the platform could not identified")
 
+// LSBReleasePath specifies the file path of the lsb_release command.
+var LSBReleasePath = "/usr/bin/lsb_release"
+
 // identifyFunc is a function which identifies the platform and retrieves the
 // release information. If the platform could not identified, the function that
 // implements identifyFunc should return ErrNotIdentified.
@@ -28,7 +32,7 @@ var identifyFuncs = []identifyFunc{
 }
 
 // Identify detects the platform and returns Info of the platform.
-func Identify() (commands.Facts, error) {
+func Identify() (state.Facts, error) {
 	for _, f := range identifyFuncs {
 		info, err := f()
 		if err == nil {
@@ -43,7 +47,7 @@ func Identify() (commands.Facts, error) {
 
 // IdentifyLSBRelease tires to retrieve the release information of LSB. If the
 // platform is not supported for LSB, DetectLSBRelease returns nil.
-func IdentifyLSBRelease() (commands.Facts, error) {
+func IdentifyLSBRelease() (state.Facts, error) {
 	lsbInfoCache.RLock()
 	if lsbInfoCache.i != nil {
 		defer lsbInfoCache.RUnlock()
@@ -63,6 +67,20 @@ func IdentifyLSBRelease() (commands.Facts, error) {
 		return info, nil
 	}
 
+	info, err = readOSReleaseFile()
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+	} else if info != nil {
+		lsbInfoCache.i = info
+		return info, nil
+	}
+
+	// the command of the alternate root can not be executed.
+	if state.IsAlternateRoot() {
+		return nil, &os.PathError{Op: "open", Path: state.RootPath("/etc/lsb-release"), Err: os.ErrNotExist}
+	}
 	info, err = execLSBRelease()
 	if err != nil {
 		return nil, err
@@ -78,7 +96,7 @@ var lsbInfoCache struct {
 
 // readLSBFile reads the LSB release information from the lsb-release file.
 func readLSBFile() (*LSBInfo, error) {
-	file, err := os.Open("/etc/lsb-release")
+	file, err := os.Open(state.RootPath("/etc/lsb-release"))
 	if err != nil {
 		return nil, err
 	}
@@ -105,8 +123,50 @@ func readLSBFile() (*LSBInfo, error) {
 	}, nil
 }
 
+// osReleaseIDs maps the ID of the os-release file to the distributor ID of LSB.
+var osReleaseIDs = map[string]string{
+	"debian":    "Debian",
+	"ubuntu":    "Ubuntu",
+	"linuxmint": "LinuxMint",
+}
+
+// readOSReleaseFile reads the release information from the os-release file,
+// which is available on the most of the distributions using systemd.
+func readOSReleaseFile() (*LSBInfo, error) {
+	file, err := os.Open(state.RootPath("/etc/os-release"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	content, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := &LineParser{
+		Delimiter:  '=',
+		TrimSpaces: true,
+		TrimQuotes: true,
+	}
+	m, err := parser.Parse(content)
+	if err != nil {
+		return nil, err
+	}
+
+	id := string(m["NAME"])
+	if name, ok := osReleaseIDs[string(m["ID"])]; ok {
+		id = name
+	}
+	return &LSBInfo{
+		ID:          id,
+		Release:     string(m["VERSION_ID"]),
+		Codename:    string(m["VERSION_CODENAME"]),
+		Description: string(m["PRETTY_NAME"]),
+	}, nil
+}
+
 func execLSBRelease() (*LSBInfo, error) {
-	out, err := exec.Command("/usr/bin/lsb_release -a").Output()
+	out, _, err := Runner.Run(exec.Command(LSBReleasePath, "-a"))
 	if err != nil {
 		return nil, err
 	}
@@ -22,7 +22,7 @@ import (
 // If failed to identify the platform or the platform is not Debian or the
 // derivatives of Debian, IdentifyDebianRelease returns an ErrNotIdentifier.
 func IdentifyDebianRelease() (*Info, error) {
-	file, err := os.Open("/etc/debian_version")
+	file, err := os.Open(state.RootPath("/etc/debian_version"))
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, ErrNotIdentified
@@ -38,7 +38,11 @@ func IdentifyDebianRelease() (*Info, error) {
 			return nil, err
 		}
 	} else {
-		return identifyDebianDerivertives(lsb)
+		info, err := identifyDebianDerivertives(lsb)
+		if err != nil || info.Platform != PlatformDebian {
+			return info, err
+		}
+		// the debian_version file has the detailed version of Debian.
 	}
 
 	b, err := ioutil.ReadAll(file)
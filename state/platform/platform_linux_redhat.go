@@ -9,6 +9,8 @@ import (
 	"io/ioutil"
 	"os"
 	"regexp"
+
+	"github.com/harukasan/orchestra-pit/state"
 )
 
 // IdentifyRedHatRelease tires to identify the derivretives of Red Hat Linux.
@@ -20,7 +22,7 @@ import (
 // If failed to identify the platform or the platform is the derivatives of
 // Red Hat Linux, IdentifyRedHatRelease returns an ErrNotIdentifier.
 func IdentifyRedHatRelease() (*Info, error) {
-	file, err := os.Open("/etc/redhat-release")
+	file, err := os.Open(state.RootPath("/etc/redhat-release"))
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, ErrNotIdentified
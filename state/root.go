@@ -0,0 +1,27 @@
+// Copyright 2015 MICHII Shunsuke. All rights reserved.
+
+package state
+
+import "path/filepath"
+
+// Root specifies the root directory of the target system. The states resolve
+// the absolute paths of the system files, e.g., the managed files, the release
+// files of the platform, and the user databases, under the Root directory.
+//
+// Root is "/" by default. Changing Root allows to build or verify the disk
+// images and the directories which will be the root of the other systems.
+var Root = "/"
+
+// RootPath returns the named path resolved under the Root directory.
+func RootPath(name string) string {
+	if Root == "" || Root == "/" {
+		return name
+	}
+	return filepath.Join(Root, name)
+}
+
+// IsAlternateRoot returns whether the Root directory is not the root of the
+// host.
+func IsAlternateRoot() bool {
+	return Root != "" && Root != "/"
+}
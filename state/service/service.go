@@ -0,0 +1,185 @@
+/*
+Package service implements the states of the services managed by systemd.
+
+The states are tested and applied by systemctl command. Following states are
+provided:
+
+	- Running  ... the service is running
+	- Stopped  ... the service is not running
+	- Enabled  ... the service is enabled, or disabled, to start on boot
+
+The actions which are applied on notifications:
+
+	- Restarted      ... restarts the service
+	- Reloaded       ... reloads the configuration of the service
+	- DaemonReloaded ... reloads the unit files of systemd
+
+VerifiedUnit checks the unit file by systemd-analyze before it is written.
+
+Under the alternate root directory, only enabling and disabling the services
+are supported.
+*/
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/harukasan/orchestra-pit/state"
+	"github.com/harukasan/orchestra-pit/state/exec"
+)
+
+// SystemctlPath specifies the path of systemctl command.
+var SystemctlPath = "/bin/systemctl"
+
+// Runner specifies the CommandRunner to run systemctl command.
+var Runner = exec.DefaultRunner
+
+// systemctl runs systemctl command with the arguments. The commands which
+// manage the unit files are run under the root directory.
+func systemctl(args ...string) ([]byte, error) {
+	if state.IsAlternateRoot() {
+		switch args[0] {
+		case "enable", "disable", "is-enabled":
+			args = append([]string{"--root=" + state.Root}, args...)
+		default:
+			return nil, fmt.Errorf("can not %s the service under the alternate root", args[0])
+		}
+	}
+	stdout, _, err := Runner.Run(exec.Command(SystemctlPath, args...))
+	return stdout, err
+}
+
+// IsActive returns whether the named service is running.
+func IsActive(name string) (bool, error) {
+	_, err := systemctl("is-active", "--quiet", name)
+	if _, ok := err.(*exec.ExitError); ok {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// IsEnabled returns whether the named service is enabled.
+func IsEnabled(name string) (bool, error) {
+	out, err := systemctl("is-enabled", name)
+	if _, ok := err.(*exec.ExitError); ok {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	s := strings.TrimSpace(string(out))
+	return s == "enabled" || s == "enabled-runtime", nil
+}
+
+// Running is a state that the service is running.
+type Running struct {
+	Name string
+}
+
+// Apply tries to start the service.
+func (s *Running) Apply() error {
+	_, err := systemctl("start", s.Name)
+	return err
+}
+
+// Test tests whether the service is running.
+func (s *Running) Test() error {
+	active, err := IsActive(s.Name)
+	if err != nil {
+		return err
+	}
+	if !active {
+		return fmt.Errorf(`the service "%s" is not running`, s.Name)
+	}
+	return nil
+}
+
+// Stopped is a state that the service is not running.
+type Stopped struct {
+	Name string
+}
+
+// Apply tries to stop the service.
+func (s *Stopped) Apply() error {
+	_, err := systemctl("stop", s.Name)
+	return err
+}
+
+// Test tests whether the service is not running.
+func (s *Stopped) Test() error {
+	active, err := IsActive(s.Name)
+	if err != nil {
+		return err
+	}
+	if active {
+		return fmt.Errorf(`the service "%s" is running`, s.Name)
+	}
+	return nil
+}
+
+// Enabled is a state that the service is enabled to start on boot. If
+// Enabled is false, the service should be disabled.
+type Enabled struct {
+	Name    string
+	Enabled bool
+}
+
+// Apply tries to enable or disable the service.
+func (s *Enabled) Apply() error {
+	cmd := "enable"
+	if !s.Enabled {
+		cmd = "disable"
+	}
+	_, err := systemctl(cmd, s.Name)
+	return err
+}
+
+// Test tests whether the service is enabled, or disabled.
+func (s *Enabled) Test() error {
+	enabled, err := IsEnabled(s.Name)
+	if err != nil {
+		return err
+	}
+	if enabled != s.Enabled {
+		if s.Enabled {
+			return fmt.Errorf(`the service "%s" is not enabled`, s.Name)
+		}
+		return fmt.Errorf(`the service "%s" is enabled`, s.Name)
+	}
+	return nil
+}
+
+// Restarted is an action to restart the service. Because the action has no
+// state to keep, Test always succeeds.
+type Restarted struct {
+	Name string
+}
+
+// Apply restarts the service.
+func (s *Restarted) Apply() error {
+	_, err := systemctl("restart", s.Name)
+	return err
+}
+
+// Test does nothing.
+func (s *Restarted) Test() error {
+	return nil
+}
+
+// Reloaded is an action to reload the configuration of the service. Because
+// the action has no state to keep, Test always succeeds.
+type Reloaded struct {
+	Name string
+}
+
+// Apply reloads the service.
+func (s *Reloaded) Apply() error {
+	_, err := systemctl("reload", s.Name)
+	return err
+}
+
+// Test does nothing.
+func (s *Reloaded) Test() error {
+	return nil
+}
@@ -0,0 +1,159 @@
+package service_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/harukasan/orchestra-pit/state"
+	"github.com/harukasan/orchestra-pit/state/service"
+)
+
+// stub is a systemctl which records the arguments, and answers the status of
+// the services by the files in its directory.
+const stub = `#!/bin/sh
+dir=$(dirname "$0")
+echo "$@" >> "$dir/calls"
+if [ "$1" = "--root=$ROOT" ]; then
+	shift
+fi
+case "$1" in
+is-active)
+	test -f "$dir/$3.active"
+	;;
+is-enabled)
+	if [ -f "$dir/$2.enabled" ]; then
+		echo enabled
+	else
+		echo disabled
+		exit 1
+	fi
+	;;
+start) touch "$dir/$2.active" ;;
+stop) rm -f "$dir/$2.active" ;;
+enable) touch "$dir/$2.enabled" ;;
+disable) rm -f "$dir/$2.enabled" ;;
+esac
+`
+
+// setup installs the stub systemctl, and returns its directory and the
+// function to restore the systemctl and to remove the directory.
+func setup(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "service_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "systemctl")
+	if err := ioutil.WriteFile(path, []byte(stub), 0755); err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	orig := service.SystemctlPath
+	service.SystemctlPath = path
+	return dir, func() {
+		service.SystemctlPath = orig
+		os.RemoveAll(dir)
+	}
+}
+
+func calls(t *testing.T, dir string) []string {
+	data, err := ioutil.ReadFile(filepath.Join(dir, "calls"))
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	os.Remove(filepath.Join(dir, "calls"))
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func TestRunning(t *testing.T) {
+	dir, cleanup := setup(t)
+	defer cleanup()
+
+	s := &service.Running{Name: "nginx"}
+	if err := s.Test(); err == nil {
+		t.Errorf("got no error for the stopped service")
+	}
+	if err := s.Apply(); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err := s.Test(); err != nil {
+		t.Errorf("got error for the running service: %v", err)
+	}
+	if err := (&service.Stopped{Name: "nginx"}).Test(); err == nil {
+		t.Errorf("got no error for the running service")
+	}
+	expected := "is-active --quiet nginx,start nginx,is-active --quiet nginx,is-active --quiet nginx"
+	if got := strings.Join(calls(t, dir), ","); got != expected {
+		t.Errorf("got calls %q, expected %q", got, expected)
+	}
+
+	if err := (&service.Stopped{Name: "nginx"}).Apply(); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err := (&service.Stopped{Name: "nginx"}).Test(); err != nil {
+		t.Errorf("got error for the stopped service: %v", err)
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	dir, cleanup := setup(t)
+	defer cleanup()
+
+	s := &service.Enabled{Name: "nginx", Enabled: true}
+	if err := s.Test(); err == nil {
+		t.Errorf("got no error for the disabled service")
+	}
+	if err := (&service.Enabled{Name: "nginx"}).Test(); err != nil {
+		t.Errorf("got error for the disabled service: %v", err)
+	}
+	if err := s.Apply(); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err := s.Test(); err != nil {
+		t.Errorf("got error for the enabled service: %v", err)
+	}
+	calls(t, dir)
+
+	if err := (&service.Enabled{Name: "nginx"}).Apply(); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if got := calls(t, dir); len(got) != 1 || got[0] != "disable nginx" {
+		t.Errorf("got calls %q, expected disable", got)
+	}
+}
+
+func TestActions(t *testing.T) {
+	dir, cleanup := setup(t)
+	defer cleanup()
+
+	if err := (&service.Restarted{Name: "nginx"}).Apply(); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err := (&service.Reloaded{Name: "nginx"}).Apply(); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if got := strings.Join(calls(t, dir), ","); got != "restart nginx,reload nginx" {
+		t.Errorf("got calls %q", got)
+	}
+}
+
+func TestAlternateRoot(t *testing.T) {
+	dir, cleanup := setup(t)
+	defer cleanup()
+	state.Root = dir
+	defer func() { state.Root = "/" }()
+	os.Setenv("ROOT", dir)
+	defer os.Unsetenv("ROOT")
+
+	if err := (&service.Enabled{Name: "nginx", Enabled: true}).Apply(); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if got := calls(t, dir); len(got) != 1 || got[0] != "--root="+dir+" enable nginx" {
+		t.Errorf("got calls %q, expected enable under the root", got)
+	}
+	if err := (&service.Running{Name: "nginx"}).Apply(); err == nil {
+		t.Errorf("got no error for starting the service under the alternate root")
+	}
+}
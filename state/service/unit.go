@@ -0,0 +1,97 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/harukasan/orchestra-pit/opit/logger"
+	"github.com/harukasan/orchestra-pit/state"
+	"github.com/harukasan/orchestra-pit/state/exec"
+)
+
+// UnitDir specifies the directory of the unit files managed by the
+// administrator.
+var UnitDir = "/etc/systemd/system"
+
+// SystemdAnalyzePath specifies the path of systemd-analyze command, which
+// verifies the unit files.
+var SystemdAnalyzePath = "/usr/bin/systemd-analyze"
+
+// DaemonReloaded is an action to reload the unit files of systemd. Because the
+// action has no state to keep, Test always succeeds.
+type DaemonReloaded struct{}
+
+// Apply reloads the unit files. Under the alternate root directory, it does
+// nothing because the unit files are loaded on boot.
+func (s *DaemonReloaded) Apply() error {
+	if state.IsAlternateRoot() {
+		logger.Debugf("skip reloading the unit files under the alternate root")
+		return nil
+	}
+	_, err := systemctl("daemon-reload")
+	return err
+}
+
+// Test does nothing.
+func (s *DaemonReloaded) Test() error {
+	return nil
+}
+
+func (s *DaemonReloaded) String() string {
+	return "systemctl daemon-reload"
+}
+
+// VerifiedUnit is a state that the content of the unit file is valid. It is
+// checked before writing the unit file, so the invalid unit is not installed.
+//
+// Name specifies the name of the unit, e.g. nginx.service. Content specifies
+// the content of the unit file.
+//
+// The unit is verified by systemd-analyze verify. If systemd-analyze is not
+// installed, or under the alternate root directory, the unit is not verified.
+type VerifiedUnit struct {
+	Name    string
+	Content []byte
+}
+
+// Apply returns the error of the verification, because the content can not
+// be fixed.
+func (s *VerifiedUnit) Apply() error {
+	return s.Test()
+}
+
+// Test verifies the content of the unit.
+func (s *VerifiedUnit) Test() error {
+	if state.IsAlternateRoot() {
+		logger.Debugf("skip verifying the unit under the alternate root")
+		return nil
+	}
+	if _, err := os.Stat(SystemdAnalyzePath); err != nil {
+		logger.Debugf("skip verifying the unit: %s", err)
+		return nil
+	}
+
+	dir, err := ioutil.TempDir("", "opit-unit-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, s.Name)
+	if err := ioutil.WriteFile(name, s.Content, 0644); err != nil {
+		return err
+	}
+
+	_, stderr, err := Runner.Run(exec.Command(SystemdAnalyzePath, "verify", name))
+	if _, ok := err.(*exec.ExitError); ok {
+		msg := strings.TrimSpace(strings.Replace(string(stderr), name, s.Name, -1))
+		if msg == "" {
+			return errors.New("the unit is invalid")
+		}
+		return fmt.Errorf("the unit is invalid: %s", msg)
+	}
+	return err
+}
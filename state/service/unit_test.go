@@ -0,0 +1,57 @@
+package service_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/harukasan/orchestra-pit/state/service"
+)
+
+// analyzeStub is a systemd-analyze which rejects the unit which has the
+// unknown key.
+const analyzeStub = `#!/bin/sh
+if grep -q '^Unknown=' "$2"; then
+	echo "$2:2: Unknown key name 'Unknown' in section 'Service'" >&2
+	exit 1
+fi
+`
+
+func TestVerifiedUnit(t *testing.T) {
+	dir, cleanup := setup(t)
+	defer cleanup()
+	path := filepath.Join(dir, "systemd-analyze")
+	if err := ioutil.WriteFile(path, []byte(analyzeStub), 0755); err != nil {
+		t.Fatal(err)
+	}
+	orig := service.SystemdAnalyzePath
+	service.SystemdAnalyzePath = path
+	defer func() { service.SystemdAnalyzePath = orig }()
+
+	s := &service.VerifiedUnit{Name: "app.service", Content: []byte("[Service]\nExecStart=/bin/true\n")}
+	if err := s.Test(); err != nil {
+		t.Errorf("got error for the valid unit: %v", err)
+	}
+	s.Content = []byte("[Service]\nUnknown=1\n")
+	err := s.Test()
+	if err == nil || !strings.Contains(err.Error(), "app.service:2: Unknown key") {
+		t.Errorf("got %v, expected the error of the unit", err)
+	}
+
+	service.SystemdAnalyzePath = filepath.Join(dir, "not-installed")
+	if err := s.Test(); err != nil {
+		t.Errorf("got error without systemd-analyze: %v", err)
+	}
+}
+
+func TestDaemonReloaded(t *testing.T) {
+	dir, cleanup := setup(t)
+	defer cleanup()
+	if err := (&service.DaemonReloaded{}).Apply(); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if got := calls(t, dir); len(got) != 1 || got[0] != "daemon-reload" {
+		t.Errorf("got calls %q, expected daemon-reload", got)
+	}
+}